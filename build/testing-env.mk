@@ -53,6 +53,12 @@ newtestrunargs:=$(subst -,-test.,${testrunargs})
 # environment vars accordingly.
 testing_with_xvfb=xvfb-run --server-args="-screen 0 1024x750x24" --auto-servernum
 
+# Setting GLOP_HEADLESS=1 renders through an offscreen EGL context instead; no
+# X server is needed in that case.
+ifneq "$(filter-out 0,${GLOP_HEADLESS})" ""
+testing_with_xvfb=
+endif
+
 ifeq "${testing_env}" ""
 testing_env:=${testing_with_xvfb}
 else
@@ -0,0 +1,152 @@
+package debug
+
+import (
+	"fmt"
+	"image"
+	"unsafe"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/imgmanip"
+)
+
+// Reads the framebuffer without stalling the render thread. Each call to
+// Capture starts copying the current read framebuffer into one of a ring of
+// pixel buffer objects; the copy finishes on the GPU while later frames are
+// drawn. Once the ring comes back around to that buffer, it's mapped and the
+// pixels are sent to the channel given to MakeAsyncCapture as an *image.NRGBA
+// with the top row of pixels first, like ScreenShotNrgba.
+//
+// With a ring of n buffers, a frame is delivered by the nth call to Capture
+// after the one that captured it. Frames are dropped, not queued, if the
+// channel is full so a slow consumer can't stall rendering either.
+//
+// All methods must be called on the render thread.
+type AsyncCapture struct {
+	Width, Height int
+
+	frames chan<- *image.NRGBA
+	ring   []gl.Buffer
+
+	// Whether each buffer in the ring has a read in flight.
+	pending []bool
+
+	// The buffer that the next call to Capture will read into.
+	next int
+
+	dropped int
+}
+
+// Gives the GPU a couple of frames to finish each copy.
+const DefaultCaptureRingSize = 2
+
+// Captures of width x height pixels will be sent to frames. Panics if
+// ringSize is less than 1.
+func MakeAsyncCapture(width, height, ringSize int, frames chan<- *image.NRGBA) *AsyncCapture {
+	if width <= 0 || height <= 0 {
+		panic(fmt.Errorf("MakeAsyncCapture: bad dims (%d, %d)", width, height))
+	}
+	if ringSize < 1 {
+		panic(fmt.Errorf("MakeAsyncCapture: ringSize must be at least 1; got %d", ringSize))
+	}
+
+	ret := &AsyncCapture{
+		Width:   width,
+		Height:  height,
+		frames:  frames,
+		ring:    make([]gl.Buffer, ringSize),
+		pending: make([]bool, ringSize),
+	}
+
+	ret.withPackBuffer(func() {
+		for i := range ret.ring {
+			ret.ring[i] = gl.GenBuffer()
+			ret.ring[i].Bind(gl.PIXEL_PACK_BUFFER)
+			gl.BufferData(gl.PIXEL_PACK_BUFFER, ret.byteSize(), nil, gl.STREAM_READ)
+		}
+	})
+
+	return ret
+}
+
+func (c *AsyncCapture) byteSize() int {
+	// 4 bytes per pixel; one byte per RGBA component
+	return c.Width * c.Height * 4
+}
+
+// Restores the PIXEL_PACK_BUFFER binding after fn runs.
+func (c *AsyncCapture) withPackBuffer(fn func()) {
+	oldBinding := gl.GetInteger(gl.PIXEL_PACK_BUFFER_BINDING)
+	defer gl.Buffer(oldBinding).Bind(gl.PIXEL_PACK_BUFFER)
+	fn()
+}
+
+// Starts reading the current read framebuffer. Call it after a frame is drawn
+// and before the buffers are swapped. Delivers the oldest capture still in
+// flight if its buffer is needed for this one.
+func (c *AsyncCapture) Capture() {
+	c.withPackBuffer(func() {
+		if c.pending[c.next] {
+			c.deliver(c.next)
+		}
+
+		c.ring[c.next].Bind(gl.PIXEL_PACK_BUFFER)
+		// With a pack buffer bound, the 'pixels' argument is an offset into the
+		// buffer; nil means offset 0.
+		gl.ReadPixels(0, 0, c.Width, c.Height, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+		c.pending[c.next] = true
+
+		c.next = (c.next + 1) % len(c.ring)
+	})
+}
+
+// Delivers every capture that's still in flight, oldest first. This stalls
+// until the GPU has finished them; call it when capturing stops.
+func (c *AsyncCapture) Flush() {
+	c.withPackBuffer(func() {
+		for i := range c.ring {
+			idx := (c.next + i) % len(c.ring)
+			if c.pending[idx] {
+				c.deliver(idx)
+			}
+		}
+	})
+}
+
+// Expects the PIXEL_PACK_BUFFER binding to be restored by the caller.
+func (c *AsyncCapture) deliver(idx int) {
+	c.pending[idx] = false
+
+	c.ring[idx].Bind(gl.PIXEL_PACK_BUFFER)
+	data := gl.MapBuffer(gl.PIXEL_PACK_BUFFER, gl.READ_ONLY)
+	if data == nil {
+		panic(fmt.Errorf("AsyncCapture: couldn't map pixel pack buffer"))
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, c.Width, c.Height))
+	copy(nrgba.Pix, unsafe.Slice((*byte)(data), c.byteSize()))
+	gl.UnmapBuffer(gl.PIXEL_PACK_BUFFER)
+
+	imgmanip.FlipVertically[*image.NRGBA](nrgba, nrgba.Pix)
+
+	select {
+	case c.frames <- nrgba:
+	default:
+		c.dropped++
+	}
+}
+
+// The number of frames that were captured but not delivered because the
+// channel was full.
+func (c *AsyncCapture) Dropped() int {
+	return c.dropped
+}
+
+// Releases the ring of buffers. Captures still in flight are discarded; call
+// Flush first to keep them.
+func (c *AsyncCapture) Delete() {
+	for _, buf := range c.ring {
+		buf.Delete()
+	}
+	c.ring = nil
+	c.pending = nil
+}
@@ -0,0 +1,106 @@
+package debug_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/debug"
+	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func clearTo(r, g, b float32) {
+	gl.ClearColor(gl.GLclampf(r), gl.GLclampf(g), gl.GLclampf(b), 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	gl.ClearColor(0, 0, 0, 1)
+}
+
+func TestAsyncCapture(t *testing.T) {
+	t.Run("delivers frames later, in order", func(t *testing.T) {
+		frames := make(chan *image.NRGBA, 8)
+		var afterFirst, afterSecond, afterThird, afterFlush int
+		testbuilder.New().WithSize(8, 8).Run(func() {
+			capture := debug.MakeAsyncCapture(8, 8, 2, frames)
+			defer capture.Delete()
+
+			clearTo(1, 0, 0)
+			capture.Capture()
+			afterFirst = len(frames)
+
+			clearTo(0, 0, 1)
+			capture.Capture()
+			afterSecond = len(frames)
+
+			clearTo(0, 1, 0)
+			capture.Capture()
+			afterThird = len(frames)
+
+			capture.Flush()
+			afterFlush = len(frames)
+		})
+
+		assert.Equal(t, 0, afterFirst)
+		assert.Equal(t, 0, afterSecond)
+		assert.Equal(t, 1, afterThird)
+		require.Equal(t, 3, afterFlush)
+
+		red := color.NRGBA{R: 255, A: 255}
+		blue := color.NRGBA{B: 255, A: 255}
+		green := color.NRGBA{G: 255, A: 255}
+		for _, expected := range []color.NRGBA{red, blue, green} {
+			frame := <-frames
+			assert.Equal(t, image.Rect(0, 0, 8, 8), frame.Bounds())
+			assert.Equal(t, expected, frame.NRGBAAt(4, 4))
+		}
+	})
+
+	t.Run("puts the top row first", func(t *testing.T) {
+		frames := make(chan *image.NRGBA, 1)
+		testbuilder.New().WithSize(8, 8).Run(func() {
+			capture := debug.MakeAsyncCapture(8, 8, 1, frames)
+			defer capture.Delete()
+
+			clearTo(1, 0, 0)
+			// Only the bottom half of the screen is green.
+			gl.Enable(gl.SCISSOR_TEST)
+			gl.Scissor(0, 0, 8, 4)
+			clearTo(0, 1, 0)
+			gl.Scissor(0, 0, 8, 8)
+			gl.Disable(gl.SCISSOR_TEST)
+
+			capture.Capture()
+			capture.Flush()
+		})
+
+		frame := <-frames
+		assert.Equal(t, color.NRGBA{R: 255, A: 255}, frame.NRGBAAt(0, 0))
+		assert.Equal(t, color.NRGBA{G: 255, A: 255}, frame.NRGBAAt(0, 7))
+	})
+
+	t.Run("drops frames rather than blocking", func(t *testing.T) {
+		frames := make(chan *image.NRGBA, 1)
+		var dropped int
+		testbuilder.New().WithSize(8, 8).Run(func() {
+			capture := debug.MakeAsyncCapture(8, 8, 1, frames)
+			defer capture.Delete()
+
+			for range 3 {
+				capture.Capture()
+			}
+			capture.Flush()
+			dropped = capture.Dropped()
+		})
+
+		assert.Equal(t, 2, dropped)
+		assert.Len(t, frames, 1)
+	})
+
+	t.Run("needs a ring", func(t *testing.T) {
+		assert.Panics(t, func() {
+			debug.MakeAsyncCapture(8, 8, 0, nil)
+		})
+	})
+}
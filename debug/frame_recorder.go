@@ -0,0 +1,186 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/caffeine-storm/glop/imgmanip"
+	"github.com/caffeine-storm/glop/system"
+)
+
+// One frame captured by a FrameRecorder.
+type RecordedFrame struct {
+	// Counts calls to SwapBuffers since recording started; with Every > 1,
+	// there are gaps.
+	Index int
+
+	// Time since the first call to SwapBuffers.
+	Timestamp time.Duration
+
+	Image *image.NRGBA
+}
+
+// Wraps a system.System so that the back buffer is captured whenever
+// SwapBuffers is called; use it in place of the wrapped System. Frames are
+// read synchronously with ScreenShotNrgba so SwapBuffers slows down while
+// recording; it's meant for bug reports and tests, not for shipping.
+//
+// SwapBuffers must be called on the render thread, as usual.
+type FrameRecorder struct {
+	system.System
+
+	// Capture every Every-th frame. Zero or one means every frame.
+	Every int
+
+	// Stop capturing after this many frames. Zero means there's no limit.
+	Limit int
+
+	// Reports the time of each frame. Defaults to time.Now.
+	Clock func() time.Time
+
+	recording bool
+	swaps     int
+	start     time.Time
+	frames    []RecordedFrame
+}
+
+var _ system.System = (*FrameRecorder)(nil)
+
+// The recorder starts out recording.
+func MakeFrameRecorder(sys system.System) *FrameRecorder {
+	return &FrameRecorder{
+		System:    sys,
+		Clock:     time.Now,
+		recording: true,
+	}
+}
+
+func (r *FrameRecorder) SwapBuffers() {
+	if r.recording {
+		r.capture()
+	}
+	r.System.SwapBuffers()
+}
+
+func (r *FrameRecorder) capture() {
+	now := r.Clock()
+	if r.swaps == 0 {
+		r.start = now
+	}
+	index := r.swaps
+	r.swaps++
+
+	every := max(r.Every, 1)
+	if index%every != 0 {
+		return
+	}
+	if r.Limit > 0 && len(r.frames) >= r.Limit {
+		return
+	}
+
+	_, _, dx, dy := r.GetWindowDims()
+	r.frames = append(r.frames, RecordedFrame{
+		Index:     index,
+		Timestamp: now.Sub(r.start),
+		Image:     ScreenShotNrgba(dx, dy),
+	})
+}
+
+// Stops capturing; later calls to SwapBuffers just swap.
+func (r *FrameRecorder) Stop() {
+	r.recording = false
+}
+
+func (r *FrameRecorder) Frames() []RecordedFrame {
+	return r.frames
+}
+
+// Describes a recorded frame sequence written by WritePngSequence.
+type FrameManifest struct {
+	Frames []FrameManifestEntry `json:"frames"`
+}
+
+type FrameManifestEntry struct {
+	Index int `json:"index"`
+	// Relative to the manifest's directory.
+	File        string `json:"file"`
+	TimestampMs int64  `json:"timestamp_ms"`
+}
+
+// The name of the manifest that WritePngSequence writes.
+const FrameManifestFile = "manifest.json"
+
+func frameFileName(index int) string {
+	return fmt.Sprintf("frame-%05d.png", index)
+}
+
+// Writes each frame to dir as a numbered PNG along with a FrameManifestFile
+// listing them. The directory is created if needed.
+func (r *FrameRecorder) WritePngSequence(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("WritePngSequence: %w", err)
+	}
+
+	manifest := FrameManifest{
+		Frames: make([]FrameManifestEntry, len(r.frames)),
+	}
+	for i, frame := range r.frames {
+		name := frameFileName(frame.Index)
+		if err := imgmanip.DumpImage(frame.Image, filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("WritePngSequence: couldn't write frame %d: %w", frame.Index, err)
+		}
+		manifest.Frames[i] = FrameManifestEntry{
+			Index:       frame.Index,
+			File:        name,
+			TimestampMs: frame.Timestamp.Milliseconds(),
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("WritePngSequence: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FrameManifestFile), data, 0644); err != nil {
+		return fmt.Errorf("WritePngSequence: %w", err)
+	}
+	return nil
+}
+
+// Reads the manifest written by WritePngSequence to dir.
+func ReadFrameManifest(dir string) (*FrameManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FrameManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("ReadFrameManifest: %w", err)
+	}
+
+	ret := &FrameManifest{}
+	if err := json.Unmarshal(data, ret); err != nil {
+		return nil, fmt.Errorf("ReadFrameManifest: couldn't parse %q: %w", dir, err)
+	}
+	return ret, nil
+}
+
+// Writes the frames as an animated PNG. Each frame is shown until the next
+// one's timestamp; the last frame is shown for as long as the one before it.
+func (r *FrameRecorder) WriteAPNG(w io.Writer) error {
+	images := make([]*image.NRGBA, len(r.frames))
+	delays := make([]time.Duration, len(r.frames))
+	for i, frame := range r.frames {
+		images[i] = frame.Image
+		if i+1 < len(r.frames) {
+			delays[i] = r.frames[i+1].Timestamp - frame.Timestamp
+		} else if i > 0 {
+			delays[i] = delays[i-1]
+		}
+	}
+
+	if err := imgmanip.EncodeAPNG(w, images, delays); err != nil {
+		return fmt.Errorf("WriteAPNG: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package debug_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caffeine-storm/glop/debug"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/rendertest"
+	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
+	"github.com/caffeine-storm/glop/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns a clock that advances by 40ms each time it's read.
+func steppingClock() func() time.Time {
+	now := time.Unix(0, 0)
+	return func() time.Time {
+		ret := now
+		now = now.Add(40 * time.Millisecond)
+		return ret
+	}
+}
+
+// Draws red, green, blue, red, ... frames through the recorder.
+func recordFrames(recorder *debug.FrameRecorder, count int) {
+	colours := [][3]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for i := range count {
+		c := colours[i%len(colours)]
+		clearTo(c[0], c[1], c[2])
+		recorder.SwapBuffers()
+	}
+}
+
+func TestFrameRecorder(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	green := color.NRGBA{G: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+
+	t.Run("captures every k-th frame up to a limit", func(t *testing.T) {
+		var frames []debug.RecordedFrame
+		testbuilder.New().WithSize(8, 8).RunWithAllTheThings(func(sys system.System, _ system.NativeWindowHandle, queue render.RenderQueueInterface) {
+			queue.Queue(func(render.RenderQueueState) {
+				recorder := debug.MakeFrameRecorder(sys)
+				recorder.Clock = steppingClock()
+				recorder.Every = 2
+				recorder.Limit = 2
+
+				recordFrames(recorder, 6)
+				frames = recorder.Frames()
+			})
+			queue.Purge()
+		})
+
+		require.Len(t, frames, 2)
+		assert.Equal(t, 0, frames[0].Index)
+		assert.Equal(t, 2, frames[1].Index)
+		assert.Equal(t, time.Duration(0), frames[0].Timestamp)
+		assert.Equal(t, 80*time.Millisecond, frames[1].Timestamp)
+		assert.Equal(t, red, frames[0].Image.NRGBAAt(4, 4))
+		assert.Equal(t, blue, frames[1].Image.NRGBAAt(4, 4))
+	})
+
+	t.Run("writes a sequence that rendertest can compare against", func(t *testing.T) {
+		dir := t.TempDir()
+		var first, second []debug.RecordedFrame
+		var writeErr, apngErr error
+		var apng bytes.Buffer
+		testbuilder.New().WithSize(8, 8).RunWithAllTheThings(func(sys system.System, _ system.NativeWindowHandle, queue render.RenderQueueInterface) {
+			queue.Queue(func(render.RenderQueueState) {
+				recorder := debug.MakeFrameRecorder(sys)
+				recorder.Clock = steppingClock()
+				recordFrames(recorder, 3)
+				recorder.Stop()
+				recordFrames(recorder, 1)
+
+				first = recorder.Frames()
+				writeErr = recorder.WritePngSequence(dir)
+				apngErr = recorder.WriteAPNG(&apng)
+
+				again := debug.MakeFrameRecorder(sys)
+				recordFrames(again, 3)
+				second = again.Frames()
+			})
+			queue.Purge()
+		})
+
+		require.NoError(t, writeErr)
+		require.NoError(t, apngErr)
+		require.Len(t, first, 3)
+		assert.Equal(t, green, first[1].Image.NRGBAAt(4, 4))
+
+		manifest, err := debug.ReadFrameManifest(dir)
+		require.NoError(t, err)
+		require.Len(t, manifest.Frames, 3)
+		assert.Equal(t, int64(80), manifest.Frames[2].TimestampMs)
+		assert.FileExists(t, filepath.Join(dir, manifest.Frames[1].File))
+
+		assert.NoError(t, rendertest.CompareFrames(second, dir, rendertest.Threshold(0)))
+
+		// Out of order frames don't match.
+		swapped := []debug.RecordedFrame{second[0], second[2], second[1]}
+		for i := range swapped {
+			swapped[i].Index = i
+		}
+		assert.Error(t, rendertest.CompareFrames(swapped, dir, rendertest.Threshold(0)))
+
+		// Unless the differences are masked off.
+		everything := rendertest.IgnoreRegions(image.Pt(8, 8), image.Rect(0, 0, 8, 8))
+		assert.NoError(t, rendertest.CompareFrames(swapped, dir, everything))
+
+		// Missing or corrupt frames are errors, not panics.
+		require.NoError(t, os.WriteFile(filepath.Join(dir, manifest.Frames[1].File), []byte("not a png"), 0644))
+		require.NoError(t, os.Remove(filepath.Join(dir, manifest.Frames[2].File)))
+		err = rendertest.CompareFrames(second, dir, rendertest.Threshold(0))
+		assert.ErrorContains(t, err, "frame 1")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		img, err := png.Decode(&apng)
+		require.NoError(t, err)
+		assert.Equal(t, red, color.NRGBAModel.Convert(img.At(4, 4)))
+	})
+
+	t.Run("reports a missing manifest", func(t *testing.T) {
+		_, err := debug.ReadFrameManifest(filepath.Join(t.TempDir(), "nope"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
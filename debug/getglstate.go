@@ -65,20 +65,9 @@ func GetDepthRange() (float64, float64) {
 	return buffer[0], buffer[1]
 }
 
+// Returns the index of the active texture unit; 0 means gl.TEXTURE0.
 func GetActiveTextureUnit() gl.GLenum {
-	return gl.GLenum(gl.TEXTURE0 - gl.GetInteger(gl.ACTIVE_TEXTURE))
-}
-
-type GlState struct {
-	MatrixMode       string
-	ModelViewMatrix  []float64
-	ProjectionMatrix []float64
-	ColorMatrix      []float64
-	TextureMatrix    []float64
-
-	Colours  map[string]string
-	Bindings map[string]int
-	FlagSet  map[string]gl.GLenum
+	return gl.GLenum(gl.GetInteger(gl.ACTIVE_TEXTURE) - gl.TEXTURE0)
 }
 
 func getColour(colourName gl.GLenum) (byte, byte, byte, byte) {
@@ -144,22 +133,3 @@ func GetColours() map[string]string {
 
 	return ret
 }
-
-func (st *GlState) String() string {
-	return fmt.Sprintf("%+v", *st)
-}
-
-// Returns a high-level description of what the current GL state is.
-func GetGlState() *GlState {
-	return &GlState{
-		MatrixMode:       mappedSymbols()[GetMatrixMode()],
-		ModelViewMatrix:  GetModelViewMatrix(),
-		ProjectionMatrix: GetProjectionMatrix(),
-		ColorMatrix:      GetColorMatrix(),
-		TextureMatrix:    GetTextureMatrix(),
-
-		Bindings: GetBindingsSet(),
-		Colours:  GetColours(),
-		FlagSet:  GetFlagSet(),
-	}
-}
@@ -2,12 +2,15 @@ package debug_test
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/caffeine-storm/gl"
 	"github.com/caffeine-storm/glop/debug"
 	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGlInspect(t *testing.T) {
@@ -50,8 +53,75 @@ func TestGlInspect(t *testing.T) {
 		})
 
 		t.Run("colours", func(t *testing.T) {
-			assert.Contains(t, stringified, "gl.CURRENT_COLOR")
-			assert.Contains(t, stringified, "gl.COLOR_CLEAR_VALUE")
+			assert.Contains(t, stringified, "CURRENT_COLOR")
+			assert.Contains(t, stringified, "COLOR_CLEAR_VALUE")
 		})
 	})
 }
+
+func TestDiffGlState(t *testing.T) {
+	var before, after, restored *debug.GlState
+	testbuilder.Run(func() {
+		before = debug.GetGlState()
+
+		gl.Enable(gl.SCISSOR_TEST)
+		gl.Scissor(1, 2, 3, 4)
+		gl.Color4f(1, 0, 0, 1)
+		after = debug.GetGlState()
+
+		box := before.ScissorBox
+		gl.Scissor(int(box[0]), int(box[1]), int(box[2]), int(box[3]))
+		gl.Disable(gl.SCISSOR_TEST)
+		gl.Color4f(1, 1, 1, 1)
+		restored = debug.GetGlState()
+	})
+
+	t.Run("snapshots are comparable", func(t *testing.T) {
+		assert.True(t, *before == *restored)
+		assert.False(t, *before == *after)
+	})
+
+	t.Run("reports typed differences", func(t *testing.T) {
+		diffs := debug.DiffGlState(before, after)
+
+		fields := map[string]debug.GlStateDifference{}
+		for _, d := range diffs {
+			fields[d.Field] = d
+		}
+
+		require.Contains(t, fields, "SCISSOR_TEST")
+		assert.Equal(t, debug.GlStateFlags, fields["SCISSOR_TEST"].Category)
+		assert.Equal(t, false, fields["SCISSOR_TEST"].Before)
+		assert.Equal(t, true, fields["SCISSOR_TEST"].After)
+
+		require.Contains(t, fields, "SCISSOR_BOX")
+		assert.Equal(t, [4]int32{1, 2, 3, 4}, fields["SCISSOR_BOX"].After)
+
+		require.Contains(t, fields, "CURRENT_COLOR")
+		assert.Equal(t, [4]float64{1, 0, 0, 1}, fields["CURRENT_COLOR"].After)
+
+		assert.Len(t, diffs, 3)
+	})
+
+	t.Run("no differences for equal states", func(t *testing.T) {
+		assert.Empty(t, debug.DiffGlState(before, restored))
+	})
+
+	t.Run("unchanged NaNs aren't differences", func(t *testing.T) {
+		withNaN := *before
+		withNaN.ModelViewMatrix[3] = math.NaN()
+		same := withNaN
+		assert.Empty(t, debug.DiffGlState(&withNaN, &same))
+
+		diffs := debug.DiffGlState(before, &withNaN)
+		require.Len(t, diffs, 1)
+		assert.Equal(t, "MODELVIEW_MATRIX", diffs[0].Field)
+	})
+
+	t.Run("fields are named after their GL enums", func(t *testing.T) {
+		for _, line := range strings.Split(before.String(), "\n") {
+			name, _, _ := strings.Cut(line, ":")
+			assert.Regexp(t, "^[A-Z0-9_]+$", name)
+		}
+	})
+}
@@ -0,0 +1,371 @@
+package debug
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/caffeine-storm/gl"
+)
+
+// Capabilities toggled with gl.Enable/gl.Disable.
+type GlFlags struct {
+	Blend                  bool
+	ClipPlane0             bool
+	ClipPlane1             bool
+	ClipPlane2             bool
+	ClipPlane3             bool
+	CullFace               bool
+	DepthTest              bool
+	Dither                 bool
+	IndexArray             bool
+	NormalArray            bool
+	Normalize              bool
+	ScissorTest            bool
+	StencilTest            bool
+	Texture2D              bool
+	Texture3D              bool
+	TextureCoordArray      bool
+	VertexArray            bool
+	VertexProgramPointSize bool
+	VertexProgramTwoSide   bool
+}
+
+// Names of the objects bound to each binding point; zero means nothing is
+// bound.
+type GlBindings struct {
+	ArrayBuffer             int
+	ElementArrayBuffer      int
+	PixelPackBuffer         int
+	PixelUnpackBuffer       int
+	Texture2D               int
+	TextureCoordArrayBuffer int
+	VertexArrayBuffer       int
+	VertexArray             int
+	Program                 int
+	DrawFramebuffer         int
+	ReadFramebuffer         int
+	Renderbuffer            int
+}
+
+type GlBlendFunc struct {
+	SrcRGB, DstRGB     gl.GLenum
+	SrcAlpha, DstAlpha gl.GLenum
+}
+
+// A snapshot of the GL state that render code commonly changes. GlStates are
+// comparable so two snapshots can be checked with == but a NaN anywhere makes
+// a state unequal to itself; DiffGlState doesn't have that problem and says
+// what changed.
+type GlState struct {
+	MatrixMode       string
+	ModelViewMatrix  [16]float64
+	ProjectionMatrix [16]float64
+	TextureMatrix    [16]float64
+
+	// Only meaningful if HasColorMatrix; see GetColorMatrix.
+	ColorMatrix    [16]float64
+	HasColorMatrix bool
+
+	// RGBA in [0, 1].
+	CurrentColour [4]float64
+	ClearColour   [4]float64
+
+	Flags    GlFlags
+	Bindings GlBindings
+
+	// x, y, width, height
+	Viewport   [4]int32
+	ScissorBox [4]int32
+
+	// near, far
+	DepthRange [2]float64
+
+	BlendFunc GlBlendFunc
+
+	// 0 means gl.TEXTURE0.
+	ActiveTextureUnit gl.GLenum
+}
+
+func getMatrix16(paramName gl.GLenum) [16]float64 {
+	return [16]float64(getMatrix(paramName))
+}
+
+func getColour4(paramName gl.GLenum) [4]float64 {
+	var ret [4]float64
+	gl.GetDoublev(paramName, ret[:])
+	return ret
+}
+
+func getInteger4(paramName gl.GLenum) [4]int32 {
+	var ret [4]int32
+	gl.GetIntegerv(paramName, ret[:])
+	return ret
+}
+
+func getFlags() GlFlags {
+	return GlFlags{
+		Blend:                  gl.IsEnabled(gl.BLEND),
+		ClipPlane0:             gl.IsEnabled(gl.CLIP_PLANE0),
+		ClipPlane1:             gl.IsEnabled(gl.CLIP_PLANE1),
+		ClipPlane2:             gl.IsEnabled(gl.CLIP_PLANE2),
+		ClipPlane3:             gl.IsEnabled(gl.CLIP_PLANE3),
+		CullFace:               gl.IsEnabled(gl.CULL_FACE),
+		DepthTest:              gl.IsEnabled(gl.DEPTH_TEST),
+		Dither:                 gl.IsEnabled(gl.DITHER),
+		IndexArray:             gl.IsEnabled(gl.INDEX_ARRAY),
+		NormalArray:            gl.IsEnabled(gl.NORMAL_ARRAY),
+		Normalize:              gl.IsEnabled(gl.NORMALIZE),
+		ScissorTest:            gl.IsEnabled(gl.SCISSOR_TEST),
+		StencilTest:            gl.IsEnabled(gl.STENCIL_TEST),
+		Texture2D:              gl.IsEnabled(gl.TEXTURE_2D),
+		Texture3D:              gl.IsEnabled(gl.TEXTURE_3D),
+		TextureCoordArray:      gl.IsEnabled(gl.TEXTURE_COORD_ARRAY),
+		VertexArray:            gl.IsEnabled(gl.VERTEX_ARRAY),
+		VertexProgramPointSize: gl.IsEnabled(gl.VERTEX_PROGRAM_POINT_SIZE),
+		VertexProgramTwoSide:   gl.IsEnabled(gl.VERTEX_PROGRAM_TWO_SIDE),
+	}
+}
+
+func getBindings() GlBindings {
+	return GlBindings{
+		ArrayBuffer:             gl.GetInteger(gl.ARRAY_BUFFER_BINDING),
+		ElementArrayBuffer:      gl.GetInteger(gl.ELEMENT_ARRAY_BUFFER_BINDING),
+		PixelPackBuffer:         gl.GetInteger(gl.PIXEL_PACK_BUFFER_BINDING),
+		PixelUnpackBuffer:       gl.GetInteger(gl.PIXEL_UNPACK_BUFFER_BINDING),
+		Texture2D:               gl.GetInteger(gl.TEXTURE_BINDING_2D),
+		TextureCoordArrayBuffer: gl.GetInteger(gl.TEXTURE_COORD_ARRAY_BUFFER_BINDING),
+		VertexArrayBuffer:       gl.GetInteger(gl.VERTEX_ARRAY_BUFFER_BINDING),
+		VertexArray:             gl.GetInteger(gl.VERTEX_ARRAY_BINDING),
+		Program:                 gl.GetInteger(gl.CURRENT_PROGRAM),
+		DrawFramebuffer:         gl.GetInteger(gl.DRAW_FRAMEBUFFER_BINDING),
+		ReadFramebuffer:         gl.GetInteger(gl.READ_FRAMEBUFFER_BINDING),
+		Renderbuffer:            gl.GetInteger(gl.RENDERBUFFER_BINDING),
+	}
+}
+
+func getBlendFunc() GlBlendFunc {
+	return GlBlendFunc{
+		SrcRGB:   gl.GLenum(gl.GetInteger(gl.BLEND_SRC_RGB)),
+		DstRGB:   gl.GLenum(gl.GetInteger(gl.BLEND_DST_RGB)),
+		SrcAlpha: gl.GLenum(gl.GetInteger(gl.BLEND_SRC_ALPHA)),
+		DstAlpha: gl.GLenum(gl.GetInteger(gl.BLEND_DST_ALPHA)),
+	}
+}
+
+// Returns a snapshot of the current GL state. Must be called on the render
+// thread of a compatibility-profile context.
+func GetGlState() *GlState {
+	ret := &GlState{
+		MatrixMode:       mappedSymbols()[GetMatrixMode()],
+		ModelViewMatrix:  getMatrix16(gl.MODELVIEW_MATRIX),
+		ProjectionMatrix: getMatrix16(gl.PROJECTION_MATRIX),
+		TextureMatrix:    getMatrix16(gl.TEXTURE_MATRIX),
+
+		CurrentColour: getColour4(gl.CURRENT_COLOR),
+		ClearColour:   getColour4(gl.COLOR_CLEAR_VALUE),
+
+		Flags:    getFlags(),
+		Bindings: getBindings(),
+
+		Viewport:   getInteger4(gl.VIEWPORT),
+		ScissorBox: getInteger4(gl.SCISSOR_BOX),
+
+		BlendFunc: getBlendFunc(),
+
+		ActiveTextureUnit: GetActiveTextureUnit(),
+	}
+
+	if colorMatrix := GetColorMatrix(); colorMatrix != nil {
+		ret.ColorMatrix = [16]float64(colorMatrix)
+		ret.HasColorMatrix = true
+	}
+
+	near, far := GetDepthRange()
+	ret.DepthRange = [2]float64{near, far}
+
+	return ret
+}
+
+// What part of the state a GlStateDifference is about.
+type GlStateCategory int
+
+const (
+	GlStateMatrices GlStateCategory = iota
+	GlStateColours
+	GlStateFlags
+	GlStateBindings
+	GlStateViewport
+	GlStateScissor
+	GlStateDepthRange
+	GlStateBlendFunc
+	GlStateActiveTexture
+)
+
+func (c GlStateCategory) String() string {
+	switch c {
+	case GlStateMatrices:
+		return "matrices"
+	case GlStateColours:
+		return "colours"
+	case GlStateFlags:
+		return "flags"
+	case GlStateBindings:
+		return "bindings"
+	case GlStateViewport:
+		return "viewport"
+	case GlStateScissor:
+		return "scissor"
+	case GlStateDepthRange:
+		return "depth range"
+	case GlStateBlendFunc:
+		return "blend func"
+	case GlStateActiveTexture:
+		return "active texture"
+	}
+	return fmt.Sprintf("GlStateCategory(%d)", int(c))
+}
+
+// One piece of state that differs between two GlStates. Before and After hold
+// the field's value from each state so they have the field's type; a bool for
+// flags, an int for bindings, a [4]float64 for colours and so on.
+type GlStateDifference struct {
+	Category GlStateCategory
+	// Named after the GL enum that the field is queried with.
+	Field         string
+	Before, After any
+}
+
+func (d GlStateDifference) String() string {
+	return fmt.Sprintf("%s: %v -> %v", d.Field, d.Before, d.After)
+}
+
+type glStateField struct {
+	category GlStateCategory
+	name     string
+	get      func(*GlState) any
+}
+
+// Every field of a GlState in the order they're reported.
+var glStateFields = []glStateField{
+	{GlStateMatrices, "MATRIX_MODE", func(st *GlState) any { return st.MatrixMode }},
+	{GlStateMatrices, "MODELVIEW_MATRIX", func(st *GlState) any { return st.ModelViewMatrix }},
+	{GlStateMatrices, "PROJECTION_MATRIX", func(st *GlState) any { return st.ProjectionMatrix }},
+	{GlStateMatrices, "TEXTURE_MATRIX", func(st *GlState) any { return st.TextureMatrix }},
+	{GlStateMatrices, "COLOR_MATRIX", func(st *GlState) any {
+		if !st.HasColorMatrix {
+			return nil
+		}
+		return st.ColorMatrix
+	}},
+
+	{GlStateColours, "CURRENT_COLOR", func(st *GlState) any { return st.CurrentColour }},
+	{GlStateColours, "COLOR_CLEAR_VALUE", func(st *GlState) any { return st.ClearColour }},
+
+	{GlStateFlags, "BLEND", func(st *GlState) any { return st.Flags.Blend }},
+	{GlStateFlags, "CLIP_PLANE0", func(st *GlState) any { return st.Flags.ClipPlane0 }},
+	{GlStateFlags, "CLIP_PLANE1", func(st *GlState) any { return st.Flags.ClipPlane1 }},
+	{GlStateFlags, "CLIP_PLANE2", func(st *GlState) any { return st.Flags.ClipPlane2 }},
+	{GlStateFlags, "CLIP_PLANE3", func(st *GlState) any { return st.Flags.ClipPlane3 }},
+	{GlStateFlags, "CULL_FACE", func(st *GlState) any { return st.Flags.CullFace }},
+	{GlStateFlags, "DEPTH_TEST", func(st *GlState) any { return st.Flags.DepthTest }},
+	{GlStateFlags, "DITHER", func(st *GlState) any { return st.Flags.Dither }},
+	{GlStateFlags, "INDEX_ARRAY", func(st *GlState) any { return st.Flags.IndexArray }},
+	{GlStateFlags, "NORMAL_ARRAY", func(st *GlState) any { return st.Flags.NormalArray }},
+	{GlStateFlags, "NORMALIZE", func(st *GlState) any { return st.Flags.Normalize }},
+	{GlStateFlags, "SCISSOR_TEST", func(st *GlState) any { return st.Flags.ScissorTest }},
+	{GlStateFlags, "STENCIL_TEST", func(st *GlState) any { return st.Flags.StencilTest }},
+	{GlStateFlags, "TEXTURE_2D", func(st *GlState) any { return st.Flags.Texture2D }},
+	{GlStateFlags, "TEXTURE_3D", func(st *GlState) any { return st.Flags.Texture3D }},
+	{GlStateFlags, "TEXTURE_COORD_ARRAY", func(st *GlState) any { return st.Flags.TextureCoordArray }},
+	{GlStateFlags, "VERTEX_ARRAY", func(st *GlState) any { return st.Flags.VertexArray }},
+	{GlStateFlags, "VERTEX_PROGRAM_POINT_SIZE", func(st *GlState) any { return st.Flags.VertexProgramPointSize }},
+	{GlStateFlags, "VERTEX_PROGRAM_TWO_SIDE", func(st *GlState) any { return st.Flags.VertexProgramTwoSide }},
+
+	{GlStateBindings, "ARRAY_BUFFER_BINDING", func(st *GlState) any { return st.Bindings.ArrayBuffer }},
+	{GlStateBindings, "ELEMENT_ARRAY_BUFFER_BINDING", func(st *GlState) any { return st.Bindings.ElementArrayBuffer }},
+	{GlStateBindings, "PIXEL_PACK_BUFFER_BINDING", func(st *GlState) any { return st.Bindings.PixelPackBuffer }},
+	{GlStateBindings, "PIXEL_UNPACK_BUFFER_BINDING", func(st *GlState) any { return st.Bindings.PixelUnpackBuffer }},
+	{GlStateBindings, "TEXTURE_BINDING_2D", func(st *GlState) any { return st.Bindings.Texture2D }},
+	{GlStateBindings, "TEXTURE_COORD_ARRAY_BUFFER_BINDING", func(st *GlState) any { return st.Bindings.TextureCoordArrayBuffer }},
+	{GlStateBindings, "VERTEX_ARRAY_BUFFER_BINDING", func(st *GlState) any { return st.Bindings.VertexArrayBuffer }},
+	{GlStateBindings, "VERTEX_ARRAY_BINDING", func(st *GlState) any { return st.Bindings.VertexArray }},
+	{GlStateBindings, "CURRENT_PROGRAM", func(st *GlState) any { return st.Bindings.Program }},
+	{GlStateBindings, "DRAW_FRAMEBUFFER_BINDING", func(st *GlState) any { return st.Bindings.DrawFramebuffer }},
+	{GlStateBindings, "READ_FRAMEBUFFER_BINDING", func(st *GlState) any { return st.Bindings.ReadFramebuffer }},
+	{GlStateBindings, "RENDERBUFFER_BINDING", func(st *GlState) any { return st.Bindings.Renderbuffer }},
+
+	{GlStateViewport, "VIEWPORT", func(st *GlState) any { return st.Viewport }},
+	{GlStateScissor, "SCISSOR_BOX", func(st *GlState) any { return st.ScissorBox }},
+	{GlStateDepthRange, "DEPTH_RANGE", func(st *GlState) any { return st.DepthRange }},
+
+	{GlStateBlendFunc, "BLEND_SRC_RGB", func(st *GlState) any { return st.BlendFunc.SrcRGB }},
+	{GlStateBlendFunc, "BLEND_DST_RGB", func(st *GlState) any { return st.BlendFunc.DstRGB }},
+	{GlStateBlendFunc, "BLEND_SRC_ALPHA", func(st *GlState) any { return st.BlendFunc.SrcAlpha }},
+	{GlStateBlendFunc, "BLEND_DST_ALPHA", func(st *GlState) any { return st.BlendFunc.DstAlpha }},
+
+	{GlStateActiveTexture, "ACTIVE_TEXTURE", func(st *GlState) any { return st.ActiveTextureUnit }},
+}
+
+// Like a == b except that floats are compared bitwise so that a NaN that
+// didn't change isn't a difference.
+func sameValue(a, b any) bool {
+	switch av := a.(type) {
+	case [16]float64:
+		bv, ok := b.([16]float64)
+		return ok && sameFloats(av[:], bv[:])
+	case [4]float64:
+		bv, ok := b.([4]float64)
+		return ok && sameFloats(av[:], bv[:])
+	case [2]float64:
+		bv, ok := b.([2]float64)
+		return ok && sameFloats(av[:], bv[:])
+	}
+	return a == b
+}
+
+func sameFloats(a, b []float64) bool {
+	for i := range a {
+		if math.Float64bits(a[i]) != math.Float64bits(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns every field that differs between a and b, in a stable order. Floats
+// are compared bitwise so the result is empty for two snapshots of the same
+// state even if it has NaNs in it.
+func DiffGlState(a, b *GlState) []GlStateDifference {
+	var ret []GlStateDifference
+	for _, field := range glStateFields {
+		before, after := field.get(a), field.get(b)
+		if sameValue(before, after) {
+			continue
+		}
+		ret = append(ret, GlStateDifference{
+			Category: field.category,
+			Field:    field.name,
+			Before:   before,
+			After:    after,
+		})
+	}
+	return ret
+}
+
+// Formats the given differences one per line.
+func FormatGlStateDiff(diffs []GlStateDifference) string {
+	lines := make([]string, len(diffs))
+	for i, d := range diffs {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (st *GlState) String() string {
+	lines := make([]string, len(glStateFields))
+	for i, field := range glStateFields {
+		lines[i] = fmt.Sprintf("%s: %v", field.name, field.get(st))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -25,6 +25,28 @@ func getBoundTextureSize() (width int, height int) {
 	return
 }
 
+// Returns the size of each mipmap level that has been allocated for the bound
+// 2D texture, starting with level 0.
+func getBoundTextureLevelSizes() []image.Point {
+	// GL leaves 'buffer' alone when asked about a level past the last one it
+	// allows so reset it before each query. No texture has more than 32 levels.
+	var ret []image.Point
+	buffer := []int32{0}
+	for level := 0; level < 32; level++ {
+		buffer[0] = 0
+		gl.GetTexLevelParameteriv(gl.TEXTURE_2D, level, gl.TEXTURE_WIDTH, buffer)
+		width := int(buffer[0])
+		buffer[0] = 0
+		gl.GetTexLevelParameteriv(gl.TEXTURE_2D, level, gl.TEXTURE_HEIGHT, buffer)
+		height := int(buffer[0])
+		if width == 0 || height == 0 {
+			break
+		}
+		ret = append(ret, image.Pt(width, height))
+	}
+	return ret
+}
+
 func getBoundTextureFormat() gl.GLenum {
 	buffer := []int32{0}
 	gl.GetTexLevelParameteriv(gl.TEXTURE_2D, 0, gl.TEXTURE_INTERNAL_FORMAT, buffer)
@@ -35,17 +57,78 @@ func getBoundTexture() gl.Texture {
 	return gl.Texture(gl.GetInteger(gl.TEXTURE_BINDING_2D))
 }
 
-func getBytesPerPixel(textureFormat gl.GLenum) int {
-	ret, ok := map[gl.GLenum]int{
-		gl.RGBA:            4,
-		gl.LUMINANCE_ALPHA: 2,
-	}[textureFormat]
+// What we know about each internal format that glop uses.
+type internalFormatInfo struct {
+	// How many bytes a texel takes up, as far as we can tell. Drivers may pad
+	// some formats, like RGB8, so treat this as an estimate.
+	bytesPerPixel int
+
+	// The TexFormat that DumpTexture reads the texture back with; 0 if it
+	// can't.
+	dumpFormat gl.GLenum
+}
+
+// Unsized and sized versions of the formats that glop uploads can be dumped.
+var internalFormats = map[gl.GLenum]internalFormatInfo{
+	gl.RGBA:              {4, TexFormatRGBA},
+	gl.RGBA8:             {4, TexFormatRGBA},
+	gl.SRGB8_ALPHA8:      {4, 0},
+	gl.RGB:               {3, 0},
+	gl.RGB8:              {3, 0},
+	gl.RGBA4:             {2, 0},
+	gl.RGB5_A1:           {2, 0},
+	gl.LUMINANCE_ALPHA:   {2, TexFormatLuminanceAlpha},
+	gl.LUMINANCE8_ALPHA8: {2, TexFormatLuminanceAlpha},
+	gl.RG8:               {2, 0},
+	gl.ALPHA:             {1, TexFormatAlpha},
+	gl.ALPHA8:            {1, TexFormatAlpha},
+	gl.LUMINANCE:         {1, 0},
+	gl.LUMINANCE8:        {1, 0},
+	gl.INTENSITY8:        {1, 0},
+	gl.R8:                {1, 0},
+	gl.RGBA16F:           {8, 0},
+	gl.RGBA32F:           {16, 0},
+	gl.DEPTH_COMPONENT:   {4, 0},
+	gl.DEPTH_COMPONENT24: {4, 0},
+	gl.DEPTH24_STENCIL8:  {4, 0},
+}
+
+// Returns the format to read a texture with the given internal format back
+// with and how many bytes each texel takes in that format.
+func getDumpFormat(internalFormat gl.GLenum) (gl.GLenum, int) {
+	dumpFormat := internalFormats[internalFormat].dumpFormat
+	if dumpFormat == 0 {
+		panic(fmt.Errorf("unknown texture format: %d", internalFormat))
+	}
+	return dumpFormat, internalFormats[dumpFormat].bytesPerPixel
+}
 
+// Returns the size of one texel for the given internal format. Returns false
+// for formats we don't know about.
+func BytesPerPixel(internalFormat gl.GLenum) (int, bool) {
+	info, ok := internalFormats[internalFormat]
+	return info.bytesPerPixel, ok
+}
+
+// Estimates how much memory the given 2D texture uses, counting every mipmap
+// level that has been allocated. Must be called on the render thread.
+func EstimateTextureBytes(textureId gl.Texture) (int, error) {
+	oldTexture := getBoundTexture()
+	textureId.Bind(gl.TEXTURE_2D)
+	defer oldTexture.Bind(gl.TEXTURE_2D)
+
+	texformat := getBoundTextureFormat()
+	bytesPerPixel, ok := BytesPerPixel(texformat)
 	if !ok {
-		panic(fmt.Errorf("unknown texture format: %d", textureFormat))
+		return 0, fmt.Errorf("unknown internal format 0x%04x", int(texformat))
 	}
 
-	return ret
+	total := 0
+	for _, size := range getBoundTextureLevelSizes() {
+		total += size.X * size.Y * bytesPerPixel
+	}
+
+	return total, nil
 }
 
 type TexFormat int
@@ -53,6 +136,7 @@ type TexFormat int
 const (
 	TexFormatRGBA           = gl.RGBA
 	TexFormatLuminanceAlpha = gl.LUMINANCE_ALPHA
+	TexFormatAlpha          = gl.ALPHA
 )
 
 func (tf TexFormat) String() string {
@@ -61,6 +145,8 @@ func (tf TexFormat) String() string {
 		return "gl.RGBA"
 	case TexFormatLuminanceAlpha:
 		return "gl.LUMINANCE_ALPHA"
+	case TexFormatAlpha:
+		return "gl.ALPHA"
 	default:
 		panic(fmt.Errorf("unknown textureformat %d", int(tf)))
 	}
@@ -83,11 +169,14 @@ func DumpTexture(textureId gl.Texture) (*image.NRGBA, error) {
 	defer oldTexture.Bind(gl.TEXTURE_2D)
 
 	textureWidth, textureHeight := getBoundTextureSize()
-	texformat := getBoundTextureFormat()
-	bytesPerPixel := getBytesPerPixel(texformat)
+	texformat, bytesPerPixel := getDumpFormat(getBoundTextureFormat())
 	data := make([]byte, textureWidth*textureHeight*bytesPerPixel)
 
+	// Rows of 1- and 2-byte texels needn't be a multiple of 4 bytes long.
+	oldAlignment := gl.GetInteger(gl.PACK_ALIGNMENT)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
 	gl.GetTexImage(gl.TEXTURE_2D, 0, texformat, gl.UNSIGNED_BYTE, data)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, oldAlignment)
 
 	glog.TraceLogger().Trace("DumpTexture", "data-from-gl", summarize(data), "texformat", TexFormat(texformat))
 
@@ -101,6 +190,12 @@ func DumpTexture(textureId gl.Texture) (*image.NRGBA, error) {
 		ga := imgmanip.NewGrayAlpha(image.Rect(0, 0, textureWidth, textureHeight))
 		ga.Pix = data
 		img = ga
+	case TexFormatAlpha:
+		// Alpha-only textures, like glyph atlases, come back as white with the
+		// texture's alpha.
+		alpha := image.NewAlpha(image.Rect(0, 0, textureWidth, textureHeight))
+		alpha.Pix = data
+		img = alpha
 	default:
 		panic(fmt.Errorf("unknown texformat: %d", int(texformat)))
 	}
@@ -0,0 +1,127 @@
+package debug
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/caffeine-storm/gl"
+)
+
+// Our GL bindings predate texture swizzling (GL 3.3 or ARB_texture_swizzle).
+const (
+	textureSwizzleR gl.GLenum = 0x8E42
+	textureSwizzleG gl.GLenum = 0x8E43
+	textureSwizzleB gl.GLenum = 0x8E44
+	textureSwizzleA gl.GLenum = 0x8E45
+)
+
+// Describes how a 2D texture is stored and sampled, as reported by the driver.
+// Drivers are free to pick a different internal format than was asked for so
+// comparing reports across platforms is a good way of finding out why a
+// texture looks different on one of them.
+type TextureInfo struct {
+	InternalFormat gl.GLenum
+	Width, Height  int
+
+	// The number of mipmap levels that have been allocated.
+	MipLevels int
+
+	MinFilter, MagFilter gl.GLenum
+	WrapS, WrapT         gl.GLenum
+
+	// Where the red, green, blue and alpha components come from when sampled.
+	// Only set if HasSwizzle; older contexts can't report it.
+	Swizzle    [4]gl.GLenum
+	HasSwizzle bool
+}
+
+var textureEnumNames = map[gl.GLenum]string{
+	gl.RGBA:                   "RGBA",
+	gl.RGBA8:                  "RGBA8",
+	gl.RGB:                    "RGB",
+	gl.RGB8:                   "RGB8",
+	gl.LUMINANCE_ALPHA:        "LUMINANCE_ALPHA",
+	gl.LUMINANCE8_ALPHA8:      "LUMINANCE8_ALPHA8",
+	gl.ALPHA:                  "ALPHA",
+	gl.ALPHA8:                 "ALPHA8",
+	gl.NEAREST:                "NEAREST",
+	gl.LINEAR:                 "LINEAR",
+	gl.NEAREST_MIPMAP_NEAREST: "NEAREST_MIPMAP_NEAREST",
+	gl.LINEAR_MIPMAP_NEAREST:  "LINEAR_MIPMAP_NEAREST",
+	gl.NEAREST_MIPMAP_LINEAR:  "NEAREST_MIPMAP_LINEAR",
+	gl.LINEAR_MIPMAP_LINEAR:   "LINEAR_MIPMAP_LINEAR",
+	gl.REPEAT:                 "REPEAT",
+	gl.CLAMP:                  "CLAMP",
+	gl.CLAMP_TO_EDGE:          "CLAMP_TO_EDGE",
+	gl.MIRRORED_REPEAT:        "MIRRORED_REPEAT",
+	gl.RED:                    "RED",
+	gl.GREEN:                  "GREEN",
+	gl.BLUE:                   "BLUE",
+	gl.ZERO:                   "ZERO",
+	gl.ONE:                    "ONE",
+}
+
+func textureEnumName(val gl.GLenum) string {
+	if name, ok := textureEnumNames[val]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", int(val))
+}
+
+func (info TextureInfo) String() string {
+	swizzle := "unknown"
+	if info.HasSwizzle {
+		names := make([]string, len(info.Swizzle))
+		for i, val := range info.Swizzle {
+			names[i] = textureEnumName(val)
+		}
+		swizzle = strings.Join(names, ",")
+	}
+
+	return fmt.Sprintf("%s %dx%d, %d mip level(s), filters: %s/%s, wrap: %s/%s, swizzle: %s",
+		textureEnumName(info.InternalFormat), info.Width, info.Height, info.MipLevels,
+		textureEnumName(info.MinFilter), textureEnumName(info.MagFilter),
+		textureEnumName(info.WrapS), textureEnumName(info.WrapT),
+		swizzle)
+}
+
+// Parses the leading 'major.minor' of GL_VERSION.
+func glVersionAtLeast(major, minor int) bool {
+	var gotMajor, gotMinor int
+	if _, err := fmt.Sscanf(gl.GetString(gl.VERSION), "%d.%d", &gotMajor, &gotMinor); err != nil {
+		return false
+	}
+	return gotMajor > major || (gotMajor == major && gotMinor >= minor)
+}
+
+func getBoundTextureParameter(pname gl.GLenum) gl.GLenum {
+	buffer := []int32{0}
+	gl.GetTexParameteriv(gl.TEXTURE_2D, pname, buffer)
+	return gl.GLenum(buffer[0])
+}
+
+// Describes the given 2D texture. Must be called on the render thread.
+func TextureReport(textureId gl.Texture) TextureInfo {
+	oldTexture := getBoundTexture()
+	textureId.Bind(gl.TEXTURE_2D)
+	defer oldTexture.Bind(gl.TEXTURE_2D)
+
+	ret := TextureInfo{
+		InternalFormat: getBoundTextureFormat(),
+		MipLevels:      len(getBoundTextureLevelSizes()),
+		MinFilter:      getBoundTextureParameter(gl.TEXTURE_MIN_FILTER),
+		MagFilter:      getBoundTextureParameter(gl.TEXTURE_MAG_FILTER),
+		WrapS:          getBoundTextureParameter(gl.TEXTURE_WRAP_S),
+		WrapT:          getBoundTextureParameter(gl.TEXTURE_WRAP_T),
+	}
+	ret.Width, ret.Height = getBoundTextureSize()
+
+	if glVersionAtLeast(3, 3) {
+		ret.HasSwizzle = true
+		for i, pname := range []gl.GLenum{textureSwizzleR, textureSwizzleG, textureSwizzleB, textureSwizzleA} {
+			ret.Swizzle[i] = getBoundTextureParameter(pname)
+		}
+	}
+
+	return ret
+}
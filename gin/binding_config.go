@@ -0,0 +1,288 @@
+package gin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/caffeine-storm/glop/gin/aggregator"
+)
+
+// The version of the binding file format that SaveBindings writes.
+const BindingConfigVersion = 1
+
+// A key as it's written in a binding file. Name is the key's registered name,
+// e.g. "Key A" or "MouseLButton", and Device is a DeviceType's String(). A
+// missing DeviceIndex means any device of that type.
+type KeySpec struct {
+	Name        string       `json:"name"`
+	Device      string       `json:"device"`
+	DeviceIndex *DeviceIndex `json:"device_index,omitempty"`
+}
+
+type ModifierSpec struct {
+	KeySpec
+	Down bool `json:"down"`
+}
+
+type BindingSpec struct {
+	Key       KeySpec        `json:"key"`
+	Modifiers []ModifierSpec `json:"modifiers,omitempty"`
+}
+
+// Maps action names to the ways of triggering them. Actions are named rather
+// than numbered because derived key indices aren't stable between runs.
+type BindingConfig struct {
+	Version int                      `json:"version"`
+	Actions map[string][]BindingSpec `json:"actions"`
+}
+
+func parseDeviceType(name string) (DeviceType, error) {
+	for dt := DeviceTypeAny; dt < DeviceTypeMax; dt++ {
+		if dt.String() == name {
+			return dt, nil
+		}
+	}
+	return DeviceTypeAny, fmt.Errorf("unknown device type %q", name)
+}
+
+func (input *Input) keyIdFromSpec(spec KeySpec) (KeyId, error) {
+	index, ok := input.name_to_index[spec.Name]
+	if !ok {
+		return KeyId{}, fmt.Errorf("unknown key %q", spec.Name)
+	}
+	deviceType, err := parseDeviceType(spec.Device)
+	if err != nil {
+		return KeyId{}, err
+	}
+	if deviceType == DeviceTypeDerived {
+		return KeyId{}, fmt.Errorf("can't bind to derived key %q", spec.Name)
+	}
+
+	id := KeyId{
+		Index: index,
+		Device: DeviceId{
+			Type:  deviceType,
+			Index: DeviceIndexAny,
+		},
+	}
+	if spec.DeviceIndex != nil {
+		if *spec.DeviceIndex < 0 || deviceType == DeviceTypeAny {
+			return KeyId{}, fmt.Errorf("bad device index %d for %q on %s", *spec.DeviceIndex, spec.Name, spec.Device)
+		}
+		id.Device.Index = *spec.DeviceIndex
+	}
+	return id, nil
+}
+
+func (input *Input) keySpecFromId(id KeyId) KeySpec {
+	name, ok := input.index_to_name[id.Index]
+	if !ok {
+		panic(fmt.Errorf("keySpecFromId: no name registered for %v", id))
+	}
+	ret := KeySpec{
+		Name:   name,
+		Device: id.Device.Type.String(),
+	}
+	if id.Device.Index != DeviceIndexAny {
+		index := id.Device.Index
+		ret.DeviceIndex = &index
+	}
+	return ret
+}
+
+func (input *Input) bindingFromSpec(spec BindingSpec) (Binding, error) {
+	primary, err := input.keyIdFromSpec(spec.Key)
+	if err != nil {
+		return Binding{}, err
+	}
+
+	modifiers := make([]KeyId, len(spec.Modifiers))
+	down := make([]bool, len(spec.Modifiers))
+	for i, modifier := range spec.Modifiers {
+		modifiers[i], err = input.keyIdFromSpec(modifier.KeySpec)
+		if err != nil {
+			return Binding{}, fmt.Errorf("modifier %d: %w", i, err)
+		}
+		down[i] = modifier.Down
+	}
+
+	return input.MakeBinding(primary, modifiers, down), nil
+}
+
+func (input *Input) specFromBinding(binding Binding) BindingSpec {
+	ret := BindingSpec{
+		Key: input.keySpecFromId(binding.PrimaryKey),
+	}
+	for i, modifier := range binding.Modifiers {
+		ret.Modifiers = append(ret.Modifiers, ModifierSpec{
+			KeySpec: input.keySpecFromId(modifier),
+			Down:    binding.Down[i],
+		})
+	}
+	return ret
+}
+
+// Returns the derived key for the named action, making it if need be. If the
+// action already exists, its bindings are replaced and the same Key is
+// returned so that code holding on to it sees the new bindings right away. An
+// action that's held when it's rebound is released first.
+func (input *Input) BindAction(name string, bindings ...Binding) Key {
+	input.logger.Trace("gin.input", "action", name)
+	dk, ok := input.actions[name]
+	if !ok {
+		dk = input.bindDerivedKeyWithIndex(name, genDerivedKeyIndex(), bindings...).(*derivedKey)
+		input.actions[name] = dk
+		return dk
+	}
+
+	// removeCauseEffect drops every edge between a cause and dk at once so
+	// each cause must only be removed once.
+	causes := map[KeyId]bool{}
+	for _, binding := range dk.Bindings {
+		causes[binding.PrimaryKey] = true
+		for _, modifier := range binding.Modifiers {
+			causes[modifier] = true
+		}
+	}
+	for cause := range causes {
+		input.removeCauseEffect(cause, dk)
+	}
+
+	// The old bindings' presses no longer count; the action is up until one of
+	// the new bindings is pressed.
+	input.releaseAction(dk)
+	dk.Bindings = bindings
+	dk.bindings_down = make([]bool, len(bindings))
+	for _, binding := range bindings {
+		input.addCauseEffect(binding.PrimaryKey, dk)
+		for _, modifier := range binding.Modifiers {
+			input.addCauseEffect(modifier, dk)
+		}
+	}
+	return dk
+}
+
+// Lets go of an action that's about to be rebound and tells listeners about
+// it straight away, as of the last call to Think.
+func (input *Input) releaseAction(dk *derivedKey) {
+	if !dk.IsDown() {
+		return
+	}
+
+	group := EventGroup{
+		TimestampMs: input.last_think_ms,
+	}
+	for i := range dk.bindings_down {
+		dk.bindings_down[i] = false
+	}
+	dk.keyState.Aggregator.AggregatorSetPressAmt(0, group.TimestampMs, aggregator.Release)
+	release := Event{
+		Key:  &dk.keyState,
+		Type: aggregator.Release,
+	}
+	group.Events = append(group.Events, release)
+	for _, dep := range input.findKeyIdObservers(dk.Id()) {
+		input.pressKey(dep, dep.CurPressAmt(), release, &group)
+	}
+
+	for _, listener := range input.listeners {
+		listener.HandleEventGroup(group)
+	}
+}
+
+// Returns the derived key for an action made with BindAction.
+func (input *Input) Action(name string) (Key, bool) {
+	dk, ok := input.actions[name]
+	if !ok {
+		return nil, false
+	}
+	return dk, true
+}
+
+// Returns the names of the actions made with BindAction, sorted.
+func (input *Input) ActionNames() []string {
+	ret := make([]string, 0, len(input.actions))
+	for name := range input.actions {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Describes the bindings of every action made with BindAction.
+func (input *Input) BindingConfig() BindingConfig {
+	ret := BindingConfig{
+		Version: BindingConfigVersion,
+		Actions: make(map[string][]BindingSpec, len(input.actions)),
+	}
+	for name, dk := range input.actions {
+		specs := make([]BindingSpec, 0, len(dk.Bindings))
+		for _, binding := range dk.Bindings {
+			specs = append(specs, input.specFromBinding(binding))
+		}
+		ret.Actions[name] = specs
+	}
+	return ret
+}
+
+// Binds every action in the config with BindAction. Nothing is changed unless
+// the whole config is valid. Actions that the config doesn't mention keep
+// their bindings.
+func (input *Input) ApplyBindingConfig(config BindingConfig) error {
+	if config.Version != BindingConfigVersion {
+		return fmt.Errorf("unsupported binding config version %d; want %d", config.Version, BindingConfigVersion)
+	}
+
+	bindings := make(map[string][]Binding, len(config.Actions))
+	for name, specs := range config.Actions {
+		if name == "" {
+			return fmt.Errorf("actions need a name")
+		}
+		// An empty list unbinds the action.
+		bindings[name] = make([]Binding, 0, len(specs))
+		for i, spec := range specs {
+			binding, err := input.bindingFromSpec(spec)
+			if err != nil {
+				return fmt.Errorf("action %q binding %d: %w", name, i, err)
+			}
+			bindings[name] = append(bindings[name], binding)
+		}
+	}
+
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		input.BindAction(name, bindings[name]...)
+	}
+	return nil
+}
+
+// Reads a JSON binding config and applies it with ApplyBindingConfig.
+func (input *Input) LoadBindings(r io.Reader) error {
+	var config BindingConfig
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return fmt.Errorf("LoadBindings: %w", err)
+	}
+	if err := input.ApplyBindingConfig(config); err != nil {
+		return fmt.Errorf("LoadBindings: %w", err)
+	}
+	return nil
+}
+
+// Writes the bindings of every action made with BindAction as JSON that
+// LoadBindings can read back.
+func (input *Input) SaveBindings(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(input.BindingConfig()); err != nil {
+		return fmt.Errorf("SaveBindings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package gin_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/caffeine-storm/glop/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jumpAndSaveConfig = `{
+  "version": 1,
+  "actions": {
+    "jump": [
+      {"key": {"name": "Space", "device": "keyboard"}},
+      {"key": {"name": "ControllerButtonSouth", "device": "controller", "device_index": 0}}
+    ],
+    "save": [
+      {
+        "key": {"name": "Key S", "device": "keyboard"},
+        "modifiers": [
+          {"name": "LeftControl", "device": "keyboard", "down": true},
+          {"name": "LeftShift", "device": "keyboard", "down": false}
+        ]
+      }
+    ]
+  }
+}`
+
+func press(key gin.KeyIndex, deviceType gin.DeviceType, amt float64, ms int64) gin.OsEvent {
+	return gin.OsEvent{
+		KeyId: gin.KeyId{
+			Device: gin.DeviceId{Type: deviceType},
+			Index:  key,
+		},
+		Press_amt:   amt,
+		TimestampMs: ms,
+	}
+}
+
+func TestLoadBindings(t *testing.T) {
+	input := gin.Make()
+	require.NoError(t, input.LoadBindings(strings.NewReader(jumpAndSaveConfig)))
+	assert.Equal(t, []string{"jump", "save"}, input.ActionNames())
+
+	jump, ok := input.Action("jump")
+	require.True(t, ok)
+	save, ok := input.Action("save")
+	require.True(t, ok)
+
+	input.Think(10, []gin.OsEvent{press(gin.Space, gin.DeviceTypeKeyboard, 1, 5)})
+	assert.True(t, jump.IsDown())
+	input.Think(20, []gin.OsEvent{press(gin.Space, gin.DeviceTypeKeyboard, 0, 15)})
+	assert.False(t, jump.IsDown())
+
+	input.Think(30, []gin.OsEvent{press(gin.ControllerButtonSouth, gin.DeviceTypeController, 1, 25)})
+	assert.True(t, jump.IsDown())
+
+	input.Think(40, []gin.OsEvent{
+		press(gin.LeftControl, gin.DeviceTypeKeyboard, 1, 31),
+		press(gin.KeyS, gin.DeviceTypeKeyboard, 1, 32),
+	})
+	assert.True(t, save.IsDown())
+}
+
+func TestSaveBindingsRoundTrips(t *testing.T) {
+	input := gin.Make()
+	require.NoError(t, input.LoadBindings(strings.NewReader(jumpAndSaveConfig)))
+
+	var saved bytes.Buffer
+	require.NoError(t, input.SaveBindings(&saved))
+
+	reloaded := gin.Make()
+	require.NoError(t, reloaded.LoadBindings(&saved))
+	assert.Equal(t, input.BindingConfig(), reloaded.BindingConfig())
+}
+
+func TestRebindingAtRuntime(t *testing.T) {
+	input := gin.Make()
+	jump := input.BindAction("jump", input.MakeBinding(gin.AnySpace, nil, nil))
+
+	err := input.ApplyBindingConfig(gin.BindingConfig{
+		Version: gin.BindingConfigVersion,
+		Actions: map[string][]gin.BindingSpec{
+			"jump": {{Key: gin.KeySpec{Name: "Key W", Device: "keyboard"}}},
+		},
+	})
+	require.NoError(t, err)
+
+	rebound, ok := input.Action("jump")
+	require.True(t, ok)
+	assert.Same(t, jump, rebound, "keys held by the game must see new bindings")
+
+	input.Think(10, []gin.OsEvent{press(gin.Space, gin.DeviceTypeKeyboard, 1, 5)})
+	assert.False(t, jump.IsDown())
+	input.Think(20, []gin.OsEvent{press(gin.KeyW, gin.DeviceTypeKeyboard, 1, 15)})
+	assert.True(t, jump.IsDown())
+
+	// Binding back to something that was bound before works too.
+	input.BindAction("jump", input.MakeBinding(gin.AnySpace, nil, nil), input.MakeBinding(gin.AnySpace, nil, nil))
+	input.BindAction("jump", input.MakeBinding(gin.AnyKeyW, nil, nil))
+}
+
+type releaseListener struct {
+	key      gin.KeyId
+	releases []int64
+}
+
+func (l *releaseListener) HandleEventGroup(group gin.EventGroup) {
+	if group.IsReleased(l.key) {
+		l.releases = append(l.releases, group.TimestampMs)
+	}
+}
+
+func (*releaseListener) Think(int64) {}
+
+func TestRebindingWhileHeld(t *testing.T) {
+	input := gin.Make()
+	jump := input.BindAction("jump", input.MakeBinding(gin.AnySpace, nil, nil))
+	listener := &releaseListener{key: jump.Id()}
+	input.RegisterEventListener(listener)
+
+	input.Think(10, []gin.OsEvent{press(gin.Space, gin.DeviceTypeKeyboard, 1, 5)})
+	require.True(t, jump.IsDown())
+
+	input.BindAction("jump", input.MakeBinding(gin.AnyKeyW, nil, nil))
+	assert.False(t, jump.IsDown())
+	assert.Equal(t, []int64{10}, listener.releases)
+
+	input.Think(20, nil)
+	assert.Zero(t, jump.FramePressAmt())
+
+	// Letting go of the old key is old news.
+	input.Think(30, []gin.OsEvent{press(gin.Space, gin.DeviceTypeKeyboard, 0, 25)})
+	assert.Equal(t, []int64{10}, listener.releases)
+
+	// The new binding works as usual.
+	input.Think(40, []gin.OsEvent{press(gin.KeyW, gin.DeviceTypeKeyboard, 1, 35)})
+	assert.True(t, jump.IsDown())
+	input.Think(50, []gin.OsEvent{press(gin.KeyW, gin.DeviceTypeKeyboard, 0, 45)})
+	assert.False(t, jump.IsDown())
+	assert.Equal(t, []int64{10, 45}, listener.releases)
+}
+
+func TestLoadBindingsRejectsBadConfigs(t *testing.T) {
+	for name, config := range map[string]string{
+		"unknown key":      `{"version": 1, "actions": {"jump": [{"key": {"name": "Hyper", "device": "keyboard"}}]}}`,
+		"unknown device":   `{"version": 1, "actions": {"jump": [{"key": {"name": "Space", "device": "theremin"}}]}}`,
+		"derived device":   `{"version": 1, "actions": {"jump": [{"key": {"name": "Space", "device": "derived"}}]}}`,
+		"negative index":   `{"version": 1, "actions": {"jump": [{"key": {"name": "Space", "device": "keyboard", "device_index": -1}}]}}`,
+		"bad modifier":     `{"version": 1, "actions": {"jump": [{"key": {"name": "Space", "device": "keyboard"}, "modifiers": [{"name": "Hyper", "device": "keyboard", "down": true}]}]}}`,
+		"future version":   `{"version": 2, "actions": {}}`,
+		"unknown field":    `{"version": 1, "actions": {}, "colour": "blue"}`,
+		"unnamed action":   `{"version": 1, "actions": {"": [{"key": {"name": "Space", "device": "keyboard"}}]}}`,
+		"not even a json!": `jump = "Space"`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			input := gin.Make()
+			jump := input.BindAction("jump", input.MakeBinding(gin.AnyKeyW, nil, nil))
+
+			assert.Error(t, input.LoadBindings(strings.NewReader(config)))
+
+			// A bad config leaves the old bindings alone.
+			input.Think(10, []gin.OsEvent{press(gin.KeyW, gin.DeviceTypeKeyboard, 1, 5)})
+			assert.True(t, jump.IsDown())
+		})
+	}
+}
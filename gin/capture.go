@@ -0,0 +1,254 @@
+package gin
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/caffeine-storm/glop/gin/aggregator"
+)
+
+var ErrCaptureCancelled = errors.New("gin: binding capture cancelled")
+
+type CaptureOptions struct {
+	// Axis keys, like controller sticks and triggers, have to move at least
+	// this far from rest to be captured.
+	Deadzone float64
+
+	// Keys that are never captured, e.g. the one that backs out of a controls
+	// screen. Capture keeps waiting when one of these is pressed.
+	Reserved []KeyId
+
+	// Keys that are captured as modifiers when they're held as another key is
+	// pressed. Pressing and releasing one by itself captures it as the
+	// primary key.
+	Modifiers []KeyId
+
+	// Keep the device index of the captured key instead of binding to any
+	// device of its type, e.g. to give each player their own keyboard.
+	SameDevice bool
+}
+
+// Halfway deadzone, modifiers are either shift, control, alt or gui and
+// Escape is reserved.
+func DefaultCaptureOptions() CaptureOptions {
+	return CaptureOptions{
+		Deadzone: 0.5,
+		Reserved: []KeyId{AnyEscape},
+		Modifiers: []KeyId{
+			AnyLeftShift, AnyRightShift,
+			AnyLeftControl, AnyRightControl,
+			AnyLeftAlt, AnyRightAlt,
+			AnyLeftGui, AnyRightGui,
+		},
+	}
+}
+
+// A capture started with Input.StartCapture. While it's waiting, Input.Think
+// still tracks which keys are down but doesn't press derived keys or tell
+// listeners about anything, other than keys that were held beforehand being
+// let go of.
+type Capture struct {
+	options CaptureOptions
+	input   *Input
+
+	// Set while a modifier is the only thing that's been pressed.
+	loneModifier *KeyId
+
+	done    chan struct{}
+	once    sync.Once
+	binding Binding
+	err     error
+}
+
+func contains(patterns []KeyId, id KeyId) bool {
+	for _, pattern := range patterns {
+		if pattern.Contains(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a binding and true if the event finishes the capture.
+func (c *Capture) offer(event OsEvent) (Binding, bool) {
+	id := event.KeyId
+	switch id.Device.Type {
+	case DeviceTypeKeyboard, DeviceTypeMouse, DeviceTypeController:
+	default:
+		return Binding{}, false
+	}
+	// The mouse's axes report where the cursor is; any nudge would count.
+	if id.Index == MouseXAxis || id.Index == MouseYAxis {
+		return Binding{}, false
+	}
+
+	if c.input.index_to_agg_type[id.Index] == aggregator.AggregatorTypeAxis {
+		if math.Abs(event.Press_amt) < c.options.Deadzone {
+			return Binding{}, false
+		}
+	} else if event.Press_amt == 0 {
+		if c.loneModifier == nil || *c.loneModifier != id {
+			return Binding{}, false
+		}
+		if contains(c.options.Reserved, id) {
+			c.loneModifier = nil
+			return Binding{}, false
+		}
+		return c.bindingFor(id), true
+	} else if contains(c.options.Modifiers, id) {
+		c.loneModifier = &id
+		return Binding{}, false
+	}
+
+	c.loneModifier = nil
+	if contains(c.options.Reserved, id) {
+		return Binding{}, false
+	}
+	return c.bindingFor(id), true
+}
+
+func (c *Capture) bindingFor(primary KeyId) Binding {
+	var modifiers []KeyId
+	var down []bool
+	for _, modifier := range c.options.Modifiers {
+		if modifier.Contains(primary) {
+			continue
+		}
+		if c.input.GetKeyById(modifier).IsDown() {
+			modifiers = append(modifiers, modifier)
+			down = append(down, true)
+		}
+	}
+
+	if !c.options.SameDevice {
+		primary.Device.Index = DeviceIndexAny
+	}
+	return c.input.MakeBinding(primary, modifiers, down)
+}
+
+func (c *Capture) finish(binding Binding, err error) {
+	c.once.Do(func() {
+		c.binding = binding
+		c.err = err
+		close(c.done)
+	})
+}
+
+// Closed once the capture has a binding or has been cancelled.
+func (c *Capture) Done() <-chan struct{} {
+	return c.done
+}
+
+// Stops the capture if it's still waiting; Wait returns ErrCaptureCancelled.
+func (c *Capture) Cancel() {
+	c.input.endCapture(c)
+	c.finish(Binding{}, ErrCaptureCancelled)
+}
+
+// Blocks until the capture finishes or ctx is done, in which case the capture
+// is cancelled. Something else must keep calling Input.Think meanwhile.
+func (c *Capture) Wait(ctx context.Context) (Binding, error) {
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		c.input.endCapture(c)
+		c.finish(Binding{}, ctx.Err())
+	}
+	return c.binding, c.err
+}
+
+type captureState struct {
+	mutex  sync.Mutex
+	active *Capture
+
+	// Keys pressed during a capture whose releases shouldn't be noticed
+	// either.
+	swallowed map[KeyId]bool
+}
+
+// Starts capturing the next meaningful input as a Binding. Only one capture
+// runs at a time; starting another cancels the first. Safe to call from the
+// goroutine that calls Think.
+func (input *Input) StartCapture(options CaptureOptions) *Capture {
+	c := &Capture{
+		options: options,
+		input:   input,
+		done:    make(chan struct{}),
+	}
+
+	input.capture.mutex.Lock()
+	previous := input.capture.active
+	input.capture.active = c
+	input.capture.mutex.Unlock()
+
+	if previous != nil {
+		previous.finish(Binding{}, ErrCaptureCancelled)
+	}
+	return c
+}
+
+// Waits for the next meaningful input, captured with DefaultCaptureOptions,
+// and returns a Binding for it. Think must be called from another goroutine
+// meanwhile; see StartCapture otherwise.
+func (input *Input) CaptureBinding(ctx context.Context) (Binding, error) {
+	return input.StartCapture(DefaultCaptureOptions()).Wait(ctx)
+}
+
+func (input *Input) endCapture(c *Capture) {
+	input.capture.mutex.Lock()
+	defer input.capture.mutex.Unlock()
+	if input.capture.active == c {
+		input.capture.active = nil
+	}
+}
+
+func (input *Input) activeCapture() *Capture {
+	input.capture.mutex.Lock()
+	defer input.capture.mutex.Unlock()
+	return input.capture.active
+}
+
+// Returns true if the event shouldn't press derived keys or reach listeners.
+// Also lets the active capture, if any, look at the event.
+func (input *Input) captureEvent(event OsEvent) bool {
+	c := input.activeCapture()
+	suppressed := input.swallow(event.KeyId, event.Press_amt, c != nil)
+	if c == nil {
+		return suppressed
+	}
+
+	if binding, ok := c.offer(event); ok {
+		input.endCapture(c)
+		c.finish(binding, nil)
+	}
+	return suppressed
+}
+
+// Like captureEvent but for the events that keys make up for themselves at
+// the end of a frame; those never finish a capture.
+func (input *Input) suppressSynthetic(id KeyId, amt float64) bool {
+	return input.swallow(id, amt, input.activeCapture() != nil)
+}
+
+// Returns true for anything pressed during a capture and for the matching
+// releases, even once the capture's over. Releasing a key that was already
+// down when the capture started isn't swallowed; whatever it was holding down
+// has to be let go of.
+func (input *Input) swallow(id KeyId, amt float64, capturing bool) bool {
+	if amt == 0 {
+		if input.capture.swallowed[id] {
+			delete(input.capture.swallowed, id)
+			return true
+		}
+		return false
+	}
+	if !capturing {
+		return false
+	}
+	if input.index_to_agg_type[id.Index] != aggregator.AggregatorTypeAxis {
+		input.capture.swallowed[id] = true
+	}
+	return true
+}
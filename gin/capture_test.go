@@ -0,0 +1,222 @@
+package gin_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caffeine-storm/glop/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingListener struct {
+	groups int
+}
+
+func (l *countingListener) HandleEventGroup(gin.EventGroup) {
+	l.groups++
+}
+
+func (*countingListener) Think(int64) {}
+
+// Returns the capture's result without waiting; it must already be done.
+func captured(t *testing.T, c *gin.Capture) gin.Binding {
+	t.Helper()
+	select {
+	case <-c.Done():
+	default:
+		require.FailNow(t, "capture should have finished")
+	}
+	binding, err := c.Wait(context.Background())
+	require.NoError(t, err)
+	return binding
+}
+
+func requireWaiting(t *testing.T, c *gin.Capture) {
+	t.Helper()
+	select {
+	case <-c.Done():
+		require.FailNow(t, "capture shouldn't have finished")
+	default:
+	}
+}
+
+func TestCaptureBinding(t *testing.T) {
+	t.Run("keyboard keys with held modifiers", func(t *testing.T) {
+		input := gin.Make()
+		input.Think(10, []gin.OsEvent{press(gin.LeftControl, gin.DeviceTypeKeyboard, 1, 5)})
+
+		c := input.StartCapture(gin.DefaultCaptureOptions())
+		input.Think(20, []gin.OsEvent{press(gin.KeyS, gin.DeviceTypeKeyboard, 1, 15)})
+
+		binding := captured(t, c)
+		assert.Equal(t, gin.AnyKeyS, binding.PrimaryKey)
+		assert.Equal(t, []gin.KeyId{gin.AnyLeftControl}, binding.Modifiers)
+		assert.Equal(t, []bool{true}, binding.Down)
+	})
+
+	t.Run("a modifier by itself", func(t *testing.T) {
+		input := gin.Make()
+		c := input.StartCapture(gin.DefaultCaptureOptions())
+
+		input.Think(10, []gin.OsEvent{press(gin.LeftShift, gin.DeviceTypeKeyboard, 1, 5)})
+		requireWaiting(t, c)
+		input.Think(20, []gin.OsEvent{press(gin.LeftShift, gin.DeviceTypeKeyboard, 0, 15)})
+
+		binding := captured(t, c)
+		assert.Equal(t, gin.AnyLeftShift, binding.PrimaryKey)
+		assert.Empty(t, binding.Modifiers)
+	})
+
+	t.Run("reserved keys are skipped", func(t *testing.T) {
+		input := gin.Make()
+		c := input.StartCapture(gin.DefaultCaptureOptions())
+
+		input.Think(10, []gin.OsEvent{press(gin.Escape, gin.DeviceTypeKeyboard, 1, 5)})
+		requireWaiting(t, c)
+		input.Think(20, []gin.OsEvent{press(gin.MouseRButton, gin.DeviceTypeMouse, 1, 15)})
+
+		binding := captured(t, c)
+		assert.Equal(t, gin.KeyId{
+			Index:  gin.MouseRButton,
+			Device: gin.DeviceId{Type: gin.DeviceTypeMouse, Index: gin.DeviceIndexAny},
+		}, binding.PrimaryKey)
+	})
+
+	t.Run("mouse motion and small axis moves are noise", func(t *testing.T) {
+		input := gin.Make()
+		c := input.StartCapture(gin.DefaultCaptureOptions())
+
+		input.Think(10, []gin.OsEvent{
+			press(gin.MouseXAxis, gin.DeviceTypeMouse, 120, 1),
+			press(gin.MouseYAxis, gin.DeviceTypeMouse, 80, 2),
+			press(gin.ControllerLeftStickX, gin.DeviceTypeController, 0.3, 3),
+			press(gin.ControllerLeftTrigger, gin.DeviceTypeController, 0.1, 4),
+		})
+		requireWaiting(t, c)
+
+		input.Think(20, []gin.OsEvent{press(gin.ControllerLeftStickX, gin.DeviceTypeController, -0.9, 15)})
+		binding := captured(t, c)
+		assert.Equal(t, gin.KeyIndex(gin.ControllerLeftStickX), binding.PrimaryKey.Index)
+	})
+
+	t.Run("the mouse wheel and controller buttons", func(t *testing.T) {
+		input := gin.Make()
+		c := input.StartCapture(gin.DefaultCaptureOptions())
+		input.Think(10, []gin.OsEvent{press(gin.MouseWheelVertical, gin.DeviceTypeMouse, -1, 5)})
+		assert.Equal(t, gin.KeyIndex(gin.MouseWheelVertical), captured(t, c).PrimaryKey.Index)
+
+		options := gin.DefaultCaptureOptions()
+		options.SameDevice = true
+		c = input.StartCapture(options)
+		event := press(gin.ControllerButtonNorth, gin.DeviceTypeController, 1, 15)
+		event.KeyId.Device.Index = 2
+		input.Think(20, []gin.OsEvent{event})
+		assert.Equal(t, event.KeyId, captured(t, c).PrimaryKey)
+	})
+}
+
+func TestCaptureSuppressesListenersAndDerivedKeys(t *testing.T) {
+	input := gin.Make()
+	listener := &countingListener{}
+	input.RegisterEventListener(listener)
+	jump := input.BindAction("jump", input.MakeBinding(gin.AnySpace, nil, nil))
+
+	c := input.StartCapture(gin.DefaultCaptureOptions())
+	input.Think(10, []gin.OsEvent{press(gin.Space, gin.DeviceTypeKeyboard, 1, 5)})
+	captured(t, c)
+
+	assert.False(t, jump.IsDown())
+	assert.Zero(t, listener.groups)
+
+	// The natural key still went down so that it can be let go of cleanly.
+	assert.True(t, input.GetKeyById(gin.AnySpace).IsDown())
+
+	// Letting go of the captured key isn't news either.
+	groups := input.Think(20, []gin.OsEvent{press(gin.Space, gin.DeviceTypeKeyboard, 0, 15)})
+	assert.Empty(t, groups)
+	assert.Zero(t, listener.groups)
+	assert.False(t, input.GetKeyById(gin.AnySpace).IsDown())
+
+	// Everything's back to normal afterwards.
+	input.Think(30, []gin.OsEvent{press(gin.Space, gin.DeviceTypeKeyboard, 1, 25)})
+	assert.True(t, jump.IsDown())
+	assert.NotZero(t, listener.groups)
+}
+
+func TestCaptureLetsGoOfKeysHeldBeforehand(t *testing.T) {
+	input := gin.Make()
+	listener := &countingListener{}
+	input.RegisterEventListener(listener)
+	jump := input.BindAction("jump", input.MakeBinding(gin.AnySpace, nil, nil))
+
+	input.Think(10, []gin.OsEvent{press(gin.Space, gin.DeviceTypeKeyboard, 1, 5)})
+	require.True(t, jump.IsDown())
+	heard := listener.groups
+
+	c := input.StartCapture(gin.DefaultCaptureOptions())
+	input.Think(20, []gin.OsEvent{press(gin.Space, gin.DeviceTypeKeyboard, 0, 15)})
+	requireWaiting(t, c)
+	assert.False(t, jump.IsDown())
+	assert.Greater(t, listener.groups, heard, "the release isn't swallowed")
+
+	heard = listener.groups
+	input.Think(30, []gin.OsEvent{press(gin.KeyJ, gin.DeviceTypeKeyboard, 1, 25)})
+	assert.Equal(t, gin.AnyKeyJ, captured(t, c).PrimaryKey)
+	assert.Equal(t, heard, listener.groups)
+
+	input.Think(40, []gin.OsEvent{press(gin.KeyJ, gin.DeviceTypeKeyboard, 0, 35)})
+	assert.False(t, jump.IsDown())
+	assert.Zero(t, jump.FramePressAmt())
+}
+
+func TestCaptureBindingWaits(t *testing.T) {
+	t.Run("for Think on another goroutine", func(t *testing.T) {
+		input := gin.Make()
+		result := make(chan gin.Binding)
+		go func() {
+			binding, err := input.CaptureBinding(context.Background())
+			assert.NoError(t, err)
+			result <- binding
+		}()
+
+		for ms := int64(10); ; ms += 10 {
+			select {
+			case binding := <-result:
+				assert.Equal(t, gin.AnyKeyQ, binding.PrimaryKey)
+				return
+			case <-time.After(time.Millisecond):
+				input.Think(ms, []gin.OsEvent{press(gin.KeyQ, gin.DeviceTypeKeyboard, 1, ms-1)})
+				input.Think(ms+5, []gin.OsEvent{press(gin.KeyQ, gin.DeviceTypeKeyboard, 0, ms+4)})
+			}
+		}
+	})
+
+	t.Run("until the context is done", func(t *testing.T) {
+		input := gin.Make()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := input.CaptureBinding(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		// The capture's over so input flows again.
+		listener := &countingListener{}
+		input.RegisterEventListener(listener)
+		input.Think(10, []gin.OsEvent{press(gin.KeyQ, gin.DeviceTypeKeyboard, 1, 5)})
+		assert.NotZero(t, listener.groups)
+	})
+
+	t.Run("until another capture starts", func(t *testing.T) {
+		input := gin.Make()
+		first := input.StartCapture(gin.DefaultCaptureOptions())
+		second := input.StartCapture(gin.DefaultCaptureOptions())
+
+		_, err := first.Wait(context.Background())
+		assert.ErrorIs(t, err, gin.ErrCaptureCancelled)
+
+		second.Cancel()
+		_, err = second.Wait(context.Background())
+		assert.ErrorIs(t, err, gin.ErrCaptureCancelled)
+	})
+}
@@ -0,0 +1,97 @@
+package gin
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Native code that can tell devices apart, e.g. two mice plugged in for local
+// multiplayer, names each one in OsEvent.DeviceIdentity. Input hands out a
+// DeviceIndex per identity the first time it sees one and never takes it back,
+// so a device that's unplugged and plugged back in gets the same index.
+type deviceRegistry struct {
+	byIdentity map[DeviceType]map[string]DeviceIndex
+	byId       map[DeviceId]string
+}
+
+func makeDeviceRegistry() deviceRegistry {
+	return deviceRegistry{
+		byIdentity: map[DeviceType]map[string]DeviceIndex{},
+		byId:       map[DeviceId]string{},
+	}
+}
+
+func (reg *deviceRegistry) assign(deviceType DeviceType, identity string, index DeviceIndex) {
+	if reg.byIdentity[deviceType] == nil {
+		reg.byIdentity[deviceType] = map[string]DeviceIndex{}
+	}
+	reg.byIdentity[deviceType][identity] = index
+	reg.byId[DeviceId{Type: deviceType, Index: index}] = identity
+}
+
+// Returns the DeviceIndex for the device with the given identity, handing out
+// the lowest unused index if the identity hasn't been seen before. The first
+// device of each type gets index 0 so programs that only look at index 0 keep
+// working with a single keyboard and mouse.
+func (input *Input) DeviceIndexFor(deviceType DeviceType, identity string) DeviceIndex {
+	if index, ok := input.devices.byIdentity[deviceType][identity]; ok {
+		return index
+	}
+
+	index := DeviceIndex(0)
+	for {
+		if _, taken := input.devices.byId[DeviceId{Type: deviceType, Index: index}]; !taken {
+			break
+		}
+		index++
+	}
+	input.devices.assign(deviceType, identity, index)
+	return index
+}
+
+// Pins a device to an index, e.g. to restore a saved player-to-device
+// assignment before the device has sent any events. Panics if the index
+// already belongs to another device.
+func (input *Input) AssignDeviceIndex(deviceType DeviceType, identity string, index DeviceIndex) {
+	if index < 0 {
+		panic(fmt.Errorf("AssignDeviceIndex: need a concrete index; got %d", index))
+	}
+	id := DeviceId{Type: deviceType, Index: index}
+	if owner, taken := input.devices.byId[id]; taken && owner != identity {
+		panic(fmt.Errorf("AssignDeviceIndex: %v already belongs to %q", id, owner))
+	}
+
+	if old, ok := input.devices.byIdentity[deviceType][identity]; ok {
+		delete(input.devices.byId, DeviceId{Type: deviceType, Index: old})
+	}
+	input.devices.assign(deviceType, identity, index)
+}
+
+// Returns the identity of the device that was given the DeviceId, if any.
+func (input *Input) DeviceIdentity(id DeviceId) (string, bool) {
+	identity, ok := input.devices.byId[id]
+	return identity, ok
+}
+
+// Returns the identities of every device of the given type that's been seen,
+// in order of their DeviceIndex.
+func (input *Input) DeviceIdentities(deviceType DeviceType) []string {
+	ret := make([]string, 0, len(input.devices.byIdentity[deviceType]))
+	for identity := range input.devices.byIdentity[deviceType] {
+		ret = append(ret, identity)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		indices := input.devices.byIdentity[deviceType]
+		return indices[ret[i]] < indices[ret[j]]
+	})
+	return ret
+}
+
+// Fills in the event's device index from its identity, if it has one.
+func (input *Input) resolveDeviceIndex(event OsEvent) OsEvent {
+	if event.DeviceIdentity == "" {
+		return event
+	}
+	event.KeyId.Device.Index = input.DeviceIndexFor(event.KeyId.Device.Type, event.DeviceIdentity)
+	return event
+}
@@ -0,0 +1,77 @@
+package gin_test
+
+import (
+	"testing"
+
+	"github.com/caffeine-storm/glop/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func keyboardEvent(identity string, key gin.KeyIndex, amt float64, ms int64) gin.OsEvent {
+	return gin.OsEvent{
+		KeyId: gin.KeyId{
+			Device: gin.DeviceId{Type: gin.DeviceTypeKeyboard},
+			Index:  key,
+		},
+		Press_amt:      amt,
+		TimestampMs:    ms,
+		DeviceIdentity: identity,
+	}
+}
+
+func TestDeviceIndexing(t *testing.T) {
+	t.Run("each identity gets its own index", func(t *testing.T) {
+		input := gin.Make()
+		input.Think(10, []gin.OsEvent{
+			keyboardEvent("AT Translated Set 2 keyboard", gin.KeyA, 1, 5),
+			keyboardEvent("USB Keyboard", gin.KeyB, 1, 6),
+		})
+
+		assert.True(t, input.GetKeyByParts(gin.KeyA, gin.DeviceTypeKeyboard, 0).IsDown())
+		assert.False(t, input.GetKeyByParts(gin.KeyA, gin.DeviceTypeKeyboard, 1).IsDown())
+		assert.True(t, input.GetKeyByParts(gin.KeyB, gin.DeviceTypeKeyboard, 1).IsDown())
+		assert.True(t, input.GetKeyByParts(gin.KeyB, gin.DeviceTypeKeyboard, gin.DeviceIndexAny).IsDown())
+
+		assert.Equal(t, []string{"AT Translated Set 2 keyboard", "USB Keyboard"}, input.DeviceIdentities(gin.DeviceTypeKeyboard))
+		identity, ok := input.DeviceIdentity(gin.DeviceId{Type: gin.DeviceTypeKeyboard, Index: 1})
+		assert.True(t, ok)
+		assert.Equal(t, "USB Keyboard", identity)
+	})
+
+	t.Run("indices survive reconnects", func(t *testing.T) {
+		input := gin.Make()
+		assert.Equal(t, gin.DeviceIndex(0), input.DeviceIndexFor(gin.DeviceTypeMouse, "first"))
+		assert.Equal(t, gin.DeviceIndex(1), input.DeviceIndexFor(gin.DeviceTypeMouse, "second"))
+
+		// Device types are counted separately.
+		assert.Equal(t, gin.DeviceIndex(0), input.DeviceIndexFor(gin.DeviceTypeKeyboard, "second"))
+
+		// 'first' was unplugged and has come back.
+		assert.Equal(t, gin.DeviceIndex(0), input.DeviceIndexFor(gin.DeviceTypeMouse, "first"))
+	})
+
+	t.Run("events without an identity keep their index", func(t *testing.T) {
+		input := gin.Make()
+		event := keyboardEvent("", gin.KeyA, 1, 5)
+		event.KeyId.Device.Index = 3
+		input.Think(10, []gin.OsEvent{event})
+
+		assert.True(t, input.GetKeyByParts(gin.KeyA, gin.DeviceTypeKeyboard, 3).IsDown())
+		assert.Empty(t, input.DeviceIdentities(gin.DeviceTypeKeyboard))
+	})
+
+	t.Run("indices can be pinned", func(t *testing.T) {
+		input := gin.Make()
+		input.AssignDeviceIndex(gin.DeviceTypeKeyboard, "player two", 1)
+		assert.Equal(t, gin.DeviceIndex(0), input.DeviceIndexFor(gin.DeviceTypeKeyboard, "player one"))
+		assert.Equal(t, gin.DeviceIndex(1), input.DeviceIndexFor(gin.DeviceTypeKeyboard, "player two"))
+
+		assert.Panics(t, func() {
+			input.AssignDeviceIndex(gin.DeviceTypeKeyboard, "player three", 0)
+		})
+
+		// Moving a device frees up its old index.
+		input.AssignDeviceIndex(gin.DeviceTypeKeyboard, "player two", 5)
+		assert.Equal(t, gin.DeviceIndex(1), input.DeviceIndexFor(gin.DeviceTypeKeyboard, "player three"))
+	})
+}
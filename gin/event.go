@@ -12,6 +12,10 @@ type OsEvent struct {
 	Press_amt   float64
 	TimestampMs int64
 	X, Y        int
+
+	// A name for the device that stays the same across reconnects. If set,
+	// Input.Think replaces KeyId.Device.Index with Input.DeviceIndexFor it.
+	DeviceIdentity string
 }
 
 type Event struct {
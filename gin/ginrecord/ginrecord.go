@@ -0,0 +1,210 @@
+// Package ginrecord saves the raw input that a system.System feeds to
+// gin.Input.Think so that it can be played back exactly, e.g. to reproduce a
+// player's bug report; see gos/replay.
+//
+// A recording is JSON lines: a header naming the format and its version
+// followed by one line per frame. Each frame is written as soon as it's
+// recorded so that a crash loses at most the frame in progress.
+package ginrecord
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/caffeine-storm/glop/gin"
+)
+
+const (
+	Format = "glop-input"
+
+	// The version that Writer writes. Readers accept it and anything older.
+	Version = 1
+)
+
+// The input for one call to gin.Input.Think. Timestamps are relative to
+// the System's startup.
+type Frame struct {
+	HorizonMs int64
+	Events    []gin.OsEvent
+}
+
+type Recording struct {
+	Version int
+	Frames  []Frame
+}
+
+type header struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+// How OsEvents are written; the field names are part of the format so they're
+// spelled out rather than taken from gin.OsEvent.
+type wireEvent struct {
+	DeviceType  gin.DeviceType  `json:"device_type"`
+	DeviceIndex gin.DeviceIndex `json:"device_index"`
+	Key         gin.KeyIndex    `json:"key"`
+	PressAmt    float64         `json:"press_amt"`
+	TimestampMs int64           `json:"timestamp_ms"`
+	X           int             `json:"x"`
+	Y           int             `json:"y"`
+	Identity    string          `json:"identity,omitempty"`
+}
+
+type wireFrame struct {
+	HorizonMs int64       `json:"horizon_ms"`
+	Events    []wireEvent `json:"events"`
+}
+
+func toWire(frame Frame) wireFrame {
+	ret := wireFrame{
+		HorizonMs: frame.HorizonMs,
+		Events:    make([]wireEvent, len(frame.Events)),
+	}
+	for i, event := range frame.Events {
+		ret.Events[i] = wireEvent{
+			DeviceType:  event.KeyId.Device.Type,
+			DeviceIndex: event.KeyId.Device.Index,
+			Key:         event.KeyId.Index,
+			PressAmt:    event.Press_amt,
+			TimestampMs: event.TimestampMs,
+			X:           event.X,
+			Y:           event.Y,
+			Identity:    event.DeviceIdentity,
+		}
+	}
+	return ret
+}
+
+func fromWire(frame wireFrame) Frame {
+	ret := Frame{
+		HorizonMs: frame.HorizonMs,
+		Events:    make([]gin.OsEvent, len(frame.Events)),
+	}
+	for i, event := range frame.Events {
+		ret.Events[i] = gin.OsEvent{
+			KeyId: gin.KeyId{
+				Device: gin.DeviceId{
+					Type:  event.DeviceType,
+					Index: event.DeviceIndex,
+				},
+				Index: event.Key,
+			},
+			Press_amt:      event.PressAmt,
+			TimestampMs:    event.TimestampMs,
+			X:              event.X,
+			Y:              event.Y,
+			DeviceIdentity: event.Identity,
+		}
+	}
+	return ret
+}
+
+// Streams frames to an io.Writer. It's a system.InputRecorder; pass it to
+// System.RecordInput.
+type Writer struct {
+	encoder *json.Encoder
+	err     error
+}
+
+// Writes the header straight away.
+func NewWriter(w io.Writer) (*Writer, error) {
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(header{Format: Format, Version: Version}); err != nil {
+		return nil, fmt.Errorf("ginrecord.NewWriter: %w", err)
+	}
+	return &Writer{encoder: encoder}, nil
+}
+
+// Writes one frame. Once a write fails, later frames are dropped; see Err.
+func (w *Writer) RecordFrame(horizonMs int64, events []gin.OsEvent) {
+	if w.err != nil {
+		return
+	}
+	if err := w.encoder.Encode(toWire(Frame{HorizonMs: horizonMs, Events: events})); err != nil {
+		w.err = fmt.Errorf("ginrecord: couldn't write frame at %dms: %w", horizonMs, err)
+	}
+}
+
+// Returns the first error from RecordFrame, if any.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Writes the whole recording in the current format.
+func (rec *Recording) Write(w io.Writer) error {
+	writer, err := NewWriter(w)
+	if err != nil {
+		return err
+	}
+	for _, frame := range rec.Frames {
+		writer.RecordFrame(frame.HorizonMs, frame.Events)
+	}
+	return writer.Err()
+}
+
+func (rec *Recording) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("Recording.Save: %w", err)
+	}
+	if err := rec.Write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("Recording.Save: %w", err)
+	}
+	return file.Close()
+}
+
+func Read(r io.Reader) (*Recording, error) {
+	scanner := bufio.NewScanner(r)
+	// Frames with lots of mouse motion make for long lines.
+	scanner.Buffer(nil, 64<<20)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("ginrecord.Read: %w", err)
+		}
+		return nil, errors.New("ginrecord.Read: empty recording")
+	}
+	var head header
+	if err := json.Unmarshal(scanner.Bytes(), &head); err != nil {
+		return nil, fmt.Errorf("ginrecord.Read: bad header: %w", err)
+	}
+	if head.Format != Format {
+		return nil, fmt.Errorf("ginrecord.Read: not a recording; format is %q", head.Format)
+	}
+	if head.Version < 1 || head.Version > Version {
+		return nil, fmt.Errorf("ginrecord.Read: unsupported version %d; want at most %d", head.Version, Version)
+	}
+
+	ret := &Recording{Version: head.Version}
+	lineNumber := 1
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var frame wireFrame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, fmt.Errorf("ginrecord.Read: line %d: %w", lineNumber, err)
+		}
+		ret.Frames = append(ret.Frames, fromWire(frame))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ginrecord.Read: %w", err)
+	}
+	return ret, nil
+}
+
+func Load(path string) (*Recording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ginrecord.Load: %w", err)
+	}
+	defer file.Close()
+	return Read(file)
+}
@@ -0,0 +1,91 @@
+package ginrecord_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caffeine-storm/glop/gin"
+	"github.com/caffeine-storm/glop/gin/ginrecord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func someRecording() *ginrecord.Recording {
+	return &ginrecord.Recording{
+		Version: ginrecord.Version,
+		Frames: []ginrecord.Frame{
+			{HorizonMs: 16, Events: []gin.OsEvent{}},
+			{
+				HorizonMs: 33,
+				Events: []gin.OsEvent{
+					{
+						KeyId: gin.KeyId{
+							Device: gin.DeviceId{Type: gin.DeviceTypeKeyboard, Index: 1},
+							Index:  gin.KeyA,
+						},
+						Press_amt:      1,
+						TimestampMs:    20,
+						X:              3,
+						Y:              4,
+						DeviceIdentity: "USB Keyboard",
+					},
+					{
+						KeyId: gin.KeyId{
+							Device: gin.DeviceId{Type: gin.DeviceTypeController},
+							Index:  gin.ControllerLeftStickX,
+						},
+						// Must come back bit-for-bit.
+						Press_amt:   0.1 + 0.2,
+						TimestampMs: 31,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, someRecording().Write(&buf))
+
+	read, err := ginrecord.Read(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, someRecording(), read)
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.jsonl")
+	require.NoError(t, someRecording().Save(path))
+
+	loaded, err := ginrecord.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, someRecording(), loaded)
+}
+
+func TestWriterStreamsFrames(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := ginrecord.NewWriter(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"), "the header is written right away")
+
+	writer.RecordFrame(16, nil)
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+	require.NoError(t, writer.Err())
+}
+
+func TestReadRejects(t *testing.T) {
+	for name, contents := range map[string]string{
+		"empty files":             "",
+		"other formats":           `{"format": "glop-frames", "version": 1}`,
+		"future versions":         `{"format": "glop-input", "version": 2}`,
+		"corrupted frames":        "{\"format\": \"glop-input\", \"version\": 1}\n{\"horizon_ms\": 1, \"events\": [",
+		"things that aren't JSON": "glop-input v1",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ginrecord.Read(strings.NewReader(contents))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -195,6 +195,34 @@ const (
 	MouseRButton                  = 305
 	MouseMButton                  = 306
 
+	// Controller buttons are named for their position, like SDL and the Linux
+	// kernel do, so that South is 'A' on an Xbox pad and 'Cross' on a
+	// PlayStation pad.
+	ControllerButtonSouth      = 400
+	ControllerButtonEast       = 401
+	ControllerButtonWest       = 402
+	ControllerButtonNorth      = 403
+	ControllerLeftBumper       = 404
+	ControllerRightBumper      = 405
+	ControllerSelect           = 406
+	ControllerStart            = 407
+	ControllerGuide            = 408
+	ControllerLeftStickButton  = 409
+	ControllerRightStickButton = 410
+	ControllerDPadUp           = 411
+	ControllerDPadDown         = 412
+	ControllerDPadLeft         = 413
+	ControllerDPadRight        = 414
+
+	// Sticks range over [-1, 1] with up and right being positive. Triggers
+	// range over [0, 1].
+	ControllerLeftStickX   = 420
+	ControllerLeftStickY   = 421
+	ControllerRightStickX  = 422
+	ControllerRightStickY  = 423
+	ControllerLeftTrigger  = 424
+	ControllerRightTrigger = 425
+
 	// standard derived keys start here
 	DerivedKeysRangeStart = 1000
 	EitherShift           = 1000 + iota
@@ -225,6 +253,10 @@ type Input struct {
 
 	// map from KeyIndex to a human-readable name for that key
 	index_to_name map[KeyIndex]string
+	name_to_index map[string]KeyIndex
+
+	// Derived keys bound with BindAction by their action's name.
+	actions map[string]*derivedKey
 
 	// The listeners will receive all events immediately after those events have
 	// been used to update all key states. The order in which listeners are
@@ -233,6 +265,19 @@ type Input struct {
 
 	// Optional logger instance to trace calls to Input.
 	logger glog.Logger
+
+	// Which DeviceIndex each device has been given.
+	devices deviceRegistry
+
+	// See StartCapture.
+	capture captureState
+
+	// Set while pressing keys for an event that derived keys and listeners
+	// mustn't see.
+	suppressing bool
+
+	// The time given to the most recent call to Think.
+	last_think_ms int64
 }
 
 func (input *Input) SetLogger(logger glog.Logger) {
@@ -268,6 +313,10 @@ func MakeLogged(logger glog.Logger) *Input {
 	input.cause_to_effect = make(map[KeyId][]Key, 16)
 	input.index_to_agg_type = make(map[KeyIndex]aggregator.AggregatorType)
 	input.index_to_name = make(map[KeyIndex]string)
+	input.name_to_index = make(map[string]KeyIndex)
+	input.actions = make(map[string]*derivedKey)
+	input.devices = makeDeviceRegistry()
+	input.capture.swallowed = make(map[KeyId]bool)
 	input.SetLogger(logger)
 
 	input.registerKeyIndex(AnyKey, aggregator.AggregatorTypeStandard, "AnyKey")
@@ -345,6 +394,28 @@ func MakeLogged(logger glog.Logger) *Input {
 	input.registerKeyIndex(MouseRButton, aggregator.AggregatorTypeStandard, "MouseRButton")
 	input.registerKeyIndex(MouseMButton, aggregator.AggregatorTypeStandard, "MouseMButton")
 
+	input.registerKeyIndex(ControllerButtonSouth, aggregator.AggregatorTypeStandard, "ControllerButtonSouth")
+	input.registerKeyIndex(ControllerButtonEast, aggregator.AggregatorTypeStandard, "ControllerButtonEast")
+	input.registerKeyIndex(ControllerButtonWest, aggregator.AggregatorTypeStandard, "ControllerButtonWest")
+	input.registerKeyIndex(ControllerButtonNorth, aggregator.AggregatorTypeStandard, "ControllerButtonNorth")
+	input.registerKeyIndex(ControllerLeftBumper, aggregator.AggregatorTypeStandard, "ControllerLeftBumper")
+	input.registerKeyIndex(ControllerRightBumper, aggregator.AggregatorTypeStandard, "ControllerRightBumper")
+	input.registerKeyIndex(ControllerSelect, aggregator.AggregatorTypeStandard, "ControllerSelect")
+	input.registerKeyIndex(ControllerStart, aggregator.AggregatorTypeStandard, "ControllerStart")
+	input.registerKeyIndex(ControllerGuide, aggregator.AggregatorTypeStandard, "ControllerGuide")
+	input.registerKeyIndex(ControllerLeftStickButton, aggregator.AggregatorTypeStandard, "ControllerLeftStickButton")
+	input.registerKeyIndex(ControllerRightStickButton, aggregator.AggregatorTypeStandard, "ControllerRightStickButton")
+	input.registerKeyIndex(ControllerDPadUp, aggregator.AggregatorTypeStandard, "ControllerDPadUp")
+	input.registerKeyIndex(ControllerDPadDown, aggregator.AggregatorTypeStandard, "ControllerDPadDown")
+	input.registerKeyIndex(ControllerDPadLeft, aggregator.AggregatorTypeStandard, "ControllerDPadLeft")
+	input.registerKeyIndex(ControllerDPadRight, aggregator.AggregatorTypeStandard, "ControllerDPadRight")
+	input.registerKeyIndex(ControllerLeftStickX, aggregator.AggregatorTypeAxis, "ControllerLeftStickX")
+	input.registerKeyIndex(ControllerLeftStickY, aggregator.AggregatorTypeAxis, "ControllerLeftStickY")
+	input.registerKeyIndex(ControllerRightStickX, aggregator.AggregatorTypeAxis, "ControllerRightStickX")
+	input.registerKeyIndex(ControllerRightStickY, aggregator.AggregatorTypeAxis, "ControllerRightStickY")
+	input.registerKeyIndex(ControllerLeftTrigger, aggregator.AggregatorTypeAxis, "ControllerLeftTrigger")
+	input.registerKeyIndex(ControllerRightTrigger, aggregator.AggregatorTypeAxis, "ControllerRightTrigger")
+
 	// TODO(#28): bind these 'default' derived keys
 	// input.bindDerivedKeyWithId("Shift", EitherShift, input.MakeBinding(LeftShift, nil, nil), input.MakeBinding(RightShift, nil, nil))
 	// input.bindDerivedKeyWithId("Control", EitherControl, input.MakeBinding(LeftControl, nil, nil), input.MakeBinding(RightControl, nil, nil))
@@ -362,8 +433,12 @@ func (input *Input) registerKeyIndex(index KeyIndex, agg_type aggregator.Aggrega
 	if prev, ok := input.index_to_name[index]; ok {
 		panic(fmt.Errorf("cannot overwrite key registration: index %d, new name %q, old name %q", index, name, prev))
 	}
+	if prev, ok := input.name_to_index[name]; ok {
+		panic(fmt.Errorf("cannot reuse key name: %q is already index %d", name, prev))
+	}
 	input.index_to_agg_type[index] = agg_type
 	input.index_to_name[index] = name
+	input.name_to_index[name] = index
 }
 
 func (input *Input) GetKeyByParts(key_index KeyIndex, device_type DeviceType, device_index DeviceIndex) Key {
@@ -548,7 +623,10 @@ func (input *Input) findKeyIdObservers(id KeyId) []Key {
 
 func (input *Input) pressKey(k Key, amt float64, cause Event, group *EventGroup) {
 	event := k.KeySetPressAmt(amt, group.TimestampMs, cause)
-	keysToPress := input.findKeyIdObservers(event.Key.Id())
+	var keysToPress []Key
+	if !input.suppressing {
+		keysToPress = input.findKeyIdObservers(event.Key.Id())
+	}
 	if event.Type != aggregator.NoEvent {
 		group.Events = append(group.Events, event)
 	}
@@ -572,11 +650,13 @@ func (input *Input) pressKey(k Key, amt float64, cause Event, group *EventGroup)
 }
 
 func (input *Input) Think(t int64, os_events []OsEvent) []EventGroup {
+	input.last_think_ms = t
 	// Generate all key events here. Derived keys are handled through pressKey
 	// and all events are aggregated into one array. Events in this array will
 	// necessarily be in sorted order.
 	var groups []EventGroup
 	for _, os_event := range os_events {
+		os_event = input.resolveDeviceIndex(os_event)
 		glog.TraceLogger().Trace("Input.Think", "os_event", os_event)
 
 		group := EventGroup{
@@ -590,13 +670,18 @@ func (input *Input) Think(t int64, os_events []OsEvent) []EventGroup {
 		// expected to populate cursor_x, cursor_y for all OsEvents.
 		group.SetMousePosition(os_event.X, os_event.Y)
 
+		// Keys still go up and down while a binding's being captured so that
+		// held modifiers can be found but nothing else gets to hear about it.
+		suppressed := input.captureEvent(os_event)
+		input.suppressing = suppressed
 		input.pressKey(
 			input.GetKeyById(os_event.KeyId),
 			os_event.Press_amt,
 			Event{},
 			&group)
+		input.suppressing = false
 
-		if len(group.Events) > 0 {
+		if len(group.Events) > 0 && !suppressed {
 			groups = append(groups, group)
 			for _, listener := range input.listeners {
 				listener.HandleEventGroup(group)
@@ -616,8 +701,11 @@ func (input *Input) Think(t int64, os_events []OsEvent) []EventGroup {
 		group := EventGroup{
 			TimestampMs: t,
 		}
+		suppressed := input.suppressSynthetic(key.Id(), amt)
+		input.suppressing = suppressed
 		input.pressKey(key, amt, Event{}, &group)
-		if len(group.Events) > 0 {
+		input.suppressing = false
+		if len(group.Events) > 0 && !suppressed {
 			groups = append(groups, group)
 			for _, listener := range input.listeners {
 				listener.HandleEventGroup(group)
@@ -1,6 +1,7 @@
 package gos
 
 import (
+	"github.com/caffeine-storm/glop/gos/headless"
 	"github.com/caffeine-storm/glop/gos/linux"
 	"github.com/caffeine-storm/glop/system"
 )
@@ -11,7 +12,15 @@ type linuxSystemObject struct {
 
 var _ system.Os = (*linuxSystemObject)(nil)
 
+// Uses X11/GLX unless the headless.EnvVar environment variable asks for an
+// offscreen EGL context instead.
 func NewSystemInterface() *linuxSystemObject {
+	if headless.Requested() {
+		return &linuxSystemObject{
+			Os: headless.New(),
+		}
+	}
+
 	return &linuxSystemObject{
 		Os: linux.New(),
 	}
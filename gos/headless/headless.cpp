@@ -0,0 +1,245 @@
+#include "include/headless.h"
+
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+#include <GL/gl.h>
+
+#include <chrono>
+#include <cstdint>
+#include <cstdlib>
+#include <mutex>
+#include <ratio>
+
+#include "../linux/logging.hpp"
+
+#ifndef EGL_PLATFORM_SURFACELESS_MESA
+#define EGL_PLATFORM_SURFACELESS_MESA 0x31DD
+#endif
+
+static std::mutex initMut;
+static EGLDisplay display = EGL_NO_DISPLAY;
+
+// Make sure the steady_clock implementation we're using supports millisecond
+// resolution.
+static_assert(std::ratio_less_equal<std::chrono::steady_clock::period,
+                                    std::milli>::value);
+static std::chrono::steady_clock monotonic_clock;
+
+// Return the current time (sampled from a monotonic clock) in milliseconds.
+static int64_t gt() {
+  auto ticks = monotonic_clock.now().time_since_epoch();
+  typedef std::ratio_divide<std::chrono::steady_clock::period, std::milli>
+      millis_per_tick;
+  return (ticks.count() * millis_per_tick::num) / millis_per_tick::den;
+}
+
+struct HeadlessContextData {
+  EGLConfig config;
+  EGLContext context;
+  EGLSurface surface;
+  int width;
+  int height;
+};
+
+// Prefer Mesa's surfaceless platform; it doesn't need a display server or
+// even a GPU. Fall back to whatever the default display is otherwise.
+static EGLDisplay openDisplay() {
+  auto getPlatformDisplay = (PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress(
+      "eglGetPlatformDisplayEXT");
+  if (getPlatformDisplay != nullptr) {
+    EGLDisplay ret = getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA,
+                                        EGL_DEFAULT_DISPLAY, nullptr);
+    if (ret != EGL_NO_DISPLAY) {
+      return ret;
+    }
+    LOG_WARN("couldn't get a surfaceless EGL display; trying the default");
+  }
+
+  return eglGetDisplay(EGL_DEFAULT_DISPLAY);
+}
+
+extern "C" {
+
+int64_t GlopHeadlessInit() {
+  auto lck = std::unique_lock(initMut);
+  if (display == EGL_NO_DISPLAY) {
+    display = openDisplay();
+    if (display == EGL_NO_DISPLAY) {
+      LOG_FATAL("couldn't open an EGL display");
+      std::abort();
+    }
+
+    EGLint major, minor;
+    if (eglInitialize(display, &major, &minor) != EGL_TRUE) {
+      LOG_FATAL("couldn't eglInitialize: error " << eglGetError());
+      std::abort();
+    }
+    LOG_DEBUG("initialized EGL " << major << "." << minor);
+  }
+
+  return gt();
+}
+
+}  // extern "C"
+
+static EGLConfig pickConfig() {
+  EGLint const attribs[] = {EGL_SURFACE_TYPE,
+                            EGL_PBUFFER_BIT,
+                            EGL_RENDERABLE_TYPE,
+                            EGL_OPENGL_BIT,
+                            EGL_RED_SIZE,
+                            8,
+                            EGL_GREEN_SIZE,
+                            8,
+                            EGL_BLUE_SIZE,
+                            8,
+                            EGL_ALPHA_SIZE,
+                            8,
+                            EGL_DEPTH_SIZE,
+                            24,
+                            EGL_STENCIL_SIZE,
+                            8,
+                            EGL_NONE};
+
+  EGLConfig ret;
+  EGLint numConfigs = 0;
+  if (eglChooseConfig(display, attribs, &ret, 1, &numConfigs) != EGL_TRUE ||
+      numConfigs <= 0) {
+    LOG_FATAL("couldn't choose an EGL config. numConfigs: "
+              << numConfigs << " error: " << eglGetError());
+    std::abort();
+  }
+  return ret;
+}
+
+// Match the version and profile that gos/linux asks GLX for. Older drivers
+// might not offer the compatibility profile so fall back to whatever the
+// driver's default is. There's no falling back from a core profile; callers
+// that ask for one can't use anything else.
+static EGLContext createContext(EGLConfig config, bool coreProfile) {
+  EGLint const compatibilityAttribs[] = {
+      EGL_CONTEXT_MAJOR_VERSION,
+      4,
+      EGL_CONTEXT_MINOR_VERSION,
+      5,
+      EGL_CONTEXT_OPENGL_PROFILE_MASK,
+      EGL_CONTEXT_OPENGL_COMPATIBILITY_PROFILE_BIT,
+      EGL_NONE};
+  EGLint const coreAttribs[] = {EGL_CONTEXT_MAJOR_VERSION,
+                                3,
+                                EGL_CONTEXT_MINOR_VERSION,
+                                3,
+                                EGL_CONTEXT_OPENGL_PROFILE_MASK,
+                                EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT,
+                                EGL_NONE};
+
+  if (coreProfile) {
+    EGLContext ret =
+        eglCreateContext(display, config, EGL_NO_CONTEXT, coreAttribs);
+    if (ret == EGL_NO_CONTEXT) {
+      LOG_FATAL("couldn't create a 3.3 core context: error "
+                << eglGetError());
+      std::abort();
+    }
+    return ret;
+  }
+
+  EGLContext ret =
+      eglCreateContext(display, config, EGL_NO_CONTEXT, compatibilityAttribs);
+  if (ret != EGL_NO_CONTEXT) {
+    return ret;
+  }
+  LOG_WARN("couldn't create a 4.5 compatibility context: error "
+           << eglGetError() << "; trying the default");
+
+  ret = eglCreateContext(display, config, EGL_NO_CONTEXT, nullptr);
+  if (ret == EGL_NO_CONTEXT) {
+    LOG_FATAL("couldn't eglCreateContext: error " << eglGetError());
+    std::abort();
+  }
+  return ret;
+}
+
+static EGLSurface createSurface(EGLConfig config, int width, int height) {
+  if (width <= 0 || height <= 0) {
+    LOG_FATAL("bad pbuffer dims: (dx,dy): (" << width << "," << height
+                                             << ")");
+    std::abort();
+  }
+
+  EGLint const attribs[] = {EGL_WIDTH, width, EGL_HEIGHT, height, EGL_NONE};
+  EGLSurface ret = eglCreatePbufferSurface(display, config, attribs);
+  if (ret == EGL_NO_SURFACE) {
+    LOG_FATAL("couldn't eglCreatePbufferSurface: error " << eglGetError());
+    std::abort();
+  }
+  return ret;
+}
+
+static void makeCurrent(HeadlessContextData *data) {
+  if (eglMakeCurrent(display, data->surface, data->surface, data->context) !=
+      EGL_TRUE) {
+    LOG_FATAL("couldn't eglMakeCurrent: error " << eglGetError());
+    std::abort();
+  }
+}
+
+extern "C" {
+
+GlopHeadlessHandle GlopHeadlessCreateContext(int width, int height,
+                                             int coreProfile) {
+  if (display == EGL_NO_DISPLAY) {
+    LOG_FATAL("GlopHeadlessInit must be called before creating a context");
+    std::abort();
+  }
+
+  // The current API is per-thread state so it has to be bound on the thread
+  // that will own the context.
+  if (eglBindAPI(EGL_OPENGL_API) != EGL_TRUE) {
+    LOG_FATAL("couldn't bind the desktop OpenGL API: error " << eglGetError());
+    std::abort();
+  }
+
+  HeadlessContextData *data = new HeadlessContextData();
+  data->config = pickConfig();
+  data->context = createContext(data->config, coreProfile != 0);
+  data->surface = createSurface(data->config, width, height);
+  data->width = width;
+  data->height = height;
+
+  makeCurrent(data);
+
+  GlopHeadlessHandle ret;
+  ret.data = data;
+  return ret;
+}
+
+int64_t GlopHeadlessThink() { return gt(); }
+
+void GlopHeadlessSwapBuffers(GlopHeadlessHandle hdl) {
+  // eglSwapBuffers is a no-op for pbuffers but callers rely on SwapBuffers to
+  // flush the GL command queue.
+  glFinish();
+}
+
+void GlopHeadlessGetWindowDims(GlopHeadlessHandle hdl, int *dx, int *dy) {
+  *dx = hdl.data->width;
+  *dy = hdl.data->height;
+}
+
+void GlopHeadlessSetWindowSize(GlopHeadlessHandle hdl, int dx, int dy) {
+  HeadlessContextData *data = hdl.data;
+  if (data->width == dx && data->height == dy) {
+    return;
+  }
+
+  EGLSurface old = data->surface;
+  data->surface = createSurface(data->config, dx, dy);
+  data->width = dx;
+  data->height = dy;
+  makeCurrent(data);
+
+  eglDestroySurface(display, old);
+}
+
+}  // extern "C"
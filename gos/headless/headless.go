@@ -0,0 +1,114 @@
+// Package headless implements system.Os on top of an offscreen EGL context so
+// that rendering works without an X server. Mesa's software rasterizer is
+// enough; no GPU is needed either.
+//
+// There is no window so there is no input; GetInputEvents never returns any
+// events.
+package headless
+
+// #cgo LDFLAGS: -lEGL -lGL
+// #include "include/headless.h"
+import "C"
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/gin"
+	"github.com/caffeine-storm/glop/system"
+)
+
+// Set this environment variable to "1" to have gos.NewSystemInterface() use
+// this package instead of the platform's windowing system.
+const EnvVar = "GLOP_HEADLESS"
+
+// Returns true iff the environment asks for a headless backend.
+func Requested() bool {
+	val, found := os.LookupEnv(EnvVar)
+	return found && val != "" && val != "0"
+}
+
+// The handle returned from CreateWindow; there's no native window to refer to.
+const WindowHandle = "headless"
+
+// GLEW's glewInit checks for GLX extensions after loading the core entry
+// points. Without a GLX display, it reports GLEW_ERROR_NO_GLX_DISPLAY even
+// though the context is otherwise usable.
+const glewErrorNoGlxDisplay = 4
+
+// Returns an error if the given result of gl.Init() means the current context
+// can't be used. Callers that might be running headless should use this
+// instead of comparing against zero.
+func CheckGlInit(code gl.GLenum) error {
+	if code == 0 {
+		return nil
+	}
+	if code == glewErrorNoGlxDisplay && Requested() {
+		return nil
+	}
+	return fmt.Errorf("couldn't gl.Init: %d", code)
+}
+
+type SystemObject struct {
+	horizon int64
+	handle  C.GlopHeadlessHandle
+}
+
+var _ system.Os = (*SystemObject)(nil)
+
+func (headless *SystemObject) Startup() int64 {
+	return int64(C.GlopHeadlessInit())
+}
+
+// Call after runtime.LockOSThread(), *NOT* in an init function. The 'x' and
+// 'y' co-ordinates are ignored. The context is a core-profile context if
+// system.CoreProfileRequested().
+func (headless *SystemObject) CreateWindow(x, y, width, height int) system.NativeWindowHandle {
+	if headless.handle.data != nil {
+		panic(fmt.Errorf("headless.CreateWindow: only one context per SystemObject is supported"))
+	}
+	coreProfile := C.int(0)
+	if system.CoreProfileRequested() {
+		coreProfile = 1
+	}
+	headless.handle = C.GlopHeadlessCreateContext(C.int(width), C.int(height), coreProfile)
+	return WindowHandle
+}
+
+func (headless *SystemObject) SwapBuffers() {
+	C.GlopHeadlessSwapBuffers(headless.handle)
+}
+
+func (headless *SystemObject) Think() int64 {
+	headless.horizon = int64(C.GlopHeadlessThink())
+	return headless.horizon
+}
+
+func (headless *SystemObject) GetInputEvents() ([]gin.OsEvent, int64) {
+	headless.horizon = int64(C.GlopHeadlessThink())
+	return nil, headless.horizon
+}
+
+func (headless *SystemObject) HideCursor(hide bool) {
+}
+
+func (headless *SystemObject) GetWindowDims() (int, int, int, int) {
+	var dx, dy C.int
+	C.GlopHeadlessGetWindowDims(headless.handle, &dx, &dy)
+	return 0, 0, int(dx), int(dy)
+}
+
+func (headless *SystemObject) SetWindowSize(width, height int) {
+	C.GlopHeadlessSetWindowSize(headless.handle, C.int(width), C.int(height))
+}
+
+// Nothing is ever presented so there's nothing to synchronize with.
+func (headless *SystemObject) EnableVSync(enable bool) {
+}
+
+func New() *SystemObject {
+	ret := &SystemObject{}
+	ret.Startup()
+	return ret
+}
@@ -0,0 +1,98 @@
+package headless_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/gos/headless"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateWindow(t *testing.T) {
+	toRunUnderGLContext := make(chan func())
+	created := make(chan bool)
+	ack := make(chan bool)
+	sysObj := headless.New()
+	go func() {
+		runtime.LockOSThread()
+
+		hdl := sysObj.CreateWindow(0, 0, 64, 32)
+		created <- hdl == headless.WindowHandle
+
+		for fn := range toRunUnderGLContext {
+			fn()
+			ack <- true
+		}
+	}()
+	defer close(toRunUnderGLContext)
+
+	if !<-created {
+		t.Fatalf("sysObj.CreateWindow failed!")
+	}
+
+	t.Run("GL context has the right version and profile", func(t *testing.T) {
+		toRunUnderGLContext <- func() {
+			major := gl.GetInteger(gl.MAJOR_VERSION)
+			minor := gl.GetInteger(gl.MINOR_VERSION)
+			t.Logf("glversion: %d.%d renderer: %q", major, minor, gl.GetString(gl.RENDERER))
+			if major < 3 {
+				t.Errorf("bad glversion: %d.%d", major, minor)
+			}
+
+			profile := gl.GetInteger(gl.CONTEXT_PROFILE_MASK)
+			assert.Equal(t, gl.CONTEXT_COMPATIBILITY_PROFILE_BIT, profile)
+		}
+		<-ack
+	})
+
+	t.Run("can read back what was rendered", func(t *testing.T) {
+		toRunUnderGLContext <- func() {
+			gl.ClearColor(1, 0, 0, 1)
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+			sysObj.SwapBuffers()
+
+			pixel := make([]byte, 4)
+			gl.ReadPixels(5, 5, 1, 1, gl.RGBA, gl.UNSIGNED_BYTE, pixel)
+			assert.Equal(t, []byte{255, 0, 0, 255}, pixel)
+			assert.Equal(t, gl.GLenum(gl.NO_ERROR), gl.GetError())
+		}
+		<-ack
+	})
+
+	t.Run("can be resized", func(t *testing.T) {
+		toRunUnderGLContext <- func() {
+			_, _, dx, dy := sysObj.GetWindowDims()
+			assert.Equal(t, []int{64, 32}, []int{dx, dy})
+
+			sysObj.SetWindowSize(128, 16)
+			_, _, dx, dy = sysObj.GetWindowDims()
+			assert.Equal(t, []int{128, 16}, []int{dx, dy})
+
+			// The new surface should be bound and usable.
+			gl.ClearColor(0, 1, 0, 1)
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+			pixel := make([]byte, 4)
+			gl.ReadPixels(127, 15, 1, 1, gl.RGBA, gl.UNSIGNED_BYTE, pixel)
+			assert.Equal(t, []byte{0, 255, 0, 255}, pixel)
+		}
+		<-ack
+	})
+
+	t.Run("never has input events", func(t *testing.T) {
+		events, horizon := sysObj.GetInputEvents()
+		assert.Empty(t, events)
+		assert.LessOrEqual(t, horizon, sysObj.Think())
+	})
+}
+
+func TestCheckGlInit(t *testing.T) {
+	assert.NoError(t, headless.CheckGlInit(0))
+
+	t.Setenv(headless.EnvVar, "")
+	assert.Error(t, headless.CheckGlInit(4))
+
+	t.Setenv(headless.EnvVar, "1")
+	assert.NoError(t, headless.CheckGlInit(4))
+	assert.Error(t, headless.CheckGlInit(1))
+}
@@ -0,0 +1,40 @@
+#ifndef GLOP_GOS_HEADLESS_HEADLESS_H
+#define GLOP_GOS_HEADLESS_HEADLESS_H
+
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+struct HeadlessContextData;
+typedef struct {
+  struct HeadlessContextData* data;
+} GlopHeadlessHandle;
+
+// Returns a timestamp in the same units as GlopHeadlessThink.
+int64_t GlopHeadlessInit();
+
+// Creates an offscreen OpenGL context backed by a pbuffer of the given size
+// and makes it current on the calling thread. If |coreProfile| is non-zero,
+// the context is a 3.3 core-profile context.
+GlopHeadlessHandle GlopHeadlessCreateContext(int width, int height,
+                                             int coreProfile);
+
+// Returns the current time in milliseconds, sampled from a monotonic clock.
+int64_t GlopHeadlessThink();
+
+// There's no front buffer to present to; waits for outstanding GL commands to
+// complete instead.
+void GlopHeadlessSwapBuffers(GlopHeadlessHandle);
+
+void GlopHeadlessGetWindowDims(GlopHeadlessHandle, int* dx, int* dy);
+
+// Replaces the backing pbuffer; its contents are lost.
+void GlopHeadlessSetWindowSize(GlopHeadlessHandle, int dx, int dy);
+
+#ifdef __cplusplus
+}  // extern "C"
+#endif
+
+#endif  // GLOP_GOS_HEADLESS_HEADLESS_H
@@ -0,0 +1,413 @@
+package evdev
+
+import (
+	"sort"
+	"time"
+
+	"github.com/caffeine-storm/glop/gin"
+	"github.com/caffeine-storm/glop/glog"
+)
+
+var buttonKeys = map[uint16]gin.KeyIndex{
+	BtnSouth:     gin.ControllerButtonSouth,
+	BtnEast:      gin.ControllerButtonEast,
+	BtnWest:      gin.ControllerButtonWest,
+	BtnNorth:     gin.ControllerButtonNorth,
+	BtnTL:        gin.ControllerLeftBumper,
+	BtnTR:        gin.ControllerRightBumper,
+	BtnSelect:    gin.ControllerSelect,
+	BtnStart:     gin.ControllerStart,
+	BtnMode:      gin.ControllerGuide,
+	BtnThumbL:    gin.ControllerLeftStickButton,
+	BtnThumbR:    gin.ControllerRightStickButton,
+	BtnDPadUp:    gin.ControllerDPadUp,
+	BtnDPadDown:  gin.ControllerDPadDown,
+	BtnDPadLeft:  gin.ControllerDPadLeft,
+	BtnDPadRight: gin.ControllerDPadRight,
+
+	// Pads without analog triggers report them as buttons.
+	BtnTL2: gin.ControllerLeftTrigger,
+	BtnTR2: gin.ControllerRightTrigger,
+}
+
+type axisKind int
+
+const (
+	axisStick axisKind = iota
+	// evdev's Y axes grow downwards but gin's grow upwards.
+	axisInvertedStick
+	axisTrigger
+	// Hats are d-pads that report as a pair of axes; each direction is a
+	// button.
+	axisHat
+)
+
+type axisMapping struct {
+	kind axisKind
+
+	// For hats, key is the negative direction and other is the positive one.
+	key, other gin.KeyIndex
+}
+
+var axisMappings = map[uint16]axisMapping{
+	AbsX:     {kind: axisStick, key: gin.ControllerLeftStickX},
+	AbsY:     {kind: axisInvertedStick, key: gin.ControllerLeftStickY},
+	AbsRX:    {kind: axisStick, key: gin.ControllerRightStickX},
+	AbsRY:    {kind: axisInvertedStick, key: gin.ControllerRightStickY},
+	AbsZ:     {kind: axisTrigger, key: gin.ControllerLeftTrigger},
+	AbsRZ:    {kind: axisTrigger, key: gin.ControllerRightTrigger},
+	AbsBrake: {kind: axisTrigger, key: gin.ControllerLeftTrigger},
+	AbsGas:   {kind: axisTrigger, key: gin.ControllerRightTrigger},
+	AbsHat0X: {kind: axisHat, key: gin.ControllerDPadLeft, other: gin.ControllerDPadRight},
+	AbsHat0Y: {kind: axisHat, key: gin.ControllerDPadUp, other: gin.ControllerDPadDown},
+}
+
+// Scales a raw axis value into gin's range for the kind of axis. Values within
+// the axis' flat region are snapped to rest so that worn sticks don't drift.
+func normalizeAxis(kind axisKind, info AbsInfo, raw int32) float64 {
+	switch kind {
+	case axisStick, axisInvertedStick:
+		centre := (float64(info.Minimum) + float64(info.Maximum)) / 2
+		half := (float64(info.Maximum) - float64(info.Minimum)) / 2
+		offset := float64(raw) - centre
+		if half <= 0 || (offset <= float64(info.Flat) && offset >= -float64(info.Flat)) {
+			return 0
+		}
+		ret := max(-1, min(1, offset/half))
+		if kind == axisInvertedStick {
+			ret = -ret
+		}
+		return ret
+	case axisTrigger:
+		span := float64(info.Maximum) - float64(info.Minimum)
+		offset := float64(raw) - float64(info.Minimum)
+		if span <= 0 || offset <= float64(info.Flat) {
+			return 0
+		}
+		return max(0, min(1, offset/span))
+	}
+
+	if raw < 0 {
+		return -1
+	} else if raw > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Events that happen in the same millisecond keep their order so maps are
+// walked in a fixed order to keep Poll's output deterministic.
+func sortedKeys[K ~int | ~uint16, V any](m map[K]V) []K {
+	ret := make([]K, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i] < ret[j]
+	})
+	return ret
+}
+
+type controller struct {
+	index  gin.DeviceIndex
+	device Device
+
+	abs map[uint16]AbsInfo
+
+	// The last value reported for each key that isn't at rest.
+	values map[gin.KeyIndex]float64
+
+	// Keys reported during the current poll.
+	reported map[gin.KeyIndex]bool
+
+	// Set after SYN_DROPPED until the next SYN_REPORT.
+	dropping bool
+}
+
+// Turns controllers' evdev events into gin.OsEvents for
+// gin.DeviceTypeController keys. Each controller gets the lowest DeviceIndex
+// that's free when it's plugged in and keeps it until it's unplugged.
+//
+// Controllers only report changes so sticks and triggers that are held still
+// are reported again on every poll; gin's axis keys forget their value after
+// each frame otherwise. Those axis keys also sum the amounts they're given
+// within a frame so each poll only reports the latest value of each one.
+type Controllers struct {
+	source Source
+
+	// How often to look for controllers that have been plugged in.
+	ScanInterval time.Duration
+
+	scanned    bool
+	lastScanMs int64
+
+	byId map[string]*controller
+}
+
+// Scans for controllers once per second by default.
+func MakeControllers(source Source) *Controllers {
+	return &Controllers{
+		source:       source,
+		ScanInterval: time.Second,
+		byId:         map[string]*controller{},
+	}
+}
+
+func (cs *Controllers) nextFreeIndex() gin.DeviceIndex {
+	used := map[gin.DeviceIndex]bool{}
+	for _, c := range cs.byId {
+		used[c.index] = true
+	}
+	index := gin.DeviceIndex(0)
+	for used[index] {
+		index++
+	}
+	return index
+}
+
+func keyEvent(index gin.DeviceIndex, key gin.KeyIndex, amt float64, ms int64) gin.OsEvent {
+	return gin.OsEvent{
+		KeyId: gin.KeyId{
+			Device: gin.DeviceId{
+				Type:  gin.DeviceTypeController,
+				Index: index,
+			},
+			Index: key,
+		},
+		Press_amt:   amt,
+		TimestampMs: ms,
+	}
+}
+
+// Records a new value for the key and returns an event for it if it changed.
+func (c *controller) set(key gin.KeyIndex, amt float64, ms int64, out []gin.OsEvent) []gin.OsEvent {
+	c.reported[key] = true
+	if c.values[key] == amt {
+		return out
+	}
+	if amt == 0 {
+		delete(c.values, key)
+	} else {
+		c.values[key] = amt
+	}
+	return append(out, keyEvent(c.index, key, amt, ms))
+}
+
+func (c *controller) setAxis(code uint16, raw int32, ms int64, out []gin.OsEvent) []gin.OsEvent {
+	mapping, ok := axisMappings[code]
+	if !ok {
+		return out
+	}
+
+	amt := normalizeAxis(mapping.kind, c.abs[code], raw)
+	if mapping.kind != axisHat {
+		return c.set(mapping.key, amt, ms, out)
+	}
+
+	negative, positive := 0.0, 0.0
+	if amt < 0 {
+		negative = 1
+	} else if amt > 0 {
+		positive = 1
+	}
+	out = c.set(mapping.key, negative, ms, out)
+	return c.set(mapping.other, positive, ms, out)
+}
+
+func (cs *Controllers) connect(id string, horizon int64) []gin.OsEvent {
+	device, err := cs.source.Open(id)
+	if err != nil {
+		glog.WarningLogger().Warn("couldn't open controller", "id", id, "err", err)
+		return nil
+	}
+
+	c := &controller{
+		index:    cs.nextFreeIndex(),
+		device:   device,
+		abs:      map[uint16]AbsInfo{},
+		values:   map[gin.KeyIndex]float64{},
+		reported: map[gin.KeyIndex]bool{},
+	}
+	cs.byId[id] = c
+	glog.InfoLogger().Info("controller connected", "id", id, "name", device.Name(), "index", c.index)
+
+	// Report wherever the sticks and triggers start out.
+	var ret []gin.OsEvent
+	for _, code := range sortedKeys(axisMappings) {
+		info, ok := device.AbsInfo(code)
+		if !ok {
+			continue
+		}
+		c.abs[code] = info
+		ret = c.setAxis(code, info.Value, horizon, ret)
+	}
+	return ret
+}
+
+// Releases everything the controller was holding.
+func (cs *Controllers) disconnect(id string, horizon int64) []gin.OsEvent {
+	c := cs.byId[id]
+	delete(cs.byId, id)
+	c.device.Close()
+	glog.InfoLogger().Info("controller disconnected", "id", id, "index", c.index)
+
+	var ret []gin.OsEvent
+	for _, key := range sortedKeys(c.values) {
+		ret = append(ret, keyEvent(c.index, key, 0, horizon))
+	}
+	return ret
+}
+
+// Returns the ids of the connected controllers in order of their DeviceIndex.
+func (cs *Controllers) sortedIds() []string {
+	ids := make([]string, 0, len(cs.byId))
+	for id := range cs.byId {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return cs.byId[ids[i]].index < cs.byId[ids[j]].index
+	})
+	return ids
+}
+
+func (cs *Controllers) scan(horizon int64) []gin.OsEvent {
+	cs.scanned = true
+	cs.lastScanMs = horizon
+
+	ids, err := cs.source.Controllers()
+	if err != nil {
+		glog.WarningLogger().Warn("couldn't look for controllers", "err", err)
+		return nil
+	}
+
+	var ret []gin.OsEvent
+	present := map[string]bool{}
+	for _, id := range ids {
+		present[id] = true
+		if _, ok := cs.byId[id]; !ok {
+			ret = append(ret, cs.connect(id, horizon)...)
+		}
+	}
+	for _, id := range cs.sortedIds() {
+		if !present[id] {
+			ret = append(ret, cs.disconnect(id, horizon)...)
+		}
+	}
+	return ret
+}
+
+func (cs *Controllers) read(id string, horizon int64) []gin.OsEvent {
+	c := cs.byId[id]
+	clear(c.reported)
+
+	events, err := c.device.ReadEvents()
+
+	var ret []gin.OsEvent
+	for _, ev := range events {
+		// Nothing should come from the future but clamp anyway so that we
+		// never report an event past the horizon.
+		ms := min(ev.Time.Milliseconds(), horizon)
+
+		switch {
+		case ev.Type == EvSyn && ev.Code == SynDropped:
+			c.dropping = true
+		case ev.Type == EvSyn:
+			if c.dropping {
+				// The kernel dropped events; catch up with where the axes are now.
+				// Buttons catch up on their next change.
+				c.dropping = false
+				for _, code := range sortedKeys(c.abs) {
+					if info, ok := c.device.AbsInfo(code); ok {
+						ret = c.setAxis(code, info.Value, ms, ret)
+					}
+				}
+			}
+		case c.dropping:
+		case ev.Type == EvKey:
+			key, ok := buttonKeys[ev.Code]
+			// A value of 2 is an auto-repeat; gin doesn't need those.
+			if !ok || ev.Value == 2 {
+				continue
+			}
+			ret = c.set(key, float64(ev.Value), ms, ret)
+		case ev.Type == EvAbs:
+			ret = c.setAxis(ev.Code, ev.Value, ms, ret)
+		}
+	}
+
+	if err != nil {
+		return append(ret, cs.disconnect(id, horizon)...)
+	}
+
+	for _, key := range sortedKeys(c.values) {
+		if !c.reported[key] && axisKeys[key] {
+			ret = append(ret, keyEvent(c.index, key, c.values[key], horizon))
+		}
+	}
+	return ret
+}
+
+// Keys whose aggregator forgets its value after each frame.
+var axisKeys = map[gin.KeyIndex]bool{
+	gin.ControllerLeftStickX:   true,
+	gin.ControllerLeftStickY:   true,
+	gin.ControllerRightStickX:  true,
+	gin.ControllerRightStickY:  true,
+	gin.ControllerLeftTrigger:  true,
+	gin.ControllerRightTrigger: true,
+}
+
+// Returns the controller events up to the given horizon, sorted by time. Also
+// looks for controllers that were plugged in or unplugged if it's been
+// ScanInterval since the last look.
+func (cs *Controllers) Poll(horizon int64) []gin.OsEvent {
+	var ret []gin.OsEvent
+	if !cs.scanned || horizon-cs.lastScanMs >= cs.ScanInterval.Milliseconds() {
+		ret = cs.scan(horizon)
+	}
+
+	for _, id := range cs.sortedIds() {
+		ret = append(ret, cs.read(id, horizon)...)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].TimestampMs < ret[j].TimestampMs
+	})
+	return latestAxisValues(ret)
+}
+
+// Drops all but the last event for each axis key; the earlier ones were
+// superseded before the frame ended.
+func latestAxisValues(events []gin.OsEvent) []gin.OsEvent {
+	last := map[gin.KeyId]int{}
+	for i, ev := range events {
+		if axisKeys[ev.KeyId.Index] {
+			last[ev.KeyId] = i
+		}
+	}
+
+	ret := events[:0]
+	for i, ev := range events {
+		if axisKeys[ev.KeyId.Index] && last[ev.KeyId] != i {
+			continue
+		}
+		ret = append(ret, ev)
+	}
+	return ret
+}
+
+// Returns the DeviceIndex of each connected controller by its Source id.
+func (cs *Controllers) Indices() map[string]gin.DeviceIndex {
+	ret := make(map[string]gin.DeviceIndex, len(cs.byId))
+	for id, c := range cs.byId {
+		ret[id] = c.index
+	}
+	return ret
+}
+
+func (cs *Controllers) Close() {
+	for _, c := range cs.byId {
+		c.device.Close()
+	}
+	clear(cs.byId)
+}
@@ -0,0 +1,316 @@
+package evdev_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caffeine-storm/glop/gin"
+	"github.com/caffeine-storm/glop/gos/linux/evdev"
+	"github.com/caffeine-storm/glop/gos/linux/evdev/evdevtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stickInfo() evdev.AbsInfo {
+	return evdev.AbsInfo{Minimum: -32768, Maximum: 32767, Flat: 128}
+}
+
+func triggerInfo() evdev.AbsInfo {
+	return evdev.AbsInfo{Minimum: 0, Maximum: 255}
+}
+
+func hatInfo() evdev.AbsInfo {
+	return evdev.AbsInfo{Minimum: -1, Maximum: 1}
+}
+
+func givenAPad(name string) *evdevtest.FakeDevice {
+	return evdevtest.MakeFakeDevice(name, map[uint16]evdev.AbsInfo{
+		evdev.AbsX:     stickInfo(),
+		evdev.AbsY:     stickInfo(),
+		evdev.AbsRZ:    triggerInfo(),
+		evdev.AbsHat0X: hatInfo(),
+	})
+}
+
+func at(ms int64, ev evdev.Event) evdev.Event {
+	ev.Time = time.Duration(ms) * time.Millisecond
+	return ev
+}
+
+func button(code uint16, value int32) evdev.Event {
+	return evdev.Event{Type: evdev.EvKey, Code: code, Value: value}
+}
+
+func axis(code uint16, value int32) evdev.Event {
+	return evdev.Event{Type: evdev.EvAbs, Code: code, Value: value}
+}
+
+func syn() evdev.Event {
+	return evdev.Event{Type: evdev.EvSyn}
+}
+
+func padKey(index gin.DeviceIndex, key gin.KeyIndex) gin.KeyId {
+	return gin.KeyId{
+		Device: gin.DeviceId{
+			Type:  gin.DeviceTypeController,
+			Index: index,
+		},
+		Index: key,
+	}
+}
+
+func pressOf(events []gin.OsEvent, id gin.KeyId) (float64, bool) {
+	found := false
+	ret := 0.0
+	for _, ev := range events {
+		if ev.KeyId == id {
+			found = true
+			ret = ev.Press_amt
+		}
+	}
+	return ret, found
+}
+
+func TestControllerButtons(t *testing.T) {
+	source := evdevtest.MakeFakeSource()
+	pad := givenAPad("pad")
+	source.Plug("a", pad)
+	controllers := evdev.MakeControllers(source)
+	defer controllers.Close()
+
+	// Nothing is held when the pad shows up.
+	assert.Empty(t, controllers.Poll(0))
+
+	pad.Feed(at(10, button(evdev.BtnSouth, 1)), at(10, syn()))
+	events := controllers.Poll(16)
+	require.Len(t, events, 1)
+	assert.Equal(t, padKey(0, gin.ControllerButtonSouth), events[0].KeyId)
+	assert.Equal(t, 1.0, events[0].Press_amt)
+	assert.Equal(t, int64(10), events[0].TimestampMs)
+
+	// Auto-repeats and unmapped buttons are dropped.
+	pad.Feed(at(20, button(evdev.BtnSouth, 2)), at(20, button(0x2c0, 1)), at(20, syn()))
+	assert.Empty(t, controllers.Poll(32))
+
+	pad.Feed(at(40, button(evdev.BtnSouth, 0)), at(40, syn()))
+	events = controllers.Poll(48)
+	require.Len(t, events, 1)
+	assert.Equal(t, 0.0, events[0].Press_amt)
+}
+
+func TestControllerAxes(t *testing.T) {
+	source := evdevtest.MakeFakeSource()
+	pad := givenAPad("pad")
+	source.Plug("a", pad)
+	controllers := evdev.MakeControllers(source)
+	defer controllers.Close()
+	controllers.Poll(0)
+
+	t.Run("sticks ignore the flat region", func(t *testing.T) {
+		pad.Feed(at(1, axis(evdev.AbsX, 100)), at(1, syn()))
+		assert.Empty(t, controllers.Poll(2))
+	})
+
+	t.Run("sticks are scaled and Y points up", func(t *testing.T) {
+		pad.Feed(at(3, axis(evdev.AbsX, 32767)), at(3, axis(evdev.AbsY, -32768)), at(3, syn()))
+		events := controllers.Poll(4)
+
+		x, ok := pressOf(events, padKey(0, gin.ControllerLeftStickX))
+		assert.True(t, ok)
+		assert.Equal(t, 1.0, x)
+		y, ok := pressOf(events, padKey(0, gin.ControllerLeftStickY))
+		assert.True(t, ok)
+		assert.Equal(t, 1.0, y)
+	})
+
+	t.Run("held axes are reported every poll", func(t *testing.T) {
+		events := controllers.Poll(5)
+		require.Len(t, events, 2)
+		for _, ev := range events {
+			assert.Equal(t, 1.0, ev.Press_amt)
+			assert.Equal(t, int64(5), ev.TimestampMs)
+		}
+	})
+
+	t.Run("triggers go from 0 to 1", func(t *testing.T) {
+		pad.Feed(at(6, axis(evdev.AbsX, 0)), at(6, axis(evdev.AbsY, 0)), at(6, axis(evdev.AbsRZ, 51)), at(6, syn()))
+		events := controllers.Poll(7)
+
+		amt, ok := pressOf(events, padKey(0, gin.ControllerRightTrigger))
+		assert.True(t, ok)
+		assert.InDelta(t, 0.2, amt, 0.001)
+		amt, _ = pressOf(events, padKey(0, gin.ControllerLeftStickX))
+		assert.Equal(t, 0.0, amt)
+	})
+
+	t.Run("hats press d-pad buttons", func(t *testing.T) {
+		pad.Feed(at(8, axis(evdev.AbsRZ, 0)), at(8, axis(evdev.AbsHat0X, -1)), at(8, syn()))
+		events := controllers.Poll(9)
+		amt, ok := pressOf(events, padKey(0, gin.ControllerDPadLeft))
+		assert.True(t, ok)
+		assert.Equal(t, 1.0, amt)
+
+		pad.Feed(at(10, axis(evdev.AbsHat0X, 1)), at(10, syn()))
+		events = controllers.Poll(11)
+		amt, _ = pressOf(events, padKey(0, gin.ControllerDPadLeft))
+		assert.Equal(t, 0.0, amt)
+		amt, _ = pressOf(events, padKey(0, gin.ControllerDPadRight))
+		assert.Equal(t, 1.0, amt)
+	})
+}
+
+func TestControllerAxisMovesWithinAPoll(t *testing.T) {
+	source := evdevtest.MakeFakeSource()
+	pad := givenAPad("pad")
+	source.Plug("a", pad)
+	controllers := evdev.MakeControllers(source)
+	defer controllers.Close()
+
+	input := gin.Make()
+	stickX := input.GetKeyByParts(gin.ControllerLeftStickX, gin.DeviceTypeController, 0)
+	input.Think(0, controllers.Poll(0))
+
+	pad.Feed(
+		at(1, axis(evdev.AbsX, 32767)), at(1, syn()),
+		at(2, axis(evdev.AbsX, 16384)), at(2, syn()),
+		at(3, axis(evdev.AbsX, 32767)), at(3, syn()),
+		at(4, button(evdev.BtnSouth, 1)), at(4, syn()),
+	)
+	events := controllers.Poll(16)
+
+	var stickEvents []gin.OsEvent
+	for _, ev := range events {
+		if ev.KeyId == padKey(0, gin.ControllerLeftStickX) {
+			stickEvents = append(stickEvents, ev)
+		}
+	}
+	require.Len(t, stickEvents, 1)
+	assert.Equal(t, 1.0, stickEvents[0].Press_amt)
+	assert.Equal(t, int64(3), stickEvents[0].TimestampMs)
+	_, ok := pressOf(events, padKey(0, gin.ControllerButtonSouth))
+	assert.True(t, ok)
+
+	input.Think(16, events)
+	assert.Equal(t, 1.0, stickX.FramePressSum())
+}
+
+func TestControllerDroppedEvents(t *testing.T) {
+	source := evdevtest.MakeFakeSource()
+	pad := givenAPad("pad")
+	source.Plug("a", pad)
+	controllers := evdev.MakeControllers(source)
+	defer controllers.Close()
+	controllers.Poll(0)
+
+	// The kernel's buffer overflowed; only the latest axis state can be
+	// trusted.
+	pad.Abs[evdev.AbsRZ] = evdev.AbsInfo{Maximum: 255, Value: 255}
+	pad.Feed(
+		at(1, evdev.Event{Type: evdev.EvSyn, Code: evdev.SynDropped}),
+		at(1, button(evdev.BtnEast, 1)),
+		at(2, syn()),
+	)
+	events := controllers.Poll(3)
+
+	_, ok := pressOf(events, padKey(0, gin.ControllerButtonEast))
+	assert.False(t, ok)
+	amt, ok := pressOf(events, padKey(0, gin.ControllerRightTrigger))
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, amt)
+}
+
+func TestControllerHotPlug(t *testing.T) {
+	source := evdevtest.MakeFakeSource()
+	first := givenAPad("first")
+	second := givenAPad("second")
+	source.Plug("a", first)
+	source.Plug("b", second)
+
+	controllers := evdev.MakeControllers(source)
+	defer controllers.Close()
+	controllers.Poll(0)
+	assert.Equal(t, map[string]gin.DeviceIndex{"a": 0, "b": 1}, controllers.Indices())
+
+	first.Feed(at(10, button(evdev.BtnStart, 1)), at(10, syn()))
+	controllers.Poll(20)
+
+	t.Run("unplugging releases held keys", func(t *testing.T) {
+		source.Unplug("a")
+		events := controllers.Poll(30)
+
+		amt, ok := pressOf(events, padKey(0, gin.ControllerStart))
+		assert.True(t, ok)
+		assert.Equal(t, 0.0, amt)
+		assert.True(t, first.Closed())
+		assert.Equal(t, map[string]gin.DeviceIndex{"b": 1}, controllers.Indices())
+	})
+
+	t.Run("new controllers are found on the next scan", func(t *testing.T) {
+		source.Plug("c", givenAPad("third"))
+		controllers.Poll(40)
+		assert.NotContains(t, controllers.Indices(), "c")
+
+		controllers.Poll(40 + time.Second.Milliseconds())
+		assert.Equal(t, map[string]gin.DeviceIndex{"b": 1, "c": 0}, controllers.Indices())
+	})
+
+	t.Run("the second controller keeps its index", func(t *testing.T) {
+		second.Feed(at(2000, button(evdev.BtnSouth, 1)), at(2000, syn()))
+		events := controllers.Poll(2010)
+		require.Len(t, events, 1)
+		assert.Equal(t, padKey(1, gin.ControllerButtonSouth), events[0].KeyId)
+	})
+}
+
+func TestControllerRecording(t *testing.T) {
+	pad, err := evdevtest.LoadEvemu("testdata/xbox360.evemu", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "Microsoft X-Box 360 pad", pad.Name())
+
+	source := evdevtest.MakeFakeSource()
+	source.Plug("xbox", pad)
+	controllers := evdev.MakeControllers(source)
+	defer controllers.Close()
+
+	input := gin.Make()
+	south := input.GetKeyByParts(gin.ControllerButtonSouth, gin.DeviceTypeController, 0)
+	stickX := input.GetKeyByParts(gin.ControllerLeftStickX, gin.DeviceTypeController, 0)
+	stickY := input.GetKeyByParts(gin.ControllerLeftStickY, gin.DeviceTypeController, 0)
+	trigger := input.GetKeyByParts(gin.ControllerRightTrigger, gin.DeviceTypeController, 0)
+	dpadLeft := input.GetKeyByParts(gin.ControllerDPadLeft, gin.DeviceTypeController, 0)
+
+	// Plays the recording up to ms milliseconds after it started.
+	frame := func(ms int64) {
+		horizon := time.Second.Milliseconds() + ms
+		pad.Advance(time.Duration(horizon) * time.Millisecond)
+		input.Think(horizon, controllers.Poll(horizon))
+	}
+
+	frame(50)
+	assert.True(t, south.IsDown())
+
+	frame(150)
+	assert.False(t, south.IsDown())
+
+	// Within the stick's flat region.
+	frame(275)
+	assert.Equal(t, 0.0, stickX.FramePressAmt())
+
+	frame(350)
+	assert.Equal(t, 1.0, stickX.FramePressAmt())
+	assert.Equal(t, 1.0, stickY.FramePressAmt())
+
+	frame(450)
+	assert.Equal(t, 1.0, trigger.FramePressAmt())
+	assert.True(t, dpadLeft.IsDown())
+
+	// The stick hasn't moved since so it's still held over.
+	frame(475)
+	assert.Equal(t, 1.0, stickX.FramePressAmt())
+
+	frame(550)
+	assert.Equal(t, 0.0, stickX.FramePressAmt())
+	assert.Equal(t, 0.0, trigger.FramePressAmt())
+	assert.False(t, dpadLeft.IsDown())
+	assert.Zero(t, pad.Pending())
+}
@@ -0,0 +1,171 @@
+package evdev
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// ioctl request numbers from linux/input.h.
+const (
+	iocRead  = 2
+	iocWrite = 1
+)
+
+func ioc(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | 'E'<<8 | nr
+}
+
+func eviocgname(size uintptr) uintptr    { return ioc(iocRead, 0x06, size) }
+func eviocgbit(ev, size uintptr) uintptr { return ioc(iocRead, 0x20+ev, size) }
+func eviocgabs(abs uintptr) uintptr {
+	return ioc(iocRead, 0x40+abs, unsafe.Sizeof(AbsInfo{}))
+}
+
+var eviocsclockid = ioc(iocWrite, 0xa0, unsafe.Sizeof(int32(0)))
+
+func ioctl(fd, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Reads controllers from the event devices in a directory; normally
+// /dev/input.
+type DevInputSource struct {
+	Dir string
+}
+
+func MakeDevInputSource() *DevInputSource {
+	return &DevInputSource{Dir: "/dev/input"}
+}
+
+var _ Source = (*DevInputSource)(nil)
+
+// Returns the paths of the event devices that have gamepad or joystick
+// buttons.
+func (src *DevInputSource) Controllers() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(src.Dir, "event*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for _, path := range paths {
+		if isController(path) {
+			ret = append(ret, path)
+		}
+	}
+	return ret, nil
+}
+
+func isController(path string) bool {
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		// Most likely a permissions problem; we can't use it either way.
+		return false
+	}
+	defer syscall.Close(fd)
+
+	var keyBits [(0x2ff + 7) / 8]byte
+	if ioctl(uintptr(fd), eviocgbit(EvKey, uintptr(len(keyBits))), unsafe.Pointer(&keyBits[0])) != nil {
+		return false
+	}
+	hasKey := func(code int) bool {
+		return keyBits[code/8]&(1<<(code%8)) != 0
+	}
+	return hasKey(BtnGamepad) || hasKey(BtnJoystick)
+}
+
+func (src *DevInputSource) Open(path string) (Device, error) {
+	// We poll from the main thread's Think so reads mustn't block. An os.File
+	// would hand the descriptor to Go's poller which does block.
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open controller %q: %w", path, err)
+	}
+
+	// Timestamp events on the same clock as the native event loop.
+	clock := int32(1) // CLOCK_MONOTONIC
+	if err := ioctl(uintptr(fd), eviocsclockid, unsafe.Pointer(&clock)); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("couldn't set the clock for %q: %w", path, err)
+	}
+
+	var name [256]byte
+	if err := ioctl(uintptr(fd), eviocgname(uintptr(len(name))), unsafe.Pointer(&name[0])); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("couldn't get the name of %q: %w", path, err)
+	}
+
+	return &devInputDevice{
+		fd:   fd,
+		name: string(bytes.TrimRight(name[:], "\x00")),
+	}, nil
+}
+
+type devInputDevice struct {
+	fd   int
+	name string
+}
+
+func (dev *devInputDevice) Name() string {
+	return dev.name
+}
+
+func (dev *devInputDevice) AbsInfo(code uint16) (AbsInfo, bool) {
+	var ret AbsInfo
+	if err := ioctl(uintptr(dev.fd), eviocgabs(uintptr(code)), unsafe.Pointer(&ret)); err != nil {
+		return AbsInfo{}, false
+	}
+	return ret, true
+}
+
+// struct input_event on 64-bit platforms.
+type rawEvent struct {
+	Sec, Usec  int64
+	Type, Code uint16
+	Value      int32
+}
+
+func (dev *devInputDevice) ReadEvents() ([]Event, error) {
+	var ret []Event
+	buf := make([]byte, 64*int(unsafe.Sizeof(rawEvent{})))
+	for {
+		n, err := syscall.Read(dev.fd, buf)
+		if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+			return ret, nil
+		}
+		if err != nil {
+			return ret, err
+		}
+		if n <= 0 {
+			return ret, io.EOF
+		}
+
+		raw := make([]rawEvent, n/int(unsafe.Sizeof(rawEvent{})))
+		if err := binary.Read(bytes.NewReader(buf[:n]), binary.NativeEndian, raw); err != nil {
+			return ret, err
+		}
+		for _, ev := range raw {
+			ret = append(ret, Event{
+				Time:  time.Duration(ev.Sec)*time.Second + time.Duration(ev.Usec)*time.Microsecond,
+				Type:  ev.Type,
+				Code:  ev.Code,
+				Value: ev.Value,
+			})
+		}
+	}
+}
+
+func (dev *devInputDevice) Close() error {
+	return syscall.Close(dev.fd)
+}
@@ -0,0 +1,96 @@
+// Package evdev reads game controllers through the Linux kernel's evdev
+// interface (/dev/input/event*) and turns their input into gin.OsEvents.
+//
+// X11 doesn't report controllers so this runs alongside the native event
+// loop. Reading /dev/input usually needs membership in the 'input' group;
+// devices that can't be opened are skipped.
+package evdev
+
+import "time"
+
+// Event types and codes from linux/input-event-codes.h that we care about.
+const (
+	EvSyn = 0x00
+	EvKey = 0x01
+	EvAbs = 0x03
+
+	SynDropped = 3
+
+	BtnJoystick  = 0x120
+	BtnGamepad   = 0x130
+	BtnSouth     = 0x130
+	BtnEast      = 0x131
+	BtnNorth     = 0x133
+	BtnWest      = 0x134
+	BtnTL        = 0x136
+	BtnTR        = 0x137
+	BtnTL2       = 0x138
+	BtnTR2       = 0x139
+	BtnSelect    = 0x13a
+	BtnStart     = 0x13b
+	BtnMode      = 0x13c
+	BtnThumbL    = 0x13d
+	BtnThumbR    = 0x13e
+	BtnDPadUp    = 0x220
+	BtnDPadDown  = 0x221
+	BtnDPadLeft  = 0x222
+	BtnDPadRight = 0x223
+
+	AbsX     = 0x00
+	AbsY     = 0x01
+	AbsZ     = 0x02
+	AbsRX    = 0x03
+	AbsRY    = 0x04
+	AbsRZ    = 0x05
+	AbsGas   = 0x09
+	AbsBrake = 0x0a
+	AbsHat0X = 0x10
+	AbsHat0Y = 0x11
+)
+
+// One struct input_event.
+type Event struct {
+	// When the kernel saw the event, on the monotonic clock.
+	Time time.Duration
+
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// A struct input_absinfo; describes the range of an absolute axis.
+type AbsInfo struct {
+	Value   int32
+	Minimum int32
+	Maximum int32
+	Fuzz    int32
+
+	// Values within Flat of the centre should be treated as the centre.
+	Flat       int32
+	Resolution int32
+}
+
+// An open evdev device.
+type Device interface {
+	Name() string
+
+	// Returns the range of the given absolute axis; false if the device
+	// doesn't have it.
+	AbsInfo(code uint16) (AbsInfo, bool)
+
+	// Returns the events that have arrived since the last call without
+	// blocking. An error means the device has gone away.
+	ReadEvents() ([]Event, error)
+
+	Close() error
+}
+
+// Finds and opens controllers.
+type Source interface {
+	// Returns an identifier, like a device path, for each controller that's
+	// plugged in right now. An identifier may be reused for a different
+	// controller once the first one has been unplugged.
+	Controllers() ([]string, error)
+
+	Open(id string) (Device, error)
+}
@@ -0,0 +1,295 @@
+// Package evdevtest provides an evdev.Source that doesn't need real devices
+// or uinput so controller handling can be tested anywhere.
+package evdevtest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caffeine-storm/glop/gos/linux/evdev"
+)
+
+var ErrUnplugged = errors.New("evdevtest: device unplugged")
+
+// A controller that reports whatever it's fed.
+type FakeDevice struct {
+	DeviceName string
+	Abs        map[uint16]evdev.AbsInfo
+
+	mutex sync.Mutex
+
+	// Events that haven't been let through by Advance yet.
+	recorded []evdev.Event
+
+	// Events that the next ReadEvents will return.
+	ready []evdev.Event
+
+	unplugged bool
+	closed    bool
+}
+
+var _ evdev.Device = (*FakeDevice)(nil)
+
+func MakeFakeDevice(name string, abs map[uint16]evdev.AbsInfo) *FakeDevice {
+	if abs == nil {
+		abs = map[uint16]evdev.AbsInfo{}
+	}
+	return &FakeDevice{
+		DeviceName: name,
+		Abs:        abs,
+	}
+}
+
+func (dev *FakeDevice) Name() string {
+	return dev.DeviceName
+}
+
+func (dev *FakeDevice) AbsInfo(code uint16) (evdev.AbsInfo, bool) {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+	info, ok := dev.Abs[code]
+	return info, ok
+}
+
+func (dev *FakeDevice) ReadEvents() ([]evdev.Event, error) {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+
+	ret := dev.ready
+	dev.ready = nil
+	if dev.unplugged {
+		return ret, ErrUnplugged
+	}
+	return ret, nil
+}
+
+func (dev *FakeDevice) Close() error {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+	dev.closed = true
+	return nil
+}
+
+func (dev *FakeDevice) Closed() bool {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+	return dev.closed
+}
+
+// Makes events readable right away. Absolute axis events also update the
+// device's AbsInfo values, like the kernel does.
+func (dev *FakeDevice) Feed(events ...evdev.Event) {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+	dev.feed(events)
+}
+
+func (dev *FakeDevice) feed(events []evdev.Event) {
+	for _, ev := range events {
+		if ev.Type == evdev.EvAbs {
+			if info, ok := dev.Abs[ev.Code]; ok {
+				info.Value = ev.Value
+				dev.Abs[ev.Code] = info
+			}
+		}
+	}
+	dev.ready = append(dev.ready, events...)
+}
+
+// Makes the recorded events that happened at or before the given time
+// readable.
+func (dev *FakeDevice) Advance(to time.Duration) {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+
+	n := sort.Search(len(dev.recorded), func(i int) bool {
+		return dev.recorded[i].Time > to
+	})
+	dev.feed(dev.recorded[:n])
+	dev.recorded = dev.recorded[n:]
+}
+
+// The number of recorded events that Advance hasn't let through yet.
+func (dev *FakeDevice) Pending() int {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+	return len(dev.recorded)
+}
+
+// Reads a recording made with evemu-record. The device's name and axes come
+// from the 'N:' and 'A:' lines; its events from the 'E:' lines are held back
+// until Advance is called. Timestamps are kept as recorded, offset by start.
+func ParseEvemu(r io.Reader, start time.Duration) (*FakeDevice, error) {
+	dev := MakeFakeDevice("", nil)
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		kind, rest, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(rest)
+
+		var err error
+		switch kind {
+		case "N":
+			dev.DeviceName = strings.TrimSpace(rest)
+		case "A":
+			err = parseAbsLine(dev, fields)
+		case "E":
+			err = parseEventLine(dev, fields, start)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ParseEvemu: line %d: %w", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ParseEvemu: %w", err)
+	}
+
+	return dev, nil
+}
+
+// 'A: <code> <min> <max> <fuzz> <flat> [<resolution>]' with a hex code.
+func parseAbsLine(dev *FakeDevice, fields []string) error {
+	if len(fields) < 5 {
+		return fmt.Errorf("need at least 5 fields for an axis; got %q", fields)
+	}
+	code, err := strconv.ParseUint(fields[0], 16, 16)
+	if err != nil {
+		return err
+	}
+	values := make([]int32, 5)
+	for i, field := range fields[1:min(len(fields), 6)] {
+		val, err := strconv.ParseInt(field, 10, 32)
+		if err != nil {
+			return err
+		}
+		values[i] = int32(val)
+	}
+	dev.Abs[uint16(code)] = evdev.AbsInfo{
+		Minimum:    values[0],
+		Maximum:    values[1],
+		Fuzz:       values[2],
+		Flat:       values[3],
+		Resolution: values[4],
+	}
+	return nil
+}
+
+// 'E: <seconds>.<micros> <type> <code> <value>' with a hex type and code.
+func parseEventLine(dev *FakeDevice, fields []string, start time.Duration) error {
+	if len(fields) != 4 {
+		return fmt.Errorf("need 4 fields for an event; got %q", fields)
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return err
+	}
+	tp, err := strconv.ParseUint(fields[1], 16, 16)
+	if err != nil {
+		return err
+	}
+	code, err := strconv.ParseUint(fields[2], 16, 16)
+	if err != nil {
+		return err
+	}
+	value, err := strconv.ParseInt(fields[3], 10, 32)
+	if err != nil {
+		return err
+	}
+	dev.recorded = append(dev.recorded, evdev.Event{
+		Time:  start + time.Duration(seconds*float64(time.Second)).Round(time.Microsecond),
+		Type:  uint16(tp),
+		Code:  uint16(code),
+		Value: int32(value),
+	})
+	return nil
+}
+
+// Like ParseEvemu but reads from a file.
+func LoadEvemu(path string, start time.Duration) (*FakeDevice, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadEvemu: %w", err)
+	}
+	defer file.Close()
+	return ParseEvemu(file, start)
+}
+
+// An evdev.Source whose controllers are plugged in and out by hand.
+type FakeSource struct {
+	mutex   sync.Mutex
+	devices map[string]*FakeDevice
+}
+
+var _ evdev.Source = (*FakeSource)(nil)
+
+func MakeFakeSource() *FakeSource {
+	return &FakeSource{
+		devices: map[string]*FakeDevice{},
+	}
+}
+
+func (src *FakeSource) Plug(id string, dev *FakeDevice) {
+	src.mutex.Lock()
+	defer src.mutex.Unlock()
+
+	dev.mutex.Lock()
+	dev.unplugged = false
+	dev.closed = false
+	dev.mutex.Unlock()
+
+	src.devices[id] = dev
+}
+
+// The device stops showing up in Controllers and reading from it fails, like
+// a real device that's been unplugged.
+func (src *FakeSource) Unplug(id string) {
+	src.mutex.Lock()
+	defer src.mutex.Unlock()
+
+	dev, ok := src.devices[id]
+	if !ok {
+		panic(fmt.Errorf("FakeSource.Unplug: no device %q", id))
+	}
+	delete(src.devices, id)
+
+	dev.mutex.Lock()
+	dev.unplugged = true
+	dev.mutex.Unlock()
+}
+
+func (src *FakeSource) Controllers() ([]string, error) {
+	src.mutex.Lock()
+	defer src.mutex.Unlock()
+
+	ret := make([]string, 0, len(src.devices))
+	for id := range src.devices {
+		ret = append(ret, id)
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+func (src *FakeSource) Open(id string) (evdev.Device, error) {
+	src.mutex.Lock()
+	defer src.mutex.Unlock()
+
+	dev, ok := src.devices[id]
+	if !ok {
+		return nil, fmt.Errorf("FakeSource.Open: no device %q", id)
+	}
+	return dev, nil
+}
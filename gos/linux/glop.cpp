@@ -6,6 +6,9 @@
 #include <X11/X.h>
 #include <X11/Xlib.h>
 #include <X11/Xutil.h>
+#ifdef GLOP_XINPUT2
+#include <X11/extensions/XInput2.h>
+#endif
 
 #include <cctype>
 #include <chrono>
@@ -14,6 +17,7 @@
 #include <cstdlib>
 #include <cstring>
 #include <iostream>
+#include <map>
 #include <mutex>
 #include <ratio>
 #include <sstream>
@@ -32,6 +36,20 @@ int screen = 0;
 XIM xim = nullptr;
 Atom close_atom;
 
+// The major opcode of the XInput2 extension or -1 if we aren't using it.
+static int xi_opcode = -1;
+
+struct DeviceIdentity {
+  std::string name;
+  // Tells apart devices with the same name; 0 for the first one.
+  int ordinal;
+};
+
+// Identities of every device we've seen by XInput2 device id. Entries are
+// only replaced, never removed, so that events queued before a device went
+// away can still be attributed to it.
+static std::map<int, DeviceIdentity> device_identities;
+
 // Make sure the steady_clock implementation we're using supports millisecond
 // resolution.
 static_assert(std::ratio_less_equal<std::chrono::steady_clock::period,
@@ -125,6 +143,10 @@ GLXFBConfig *pickFbConfig(int *numConfigs) {
 
 static void glopSetCurrentContext(OsWindowData *data);
 static Bool EventTester(Display *display, XEvent *event, XPointer arg);
+#ifdef GLOP_XINPUT2
+static void HandleXIEvent(OsWindowData *data, XWindowAttributes const *attrs,
+                          XGenericEventCookie *cookie);
+#endif
 static bool SynthMotion(XWindowAttributes const *attrs,
                         const XMotionEvent &event, Window window,
                         struct GlopKeyEvent *ev, struct GlopKeyEvent *ev2);
@@ -137,6 +159,86 @@ static bool SynthKey(XWindowAttributes const *attrs, KeySym const &sym,
 
 extern "C" {
 
+#ifdef GLOP_XINPUT2
+// Gives each physical keyboard and mouse an identity. A device that shares
+// its name with one that's still plugged in gets the lowest free ordinal.
+static void RefreshDeviceIdentities() {
+  int num_devices;
+  XIDeviceInfo *devices = XIQueryDevice(display, XIAllDevices, &num_devices);
+  if (devices == nullptr) {
+    LOG_WARN("couldn't query XInput2 devices");
+    return;
+  }
+
+  std::map<int, DeviceIdentity> present;
+  for (int i = 0; i < num_devices; i++) {
+    XIDeviceInfo const &info = devices[i];
+    if (info.use != XISlavePointer && info.use != XISlaveKeyboard) continue;
+
+    auto found = device_identities.find(info.deviceid);
+    if (found != device_identities.end() && found->second.name == info.name) {
+      present[info.deviceid] = found->second;
+    } else {
+      present[info.deviceid] = DeviceIdentity{info.name, -1};
+    }
+  }
+  XIFreeDeviceInfo(devices);
+
+  for (auto &[id, identity] : present) {
+    if (identity.ordinal >= 0) continue;
+    int ordinal = 0;
+    bool taken = true;
+    while (taken) {
+      taken = false;
+      for (auto const &[otherId, other] : present) {
+        if (other.name == identity.name && other.ordinal == ordinal) {
+          taken = true;
+          ordinal++;
+          break;
+        }
+      }
+    }
+    identity.ordinal = ordinal;
+    LOG_DEBUG("device " << id << " is '" << identity.name << "' #" << ordinal);
+  }
+
+  for (auto const &[id, identity] : present) {
+    device_identities[id] = identity;
+  }
+}
+
+static void InitXInput2() {
+  int event, error;
+  if (!XQueryExtension(display, "XInputExtension", &xi_opcode, &event,
+                       &error)) {
+    LOG_WARN("XInput2 isn't available; all input comes from one device");
+    xi_opcode = -1;
+    return;
+  }
+
+  int major = 2, minor = 0;
+  if (XIQueryVersion(display, &major, &minor) != Success) {
+    LOG_WARN("XInput 2.0 isn't supported; all input comes from one device");
+    xi_opcode = -1;
+    return;
+  }
+
+  RefreshDeviceIdentities();
+}
+#endif
+
+int GlopGetDeviceIdentity(int32_t device_id, char *buf, size_t len) {
+  auto found = device_identities.find(device_id);
+  if (found == device_identities.end() || len == 0) return 0;
+
+  std::string identity = found->second.name;
+  if (found->second.ordinal > 0) {
+    identity += " #" + std::to_string(found->second.ordinal + 1);
+  }
+  std::snprintf(buf, len, "%s", identity.c_str());
+  return 1;
+}
+
 void GlopClearKeyEvent(struct GlopKeyEvent *event) {
   event->index = 0;
   event->device_type = 0;
@@ -146,6 +248,7 @@ void GlopClearKeyEvent(struct GlopKeyEvent *event) {
   event->cursor_y = 0;
   event->num_lock = 0;
   event->caps_lock = 0;
+  event->device_id = 0;
 }
 
 struct OsWindowData {
@@ -184,6 +287,10 @@ int64_t GlopInit() {
     }
 
     close_atom = XInternAtom(display, "WM_DELETE_WINDOW", False);
+
+#ifdef GLOP_XINPUT2
+    InitXInput2();
+#endif
   }
 
   return gt();
@@ -198,33 +305,46 @@ typedef GLXContext (*glXCreateContextAttribsARBProc)(Display *, GLXFBConfig,
                                                      GLXContext, Bool,
                                                      const int *);
 
-GLXContext createContextFromConfig(GLXFBConfig *fbConfig) {
+// A core profile drops the fixed-function pipeline; see
+// system.CoreProfileEnvVar.
+GLXContext createContextFromConfig(GLXFBConfig *fbConfig, bool coreProfile) {
   glXCreateContextAttribsARBProc glXCreateContextAttribsARB = nullptr;
   glXCreateContextAttribsARB =
       (glXCreateContextAttribsARBProc)glXGetProcAddressARB(
           (const GLubyte *)"glXCreateContextAttribsARB");
 
-  int context_attribs[] = {GLX_CONTEXT_MAJOR_VERSION_ARB,
-                           4,
-                           GLX_CONTEXT_MINOR_VERSION_ARB,
-                           5,
-                           GLX_CONTEXT_PROFILE_MASK_ARB,
-                           GLX_CONTEXT_COMPATIBILITY_PROFILE_BIT_ARB,
-                           None};
+  int compatibility_attribs[] = {GLX_CONTEXT_MAJOR_VERSION_ARB,
+                                 4,
+                                 GLX_CONTEXT_MINOR_VERSION_ARB,
+                                 5,
+                                 GLX_CONTEXT_PROFILE_MASK_ARB,
+                                 GLX_CONTEXT_COMPATIBILITY_PROFILE_BIT_ARB,
+                                 None};
+  int core_attribs[] = {GLX_CONTEXT_MAJOR_VERSION_ARB,
+                        3,
+                        GLX_CONTEXT_MINOR_VERSION_ARB,
+                        3,
+                        GLX_CONTEXT_PROFILE_MASK_ARB,
+                        GLX_CONTEXT_CORE_PROFILE_BIT_ARB,
+                        None};
+  int *context_attribs = coreProfile ? core_attribs : compatibility_attribs;
 
   GLXContext noSharedContext = nullptr;
   Bool useDirectRendering = True;
   GLXContext ret = glXCreateContextAttribsARB(
       display, *fbConfig, noSharedContext, useDirectRendering, context_attribs);
   if (ret == nullptr) {
-    LOG_FATAL("couldn't glXCreateContextAttribsARB");
+    LOG_FATAL("couldn't glXCreateContextAttribsARB for a "
+              << (coreProfile ? "3.3 core" : "4.5 compatibility")
+              << " context");
     std::abort();
   }
   return ret;
 }
 
 GlopWindowHandle GlopCreateWindowHandle(char const *title, int x, int y,
-                                        int width, int height) {
+                                        int width, int height,
+                                        int coreProfile) {
   OsWindowData *nw = new OsWindowData();
 
   if (x < 0 || y < 0 || width <= 0 || height <= 0) {
@@ -243,7 +363,7 @@ GlopWindowHandle GlopCreateWindowHandle(char const *title, int x, int y,
   }
 
   GLXContext shareList = nullptr;
-  nw->context = createContextFromConfig(fbConfigs);
+  nw->context = createContextFromConfig(fbConfigs, coreProfile != 0);
 
   // Grab the VisualInfo associated with the frame buffer config we chose.
   nw->vinfo = glXGetVisualFromFBConfig(display, fbConfigs[0]);
@@ -340,6 +460,27 @@ GlopWindowHandle GlopCreateWindowHandle(char const *title, int x, int y,
 
   XSetWMProtocols(display, nw->window, &close_atom, 1);
 
+#ifdef GLOP_XINPUT2
+  if (xi_opcode >= 0) {
+    // Selecting XInput2 events replaces the core events of the same types
+    // with ones that say which device they came from.
+    unsigned char windowBits[XIMaskLen(XI_LASTEVENT)] = {};
+    XISetMask(windowBits, XI_KeyPress);
+    XISetMask(windowBits, XI_KeyRelease);
+    XISetMask(windowBits, XI_ButtonPress);
+    XISetMask(windowBits, XI_ButtonRelease);
+    XISetMask(windowBits, XI_Motion);
+    XIEventMask windowMask = {XIAllMasterDevices, sizeof(windowBits),
+                              windowBits};
+    XISelectEvents(display, nw->window, &windowMask, 1);
+
+    unsigned char rootBits[XIMaskLen(XI_LASTEVENT)] = {};
+    XISetMask(rootBits, XI_HierarchyChanged);
+    XIEventMask rootMask = {XIAllDevices, sizeof(rootBits), rootBits};
+    XISelectEvents(display, RootWindow(display, screen), &rootMask, 1);
+  }
+#endif
+
   nw->inputcontext =
       XCreateIC(xim, XNInputStyle, XIMPreeditNothing | XIMStatusNothing,
                 XNClientWindow, nw->window, XNFocusWindow, nw->window, NULL);
@@ -434,6 +575,16 @@ int64_t GlopThink(GlopWindowHandle windowHandle) {
         }
         break;
       }
+
+#ifdef GLOP_XINPUT2
+      case GenericEvent:
+        if (XGetEventData(display, &event.xcookie)) {
+          HandleXIEvent(data, &attrs, &event.xcookie);
+          XFreeEventData(display, &event.xcookie);
+        }
+        break;
+#endif
+
       case FocusIn:
         XSetICFocus(data->inputcontext);
         break;
@@ -891,9 +1042,97 @@ static Bool EventTester(Display *display, XEvent *event, XPointer arg) {
   // arg == *OsWindowData
   // select for events targeted at this window
   OsWindowData *data = (OsWindowData *)(arg);
+#ifdef GLOP_XINPUT2
+  // XInput2 events don't say which window they're for until their data has
+  // been fetched; HandleXIEvent skips the ones for other windows.
+  if (event->type == GenericEvent && event->xcookie.extension == xi_opcode) {
+    return True;
+  }
+#endif
   return event->xany.window == data->window;
 }
 
+#ifdef GLOP_XINPUT2
+// Fills in the parts of a core event that SynthKey, SynthButton and
+// SynthMotion look at.
+template <typename CoreEvent>
+static CoreEvent ToCoreEvent(XIDeviceEvent const *xev, int type) {
+  CoreEvent ret;
+  std::memset(&ret, 0, sizeof(ret));
+  ret.type = type;
+  ret.display = xev->display;
+  ret.window = xev->event;
+  ret.root = xev->root;
+  ret.subwindow = xev->child;
+  ret.time = xev->time;
+  ret.x = int(xev->event_x);
+  ret.y = int(xev->event_y);
+  ret.x_root = int(xev->root_x);
+  ret.y_root = int(xev->root_y);
+  ret.state = xev->mods.effective | (xev->group.effective << 13);
+  ret.same_screen = True;
+  return ret;
+}
+
+static void HandleXIEvent(OsWindowData *data, XWindowAttributes const *attrs,
+                          XGenericEventCookie *cookie) {
+  if (cookie->extension != xi_opcode) return;
+
+  if (cookie->evtype == XI_HierarchyChanged) {
+    RefreshDeviceIdentities();
+    return;
+  }
+
+  auto const *xev = static_cast<XIDeviceEvent const *>(cookie->data);
+  if (xev->event != data->window) return;
+
+  struct GlopKeyEvent ev, ev2;
+  GlopClearKeyEvent(&ev);
+  GlopClearKeyEvent(&ev2);
+  int count = 0;
+
+  switch (cookie->evtype) {
+    case XI_KeyPress:
+    case XI_KeyRelease: {
+      // XInput2 flags auto-repeats instead of sending fake releases.
+      if (xev->flags & XIKeyRepeat) return;
+
+      bool pushed = cookie->evtype == XI_KeyPress;
+      XKeyEvent core =
+          ToCoreEvent<XKeyEvent>(xev, pushed ? KeyPress : KeyRelease);
+      core.keycode = xev->detail;
+
+      char buf[2];
+      KeySym sym;
+      XLookupString(&core, buf, sizeof(buf), &sym, nullptr);
+      if (SynthKey(attrs, sym, pushed, core, data->window, &ev)) count = 1;
+      break;
+    }
+
+    case XI_ButtonPress:
+    case XI_ButtonRelease: {
+      bool pushed = cookie->evtype == XI_ButtonPress;
+      XButtonEvent core =
+          ToCoreEvent<XButtonEvent>(xev, pushed ? ButtonPress : ButtonRelease);
+      core.button = xev->detail;
+      if (SynthButton(attrs, pushed, core, data->window, &ev)) count = 1;
+      break;
+    }
+
+    case XI_Motion: {
+      XMotionEvent core = ToCoreEvent<XMotionEvent>(xev, MotionNotify);
+      if (SynthMotion(attrs, core, data->window, &ev, &ev2)) count = 2;
+      break;
+    }
+  }
+
+  ev.device_id = xev->sourceid;
+  ev2.device_id = xev->sourceid;
+  if (count >= 1) data->events.push_back(ev);
+  if (count >= 2) data->events.push_back(ev2);
+}
+#endif
+
 void glopDestroyWindow(OsWindowData *data) { delete data; }
 
 void glopGetWindowPosition(const OsWindowData *data, int *x, int *y) {
@@ -101,6 +101,10 @@ struct GlopKeyEvent {
 
   int num_lock;
   int caps_lock;
+
+  // The XInput2 id of the physical device that sent the event; 0 if unknown.
+  // Ids get reused so use GlopGetDeviceIdentity to tell devices apart.
+  int32_t device_id;
 };
 
 void GlopClearKeyEvent(struct GlopKeyEvent* event);
@@ -112,9 +116,12 @@ typedef struct {
 uint64_t GetNativeHandle(GlopWindowHandle);
 
 int64_t GlopInit();
-// Returns an opaque handle for further window operations.
+// Returns an opaque handle for further window operations. If |coreProfile| is
+// non-zero, the window's context is a 3.3 core-profile context; otherwise it's
+// a 4.5 compatibility-profile context.
 GlopWindowHandle GlopCreateWindowHandle(char const* title, int x, int y,
-                                        int width, int height);
+                                        int width, int height,
+                                        int coreProfile);
 
 // Returns the current time like GetInputEvents' |_horizon|.
 int64_t GlopThink(GlopWindowHandle);
@@ -127,6 +134,11 @@ void GlopGetInputEvents(GlopWindowHandle, struct GlopKeyEvent** events_ret,
                         size_t* num_events, int64_t* horizon);
 void GlopEnableVSync(int enable);
 
+// Writes a NUL-terminated name for the device with the given |device_id| that
+// stays the same if the device is unplugged and plugged back in. Returns 0 if
+// the device is unknown, e.g. because glop was built without XInput2.
+int GlopGetDeviceIdentity(int32_t device_id, char* buf, size_t len);
+
 #ifdef __cplusplus
 }  // extern "C"
 #endif
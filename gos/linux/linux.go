@@ -7,16 +7,25 @@ import "C"
 
 import (
 	"fmt"
+	"sort"
 	"unsafe"
 
 	"github.com/caffeine-storm/glop/gin"
 	"github.com/caffeine-storm/glop/glog"
+	"github.com/caffeine-storm/glop/gos/linux/evdev"
 	"github.com/caffeine-storm/glop/system"
 )
 
 type SystemObject struct {
 	horizon      int64
 	windowHandle C.GlopWindowHandle // Handle to native per-window data
+
+	// X11 doesn't know about game controllers so they're read from evdev.
+	controllers *evdev.Controllers
+
+	// Where the cursor was for the latest native event, in window coords.
+	// Controller events don't have a cursor of their own.
+	cursorX, cursorY int
 }
 
 func (linux *SystemObject) Startup() int64 {
@@ -31,9 +40,14 @@ func (linux *SystemObject) Quit() {
 	panic("Not implemented on linux")
 }
 
-// Call after runtime.LockOSThread(), *NOT* in an init function
+// Call after runtime.LockOSThread(), *NOT* in an init function. The window's
+// context is a core-profile context if system.CoreProfileRequested().
 func (linux *SystemObject) CreateWindow(x, y, width, height int) system.NativeWindowHandle {
-	linux.windowHandle = C.GlopCreateWindowHandle(C.CString("linux window"), C.int(x), C.int(y), C.int(width), C.int(height))
+	coreProfile := C.int(0)
+	if system.CoreProfileRequested() {
+		coreProfile = 1
+	}
+	linux.windowHandle = C.GlopCreateWindowHandle(C.CString("linux window"), C.int(x), C.int(y), C.int(width), C.int(height), coreProfile)
 	return fmt.Sprintf("%d", C.GetNativeHandle(linux.windowHandle))
 }
 
@@ -54,7 +68,7 @@ func nativeDeviceToGinDevice(n C.short) gin.DeviceType {
 		return gin.DeviceTypeMouse
 	case C.glopDeviceDerived:
 		return gin.DeviceTypeDerived
-		// gin.DeviceTypeController is not supported right now
+		// gin.DeviceTypeController events come from evdev, not native code.
 	}
 
 	panic(fmt.Errorf("nativeDeviceToGinDevice: got invalid value %d", n))
@@ -71,17 +85,29 @@ func NewNativeMouseEvent(x, y int) *NativeKeyEvent {
 	}
 }
 
+// Used by tests to pretend that an event came from a particular device.
+func (nativeEvent *NativeKeyEvent) WithDeviceId(id int) *NativeKeyEvent {
+	nativeEvent.device_id = C.int32_t(id)
+	return nativeEvent
+}
+
 type RawCursorToWindowCoordser interface {
 	RawCursorToWindowCoords(x, y int) (int, int)
 }
 
+// Optionally implemented by NativeToGin's RawCursorToWindowCoordser to name
+// the device behind a native device id; see gin.OsEvent.DeviceIdentity.
+type DeviceIdentifier interface {
+	DeviceIdentity(nativeDeviceId int) string
+}
+
 func NativeToGin(linux RawCursorToWindowCoordser, nativeEvent *NativeKeyEvent) gin.OsEvent {
 	wx, wy := linux.RawCursorToWindowCoords(int(nativeEvent.cursor_x), int(nativeEvent.cursor_y))
 	keyId := gin.KeyId{
 		Device: gin.DeviceId{
 			Type: nativeDeviceToGinDevice(nativeEvent.device_type),
-			// TODO(#28): shouldn't we be indexing devices?
-			Index: 0, // gin.DeviceIndex(nativeEvent.device_index),
+			// gin.Input picks the index from DeviceIdentity if there is one.
+			Index: 0,
 		},
 		Index: gin.KeyIndex(nativeEvent.index),
 	}
@@ -92,6 +118,9 @@ func NativeToGin(linux RawCursorToWindowCoordser, nativeEvent *NativeKeyEvent) g
 		X:           wx,
 		Y:           wy,
 	}
+	if identifier, ok := linux.(DeviceIdentifier); ok && nativeEvent.device_id != 0 {
+		ret.DeviceIdentity = identifier.DeviceIdentity(int(nativeEvent.device_id))
+	}
 
 	glog.TraceLogger().Trace("native to gin", "native", *nativeEvent, "ret", ret)
 
@@ -143,9 +172,36 @@ func (linux *SystemObject) GetInputEvents() ([]gin.OsEvent, int64) {
 		i++
 	}
 
+	if len(events) > 0 {
+		last := events[len(events)-1]
+		linux.cursorX, linux.cursorY = last.X, last.Y
+	}
+
+	controllerEvents := linux.controllers.Poll(linux.horizon)
+	if len(controllerEvents) == 0 {
+		return events, linux.horizon
+	}
+	for i := range controllerEvents {
+		controllerEvents[i].X, controllerEvents[i].Y = linux.cursorX, linux.cursorY
+	}
+	events = append(events, controllerEvents...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].TimestampMs < events[j].TimestampMs
+	})
+
 	return events, linux.horizon
 }
 
+// Returns "" for devices that native code doesn't know; without the
+// 'xinput2' build tag that's all of them.
+func (linux *SystemObject) DeviceIdentity(nativeDeviceId int) string {
+	var buf [256]C.char
+	if C.GlopGetDeviceIdentity(C.int32_t(nativeDeviceId), &buf[0], C.size_t(len(buf))) == 0 {
+		return ""
+	}
+	return C.GoString(&buf[0])
+}
+
 func (linux *SystemObject) HideCursor(hide bool) {
 }
 
@@ -174,7 +230,9 @@ func (linux *SystemObject) EnableVSync(enable bool) {
 }
 
 func New() *SystemObject {
-	ret := &SystemObject{}
+	ret := &SystemObject{
+		controllers: evdev.MakeControllers(evdev.MakeDevInputSource()),
+	}
 	ret.Startup()
 	return ret
 }
@@ -43,6 +43,41 @@ func TestNativeToGin(t *testing.T) {
 	}
 }
 
+type stubIdentifier struct {
+	stubCoordser
+	names map[int]string
+}
+
+func (m *stubIdentifier) DeviceIdentity(nativeDeviceId int) string {
+	return m.names[nativeDeviceId]
+}
+
+func TestNativeToGinDeviceIdentity(t *testing.T) {
+	identifier := &stubIdentifier{
+		names: map[int]string{
+			7: "Logitech USB Optical Mouse",
+		},
+	}
+
+	t.Run("known devices are named", func(t *testing.T) {
+		ginEvent := linux.NativeToGin(identifier, GivenAClickAt(0, 0).WithDeviceId(7))
+		if ginEvent.DeviceIdentity != "Logitech USB Optical Mouse" {
+			t.Fatalf("expected the device's identity, got %q", ginEvent.DeviceIdentity)
+		}
+	})
+
+	t.Run("unknown devices aren't", func(t *testing.T) {
+		ginEvent := linux.NativeToGin(identifier, GivenAClickAt(0, 0))
+		if ginEvent.DeviceIdentity != "" {
+			t.Fatalf("expected no identity, got %q", ginEvent.DeviceIdentity)
+		}
+		ginEvent = linux.NativeToGin(StubCoordser(0, 0), GivenAClickAt(0, 0).WithDeviceId(7))
+		if ginEvent.DeviceIdentity != "" {
+			t.Fatalf("expected no identity without a DeviceIdentifier, got %q", ginEvent.DeviceIdentity)
+		}
+	})
+}
+
 func TestGlopCreateWindowHandle(t *testing.T) {
 	t.Run("can create window", func(t *testing.T) {
 		toRunUnderGLContext := make(chan func())
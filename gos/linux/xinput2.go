@@ -0,0 +1,11 @@
+//go:build xinput2
+
+package linux
+
+// Building with the 'xinput2' tag tells keyboards and mice apart through
+// XInput2 so that each gets its own gin.DeviceIndex. It needs libXi and its
+// headers (libxi-dev on Debian).
+
+// #cgo CPPFLAGS: -DGLOP_XINPUT2
+// #cgo LDFLAGS: -lXi
+import "C"
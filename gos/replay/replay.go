@@ -0,0 +1,151 @@
+// Package replay implements system.Os by playing back input recorded with
+// gin/ginrecord. Each call to GetInputEvents returns the next recorded frame
+// with its original timestamps and horizon so gin.Input.Think produces the
+// same EventGroups it did while recording.
+//
+// Tests can use it in place of systemtest.Driver; it needs neither X nor
+// xdotool.
+package replay
+
+import (
+	"time"
+
+	"github.com/caffeine-storm/glop/gin"
+	"github.com/caffeine-storm/glop/gin/ginrecord"
+	"github.com/caffeine-storm/glop/system"
+)
+
+type SystemObject struct {
+	// Handles windows and GL if set; its own input is thrown away. With no
+	// window, CreateWindow returns WindowHandle and the other window calls do
+	// nothing.
+	Window system.Os
+
+	// Makes Think wait so that frames play back at the pace they were
+	// recorded at, e.g. to watch a bug report. Tests leave it off.
+	Paced bool
+
+	frames  []ginrecord.Frame
+	next    int
+	horizon int64
+
+	// When playback started, for Paced.
+	start time.Time
+}
+
+var _ system.Os = (*SystemObject)(nil)
+
+// The handle returned from CreateWindow when there's no Window.
+const WindowHandle = "replay"
+
+// window may be nil.
+func New(window system.Os, recording *ginrecord.Recording) *SystemObject {
+	return &SystemObject{
+		Window: window,
+		frames: recording.Frames,
+	}
+}
+
+// Returns a System that plays the recording into input with no window.
+func MakeSystem(recording *ginrecord.Recording, input *gin.Input) (system.System, *SystemObject) {
+	os := New(nil, recording)
+	sys := system.Make(os, input)
+	sys.Startup()
+	return sys, os
+}
+
+// Recorded timestamps are relative to startup already so playback starts at
+// 0.
+func (replay *SystemObject) Startup() int64 {
+	if replay.Window != nil {
+		replay.Window.Startup()
+	}
+	replay.start = time.Now()
+	return 0
+}
+
+func (replay *SystemObject) Think() int64 {
+	if replay.Window != nil {
+		replay.Window.Think()
+	}
+	if replay.Paced && replay.next < len(replay.frames) {
+		due := replay.start.Add(time.Duration(replay.frames[replay.next].HorizonMs) * time.Millisecond)
+		time.Sleep(time.Until(due))
+	}
+	return replay.horizon
+}
+
+// Returns the next recorded frame. Once they've all been played, there are no
+// more events and the horizon stays put.
+func (replay *SystemObject) GetInputEvents() ([]gin.OsEvent, int64) {
+	if replay.Window != nil {
+		// Drain the window's input so that it doesn't pile up.
+		replay.Window.GetInputEvents()
+	}
+	if replay.next >= len(replay.frames) {
+		return nil, replay.horizon
+	}
+
+	frame := replay.frames[replay.next]
+	replay.next++
+	replay.horizon = frame.HorizonMs
+
+	// system.System rewrites timestamps in place; keep the recording intact.
+	events := make([]gin.OsEvent, len(frame.Events))
+	copy(events, frame.Events)
+	return events, frame.HorizonMs
+}
+
+// True once every frame has been played.
+func (replay *SystemObject) Done() bool {
+	return replay.next >= len(replay.frames)
+}
+
+// Calls sys.Think until every frame has been played and returns each frame's
+// EventGroups. sys must be using this SystemObject.
+func (replay *SystemObject) PlayAll(sys system.System) [][]gin.EventGroup {
+	var ret [][]gin.EventGroup
+	for !replay.Done() {
+		sys.Think()
+		ret = append(ret, sys.GetInputEvents())
+	}
+	return ret
+}
+
+func (replay *SystemObject) CreateWindow(x, y, width, height int) system.NativeWindowHandle {
+	if replay.Window != nil {
+		return replay.Window.CreateWindow(x, y, width, height)
+	}
+	return WindowHandle
+}
+
+func (replay *SystemObject) HideCursor(hide bool) {
+	if replay.Window != nil {
+		replay.Window.HideCursor(hide)
+	}
+}
+
+func (replay *SystemObject) GetWindowDims() (int, int, int, int) {
+	if replay.Window != nil {
+		return replay.Window.GetWindowDims()
+	}
+	return 0, 0, 0, 0
+}
+
+func (replay *SystemObject) SetWindowSize(width, height int) {
+	if replay.Window != nil {
+		replay.Window.SetWindowSize(width, height)
+	}
+}
+
+func (replay *SystemObject) SwapBuffers() {
+	if replay.Window != nil {
+		replay.Window.SwapBuffers()
+	}
+}
+
+func (replay *SystemObject) EnableVSync(enable bool) {
+	if replay.Window != nil {
+		replay.Window.EnableVSync(enable)
+	}
+}
@@ -0,0 +1,191 @@
+package replay_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/caffeine-storm/glop/gin"
+	"github.com/caffeine-storm/glop/gin/aggregator"
+	"github.com/caffeine-storm/glop/gin/ginrecord"
+	"github.com/caffeine-storm/glop/gos/replay"
+	"github.com/caffeine-storm/glop/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Stands in for a real OS; hands out one scripted frame per Think.
+type scriptedOs struct {
+	system.Os
+	startMs int64
+	frames  []ginrecord.Frame
+	next    int
+}
+
+func (s *scriptedOs) Startup() int64 {
+	return s.startMs
+}
+
+func (s *scriptedOs) Think() int64 {
+	return s.startMs + s.frames[s.next].HorizonMs
+}
+
+func (s *scriptedOs) GetInputEvents() ([]gin.OsEvent, int64) {
+	frame := s.frames[s.next]
+	s.next++
+	events := make([]gin.OsEvent, len(frame.Events))
+	for i, event := range frame.Events {
+		event.TimestampMs += s.startMs
+		events[i] = event
+	}
+	return events, s.startMs + frame.HorizonMs
+}
+
+func event(deviceType gin.DeviceType, key gin.KeyIndex, amt float64, ms int64) gin.OsEvent {
+	return gin.OsEvent{
+		KeyId: gin.KeyId{
+			Device: gin.DeviceId{Type: deviceType},
+			Index:  key,
+		},
+		Press_amt:   amt,
+		TimestampMs: ms,
+		X:           int(ms),
+		Y:           int(ms * 2),
+	}
+}
+
+func session() []ginrecord.Frame {
+	return []ginrecord.Frame{
+		{HorizonMs: 16, Events: []gin.OsEvent{
+			event(gin.DeviceTypeMouse, gin.MouseXAxis, 10, 3),
+			event(gin.DeviceTypeMouse, gin.MouseYAxis, 20, 3),
+			event(gin.DeviceTypeKeyboard, gin.LeftShift, 1, 9),
+		}},
+		{HorizonMs: 33, Events: []gin.OsEvent{
+			event(gin.DeviceTypeKeyboard, gin.KeyW, 1, 20),
+			event(gin.DeviceTypeMouse, gin.MouseWheelVertical, -1, 25),
+		}},
+		{HorizonMs: 50},
+		{HorizonMs: 66, Events: []gin.OsEvent{
+			event(gin.DeviceTypeKeyboard, gin.LeftShift, 0, 51),
+			event(gin.DeviceTypeController, gin.ControllerLeftStickY, 0.75, 60),
+			event(gin.DeviceTypeKeyboard, gin.KeyW, 0, 64),
+		}},
+	}
+}
+
+func withActions(input *gin.Input) *gin.Input {
+	input.BindAction("run", input.MakeBinding(gin.AnyKeyW, []gin.KeyId{gin.AnyLeftShift}, []bool{true}))
+	input.BindAction("walk", input.MakeBinding(gin.AnyKeyW, nil, nil))
+	return input
+}
+
+// EventGroups refer to keys of a particular gin.Input; describe them in a way
+// that can be compared between Inputs.
+func describe(frames [][]gin.EventGroup) []string {
+	var ret []string
+	for i, groups := range frames {
+		for _, group := range groups {
+			position := "none"
+			if group.HasMousePosition() {
+				x, y := group.GetMousePosition()
+				position = fmt.Sprintf("%d,%d", x, y)
+			}
+			for _, ev := range group.Events {
+				ret = append(ret, fmt.Sprintf("frame %d at %dms mouse %s: %s %v %v amt %v",
+					i, group.TimestampMs, position, ev.Key.Name(), ev.Key.Id().Device, ev.Type, ev.Key.CurPressAmt()))
+			}
+		}
+	}
+	return ret
+}
+
+func TestReplayMatchesRecording(t *testing.T) {
+	var recorded bytes.Buffer
+	writer, err := ginrecord.NewWriter(&recorded)
+	require.NoError(t, err)
+
+	live := system.Make(&scriptedOs{startMs: 123456, frames: session()}, withActions(gin.Make()))
+	live.Startup()
+	live.RecordInput(writer)
+	var liveFrames [][]gin.EventGroup
+	for range session() {
+		live.Think()
+		liveFrames = append(liveFrames, live.GetInputEvents())
+	}
+	require.NoError(t, writer.Err())
+
+	recording, err := ginrecord.Read(&recorded)
+	require.NoError(t, err)
+	require.Len(t, recording.Frames, len(session()))
+	assert.Equal(t, int64(16), recording.Frames[0].HorizonMs, "timestamps are relative to startup")
+
+	sys, os := replay.MakeSystem(recording, withActions(gin.Make()))
+	replayedFrames := os.PlayAll(sys)
+
+	assert.NotEmpty(t, describe(liveFrames))
+	assert.Equal(t, describe(liveFrames), describe(replayedFrames))
+
+	// Playing the recording again gives the same result too.
+	sys, os = replay.MakeSystem(recording, withActions(gin.Make()))
+	assert.Equal(t, describe(liveFrames), describe(os.PlayAll(sys)))
+}
+
+type clickListener struct {
+	clicks []string
+}
+
+func (l *clickListener) HandleEventGroup(group gin.EventGroup) {
+	if group.IsPressed(gin.AnyMouseLButton) {
+		x, y := group.GetMousePosition()
+		l.clicks = append(l.clicks, fmt.Sprintf("%d,%d", x, y))
+	}
+}
+
+func (*clickListener) Think(int64) {}
+
+func TestReplayInPlaceOfDriver(t *testing.T) {
+	recording, err := ginrecord.Load("testdata/click.jsonl")
+	require.NoError(t, err)
+
+	input := gin.Make()
+	sys, os := replay.MakeSystem(recording, input)
+	listener := &clickListener{}
+	sys.AddInputListener(listener)
+
+	assert.Equal(t, replay.WindowHandle, sys.CreateWindow(0, 0, 64, 64))
+	for !os.Done() {
+		sys.Think()
+	}
+	assert.Equal(t, []string{"30,40"}, listener.clicks)
+	assert.False(t, input.GetKeyById(gin.AnyMouseLButton).IsDown())
+
+	// Nothing more happens once the recording's over.
+	assert.Equal(t, int64(50), sys.Think())
+	assert.Empty(t, sys.GetInputEvents())
+}
+
+func TestPacedReplay(t *testing.T) {
+	recording := &ginrecord.Recording{
+		Version: ginrecord.Version,
+		Frames: []ginrecord.Frame{
+			{HorizonMs: 0},
+			{HorizonMs: 30, Events: []gin.OsEvent{
+				event(gin.DeviceTypeKeyboard, gin.KeyA, 1, 25),
+			}},
+		},
+	}
+
+	os := replay.New(nil, recording)
+	os.Paced = true
+	sys := system.Make(os, gin.Make())
+	sys.Startup()
+
+	start := time.Now()
+	events := os.PlayAll(sys)
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	require.Len(t, events, 2)
+	assert.Len(t, events[1], 1)
+	assert.Equal(t, aggregator.Press, events[1][0].PrimaryEvent().Type)
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/caffeine-storm/gl"
 	"github.com/caffeine-storm/glop/glog"
 	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/batch"
+	"github.com/caffeine-storm/mathgl"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -43,6 +45,51 @@ const font_fragment_shader string = `
   }
 `
 
+// The name that dictionaries register their shader under in a
+// render.ShaderBank.
+const FontShaderName = "glop.font"
+
+// Like font_vertex_shader and font_fragment_shader but laid out for
+// render.QuadBatch; see AddString.
+const font_batch_vertex_shader string = `
+  #version 330 core
+  uniform mat4 glop_ModelViewProjection;
+
+  layout(location = 0) in vec2 position;
+  layout(location = 1) in vec2 texCoord;
+  layout(location = 2) in vec4 colour;
+
+  out vec2 fragTexCoord;
+  out vec4 fragColour;
+
+  void main() {
+    gl_Position = glop_ModelViewProjection * vec4(position, 0.0, 1.0);
+    fragTexCoord = texCoord;
+    fragColour = colour;
+  }
+`
+
+const font_batch_fragment_shader string = `
+  #version 330 core
+  uniform vec4 glop_Colour;
+  uniform sampler2D glop_Texture;
+
+  in vec2 fragTexCoord;
+  in vec4 fragColour;
+
+  out vec4 outColour;
+
+  void main() {
+    float dist = texture(glop_Texture, fragTexCoord).a;
+    float alpha = smoothstep(0.05, 0.95, dist);
+    outColour = fragColour * glop_Colour * vec4(1.0, 1.0, 1.0, alpha);
+  }
+`
+
+// The name that AddString registers its batch.SpriteBatch-compatible shader
+// under.
+const FontBatchShaderName = "glop.font.batch"
+
 type Justification int
 
 const (
@@ -275,8 +322,10 @@ func (font *RasteredFont) StringPixelWidth(s string) float64 {
 	return width
 }
 
-func buildBlittingData(s string, d *Dictionary, x_pos_px, y_pos_px, height_px float64) blitBuffer {
-	blittingData := blitBuffer{}
+// Lays out one quad per rune of 's'. The quads' texture co-ordinates refer to
+// the dictionary's glyph texture.
+func glyphQuads(s string, d *Dictionary, x_pos_px, y_pos_px, height_px float64) []render.Quad {
+	var ret []render.Quad
 	var prev rune
 	verticalScale := height_px / float64(d.MaxHeight())
 	horizontalScale := verticalScale
@@ -288,8 +337,30 @@ func buildBlittingData(s string, d *Dictionary, x_pos_px, y_pos_px, height_px fl
 		info := d.getInfo(r)
 		xleft_px := x_pos_px
 		xright_px := x_pos_px + float64(info.Bounds.Dx())*horizontalScale
-		ybot_px := float32(y_pos_px)
-		ytop_px := float32(y_pos_px + height_px)
+
+		// Note: the texture is loaded 'upside down' so we flip our y-coordinates
+		// in texture-space.
+		quad := render.Quad{
+			X0:     float32(xleft_px),
+			Y0:     float32(y_pos_px),
+			X1:     float32(xright_px),
+			Y1:     float32(y_pos_px + height_px),
+			U0:     float32(info.Pos.Min.X) / float32(d.Data.Dx),
+			V0:     float32(info.Pos.Max.Y) / float32(d.Data.Dy),
+			U1:     float32(info.Pos.Max.X) / float32(d.Data.Dx),
+			V1:     float32(info.Pos.Min.Y) / float32(d.Data.Dy),
+			Colour: mathgl.Vec4{X: 1, Y: 1, Z: 1, W: 1},
+		}
+		d.logger.Trace("render-char", "x_pos", x_pos_px, "rune", string(r), "runeInfo", info, "geometry", quad)
+		ret = append(ret, quad)
+		x_pos_px += info.Advance * horizontalScale
+	}
+	return ret
+}
+
+func buildBlittingData(s string, d *Dictionary, x_pos_px, y_pos_px, height_px float64) blitBuffer {
+	blittingData := blitBuffer{}
+	for _, quad := range glyphQuads(s, d, x_pos_px, y_pos_px, height_px) {
 		start := uint16(len(blittingData.vertexData))
 		blittingData.indicesData = append(blittingData.indicesData, start+0)
 		blittingData.indicesData = append(blittingData.indicesData, start+1)
@@ -298,34 +369,12 @@ func buildBlittingData(s string, d *Dictionary, x_pos_px, y_pos_px, height_px fl
 		blittingData.indicesData = append(blittingData.indicesData, start+2)
 		blittingData.indicesData = append(blittingData.indicesData, start+3)
 
-		// Note: the texture is loaded 'upside down' so we flip our y-coordinates
-		// in texture-space.
-		blittingData.vertexData = append(blittingData.vertexData, blitVertex{
-			x: float32(xleft_px),
-			y: ytop_px,
-			u: float32(info.Pos.Min.X) / float32(d.Data.Dx),
-			v: float32(info.Pos.Min.Y) / float32(d.Data.Dy),
-		})
-		blittingData.vertexData = append(blittingData.vertexData, blitVertex{
-			x: float32(xleft_px),
-			y: ybot_px,
-			u: float32(info.Pos.Min.X) / float32(d.Data.Dx),
-			v: float32(info.Pos.Max.Y) / float32(d.Data.Dy),
-		})
-		blittingData.vertexData = append(blittingData.vertexData, blitVertex{
-			x: float32(xright_px),
-			y: ybot_px,
-			u: float32(info.Pos.Max.X) / float32(d.Data.Dx),
-			v: float32(info.Pos.Max.Y) / float32(d.Data.Dy),
-		})
-		blittingData.vertexData = append(blittingData.vertexData, blitVertex{
-			x: float32(xright_px),
-			y: ytop_px,
-			u: float32(info.Pos.Max.X) / float32(d.Data.Dx),
-			v: float32(info.Pos.Min.Y) / float32(d.Data.Dy),
-		})
-		d.logger.Trace("render-char", "x_pos", x_pos_px, "rune", string(r), "runeInfo", info, "geometry", blittingData.vertexData[start:])
-		x_pos_px += info.Advance * horizontalScale
+		blittingData.vertexData = append(blittingData.vertexData,
+			blitVertex{x: quad.X0, y: quad.Y1, u: quad.U0, v: quad.V1},
+			blitVertex{x: quad.X0, y: quad.Y0, u: quad.U0, v: quad.V0},
+			blitVertex{x: quad.X1, y: quad.Y0, u: quad.U1, v: quad.V0},
+			blitVertex{x: quad.X1, y: quad.Y1, u: quad.U1, v: quad.V1},
+		)
 	}
 
 	d.logger.Trace("geometry", "verts", blittingData.vertexData, "idxs", blittingData.indicesData)
@@ -342,6 +391,25 @@ func buildBlittingData(s string, d *Dictionary, x_pos_px, y_pos_px, height_px fl
 	return blittingData
 }
 
+// Returns where the left end of 's' goes so that it's justified w.r.t. target.
+func (d *Dictionary) justify(s string, target Point, just Justification) (float64, float64) {
+	string_width_px := d.StringPixelWidth(s)
+
+	d.logger.Trace("sizes", "width", string_width_px, "d.Data.Dx", d.Data.Dx, "d.Data.Dy", d.Data.Dy)
+
+	x_pos_px := float64(target.X)
+	y_pos_px := float64(target.Y)
+
+	switch just {
+	case Center:
+		x_pos_px -= string_width_px / 2
+	case Right:
+		x_pos_px -= string_width_px
+	}
+
+	return x_pos_px, y_pos_px
+}
+
 // Renders the string 's' at the given position with the given height. Values
 // are in units of pixels w.r.t. an origin at the bottom-left of the screen.
 // The text is positioned based on the given justification:
@@ -363,22 +431,9 @@ func (d *Dictionary) RenderString(s string, target Point, height int, just Justi
 		return
 	}
 
-	string_width_px := d.StringPixelWidth(s)
-
-	d.logger.Trace("sizes", "width", string_width_px, "d.Data.Dx", d.Data.Dx, "d.Data.Dy", d.Data.Dy)
-
-	x_pos_px := float64(target.X)
-	y_pos_px := float64(target.Y)
-
+	x_pos_px, y_pos_px := d.justify(s, target, just)
 	height_px := float64(height)
 
-	switch just {
-	case Center:
-		x_pos_px -= string_width_px / 2
-	case Right:
-		x_pos_px -= string_width_px
-	}
-
 	blittingData, ok := d.stringBlittingCache[s]
 	if !ok {
 		blittingData = buildBlittingData(s, d, x_pos_px, y_pos_px, height_px)
@@ -387,14 +442,16 @@ func (d *Dictionary) RenderString(s string, target Point, height int, just Justi
 
 	d.logger.Trace("renderstring blittingData", "todraw", s, "data", blittingData)
 
-	err := shaders.EnableShader("glop.font")
+	err := shaders.EnableShader(FontShaderName)
 	if err != nil {
 		panic(err)
 	}
 	defer shaders.EnableShader("")
 
 	// We want to use the 0'th texture unit.
-	shaders.SetUniformI("glop.font", "tex", 0)
+	if err := shaders.SetUniformI(FontShaderName, "tex", 0); err != nil {
+		d.logger.Warn("couldn't point the font shader at texture unit 0", "err", err)
+	}
 
 	render.LogAndClearGlErrors(d.logger)
 
@@ -424,6 +481,28 @@ func (d *Dictionary) RenderString(s string, target Point, height int, just Justi
 	render.LogAndClearGlErrors(d.logger)
 }
 
+// Like RenderString but queues the glyphs in the given batch instead of
+// drawing them right away. Glyphs are blended with whatever blend function is
+// in effect when the batch flushes; RenderString uses (SRC_ALPHA,
+// ONE_MINUS_SRC_ALPHA) for colour. The first call compiles FontBatchShaderName
+// in the batch's ShaderBank; it needs GLSL 3.30 unlike RenderString.
+func (d *Dictionary) AddString(b *batch.SpriteBatch, s string, target Point, height int, just Justification) {
+	if d.texture == 0 {
+		panic(fmt.Errorf("can't AddString for uninitialized Dictionary"))
+	}
+	if !b.Shaders().HasShader(FontBatchShaderName) {
+		err := b.Shaders().RegisterShader(FontBatchShaderName, font_batch_vertex_shader, font_batch_fragment_shader)
+		if err != nil {
+			panic(fmt.Errorf("failed to register font %q: %w", FontBatchShaderName, err))
+		}
+	}
+
+	x_pos_px, y_pos_px := d.justify(s, target, just)
+	for _, quad := range glyphQuads(s, d, x_pos_px, y_pos_px, float64(height)) {
+		b.AddWithShader(FontBatchShaderName, d.texture, quad)
+	}
+}
+
 func fix26_6_to_float64(n fixed.Int26_6) float64 {
 	// 'n' is a fractional value packed into an int32 with the 26
 	// most-significant bits representing the 'whole' portion and the 6
@@ -553,17 +632,17 @@ func (d *Dictionary) Store(outputStream io.Writer) error {
 }
 
 func (d *Dictionary) initialize(renderQueue render.RenderQueueInterface) {
-	d.compileShaders("glop.font", renderQueue)
+	d.compileShaders(FontShaderName, font_vertex_shader, font_fragment_shader, renderQueue)
 	d.uploadGlyphTexture(renderQueue)
 }
 
-func (d *Dictionary) compileShaders(shaderName string, renderQueue render.RenderQueueInterface) {
+func (d *Dictionary) compileShaders(shaderName, vertex, fragment string, renderQueue render.RenderQueueInterface) {
 	renderQueue.Queue(func(st render.RenderQueueState) {
 		if st.Shaders().HasShader(shaderName) {
 			return
 		}
 
-		err := st.Shaders().RegisterShader(shaderName, font_vertex_shader, font_fragment_shader)
+		err := st.Shaders().RegisterShader(shaderName, vertex, fragment)
 		if err != nil {
 			panic(fmt.Errorf("failed to register font %q: %w", shaderName, err))
 		}
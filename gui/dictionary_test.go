@@ -14,6 +14,7 @@ import (
 	"github.com/caffeine-storm/glop/glog"
 	"github.com/caffeine-storm/glop/gui"
 	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/batch"
 	"github.com/caffeine-storm/glop/render/rendertest"
 	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
 	"github.com/stretchr/testify/assert"
@@ -120,9 +121,17 @@ func TestDictionaryRenderString(t *testing.T) {
 		testbuilder.New().WithQueue().Run(func(queue render.RenderQueueInterface) {
 			d := gui.LoadAndInitializeDictionaryForTest(queue, glog.DebugLogger())
 
-			// TODO(tmckee): clean: expose 'glop.font' name through the API instead of
-			// copy-pasta.
-			d.RenderString("render this", gui.PointAt(12, 2), 14, gui.Left, rendertest.StubShaderBank("glop.font"))
+			d.RenderString("render this", gui.PointAt(12, 2), 14, gui.Left, rendertest.StubShaderBank(gui.FontShaderName))
+		})
+	})
+
+	t.Run("uses a shader that exposes the uniforms it sets", func(t *testing.T) {
+		testbuilder.New().WithQueue().Run(func(queue render.RenderQueueInterface) {
+			gui.LoadAndInitializeDictionaryForTest(queue, glog.DebugLogger())
+
+			rendertest.MustExposeUniforms(t, queue, gui.FontShaderName, map[string]gl.GLenum{
+				"tex": gl.SAMPLER_2D,
+			})
 		})
 	})
 
@@ -141,6 +150,38 @@ func TestDictionaryRenderString(t *testing.T) {
 			rendertest.MustLookLikeFile(t, queue, "laughing")
 		})
 	})
+
+	t.Run("draws the same through a SpriteBatch", func(t *testing.T) {
+		var stats batch.Stats
+		var compiledUpFront, compiledForBatch bool
+		testbuilder.New().WithSize(128, 32).WithQueue().Run(func(queue render.RenderQueueInterface) {
+			d := gui.LoadAndInitializeDictionaryForTest(queue, glog.DebugLogger())
+
+			queue.Queue(func(st render.RenderQueueState) {
+				b := batch.New(st.Shaders())
+				defer b.Delete()
+				b.Transforms = render.CurrentTransforms()
+
+				compiledUpFront = st.Shaders().HasShader(gui.FontBatchShaderName)
+				d.AddString(b, "kekw    ", gui.Point{}, d.MaxHeight(), gui.Left)
+				compiledForBatch = st.Shaders().HasShader(gui.FontBatchShaderName)
+				d.AddString(b, "    rofl", gui.Point{}, d.MaxHeight(), gui.Left)
+
+				gl.Enable(gl.BLEND)
+				defer gl.Disable(gl.BLEND)
+				gl.BlendFuncSeparate(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA, gl.ZERO, gl.ONE)
+				stats = b.EndFrame()
+			})
+			queue.Purge()
+
+			rendertest.MustLookLikeFile(t, queue, "laughing")
+		})
+
+		assert.Equal(t, 1, stats.DrawCalls)
+		assert.Equal(t, 4*len("kekw        rofl"), stats.Vertices)
+		assert.False(t, compiledUpFront, "RenderString users shouldn't need GLSL 3.30")
+		assert.True(t, compiledForBatch)
+	})
 }
 
 func TestGetFontMetrics(t *testing.T) {
@@ -7,6 +7,7 @@ import (
 	"github.com/caffeine-storm/glop/gin"
 	"github.com/caffeine-storm/glop/glog"
 	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/batch"
 )
 
 type DrawingContext interface {
@@ -15,6 +16,16 @@ type DrawingContext interface {
 	GetLogger() glog.Logger
 }
 
+// DrawingContexts that also implement BatchingContext let widgets queue their
+// quads in a shared batch.SpriteBatch instead of drawing them right away.
+// GetSpriteBatch may return nil, in which case widgets draw as usual. Queued
+// quads are drawn when the batch flushes so they can end up underneath things
+// that other widgets drew immediately.
+type BatchingContext interface {
+	DrawingContext
+	GetSpriteBatch() *batch.SpriteBatch
+}
+
 type UpdateableDrawingContext interface {
 	DrawingContext
 	SetDictionary(fontname string, d *Dictionary)
@@ -93,6 +104,9 @@ type Gui struct {
 	dictionaries map[string]*Dictionary
 	shaders      map[string]*render.ShaderBank
 
+	// Optional; see BatchingContext.
+	spriteBatch *batch.SpriteBatch
+
 	// Stack of widgets that have focus
 	focus []Widget
 
@@ -106,6 +120,7 @@ type Gui struct {
 var (
 	_ DrawingContext           = (*Gui)(nil)
 	_ UpdateableDrawingContext = (*Gui)(nil)
+	_ BatchingContext          = (*Gui)(nil)
 	_ EventHandlingContext     = (*Gui)(nil)
 )
 
@@ -133,6 +148,17 @@ func (g *Gui) GetLogger() glog.Logger {
 	return g.logger
 }
 
+func (g *Gui) GetSpriteBatch() *batch.SpriteBatch {
+	return g.spriteBatch
+}
+
+// Has widgets that support batching draw through the given batch; pass nil to
+// go back to drawing immediately. Draw flushes the batch and ends its frame so
+// its LastFrame stats describe the last call to Draw.
+func (g *Gui) SetSpriteBatch(b *batch.SpriteBatch) {
+	g.spriteBatch = b
+}
+
 func (g *Gui) SetDictionary(fontname string, d *Dictionary) {
 	g.dictionaries[fontname] = d
 }
@@ -160,10 +186,20 @@ func (g *Gui) Draw() {
 	gl.Ortho(float64(region.X), float64(region.X+region.Dx), float64(region.Y), float64(region.Y+region.Dy), 1000, -1000)
 	gl.ClearColor(0, 0, 0, 1)
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	if g.spriteBatch != nil {
+		g.spriteBatch.Transforms = render.CurrentTransforms()
+	}
 	g.root.Draw(region, g)
+	if g.spriteBatch != nil {
+		// Focus decorations go on top of everything else.
+		g.spriteBatch.Flush()
+	}
 	if g.FocusWidget() != nil {
 		g.FocusWidget().DrawFocused(region, g)
 	}
+	if g.spriteBatch != nil {
+		g.spriteBatch.EndFrame()
+	}
 	render.LogAndClearGlErrors(glog.InfoLogger())
 }
 
@@ -43,7 +43,7 @@ func LoadAndInitializeDictionaryForTest(renderQueue render.RenderQueueInterface,
 	}
 
 	renderQueue.Queue(render.RenderJob(func(st render.RenderQueueState) {
-		fontShaderName := "glop.font"
+		fontShaderName := FontShaderName
 		if st.Shaders().HasShader(fontShaderName) {
 			return
 		}
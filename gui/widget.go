@@ -8,7 +8,9 @@ import (
 	"runtime"
 
 	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/render"
 	"github.com/caffeine-storm/glu"
+	"github.com/caffeine-storm/mathgl"
 )
 
 type Widget interface {
@@ -197,6 +199,27 @@ func (w *ImageBox) Draw(region Region, ctx DrawingContext) {
 		return
 	}
 
+	if bctx, ok := ctx.(BatchingContext); ok && bctx.GetSpriteBatch() != nil {
+		gl.Enable(gl.BLEND)
+		bctx.GetSpriteBatch().Add(w.texture, render.Quad{
+			X0: float32(region.X),
+			Y0: float32(region.Y),
+			X1: float32(region.X + region.Dx),
+			Y1: float32(region.Y + region.Dy),
+			U0: 0,
+			V0: 0,
+			U1: 1,
+			V1: -1,
+			Colour: mathgl.Vec4{
+				X: float32(w.r),
+				Y: float32(w.g),
+				Z: float32(w.b),
+				W: float32(w.a),
+			},
+		})
+		return
+	}
+
 	w.texture.Bind(gl.TEXTURE_2D)
 	gl.Enable(gl.BLEND)
 	gl.Color4d(w.r, w.g, w.b, w.a)
@@ -0,0 +1,143 @@
+package imgmanip
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"io"
+	"time"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+type apngWriter struct {
+	w   io.Writer
+	err error
+
+	// fcTL and fdAT chunks share one sequence; IDAT doesn't take a number.
+	sequence uint32
+}
+
+func (aw *apngWriter) chunk(name string, data []byte) {
+	if aw.err != nil {
+		return
+	}
+
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], name)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	var footer [4]byte
+	binary.BigEndian.PutUint32(footer[:], crc.Sum32())
+
+	for _, part := range [][]byte{header[:], data, footer[:]} {
+		if _, aw.err = aw.w.Write(part); aw.err != nil {
+			return
+		}
+	}
+}
+
+func (aw *apngWriter) nextSequence() uint32 {
+	ret := aw.sequence
+	aw.sequence++
+	return ret
+}
+
+// Each row is prefixed with filter type 0 (none) and the whole thing is
+// deflated.
+func compressedRows(img *image.NRGBA) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+
+	bounds := img.Bounds()
+	rowBytes := 4 * bounds.Dx()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		start := img.PixOffset(bounds.Min.X, y)
+		if _, err := zw.Write([]byte{0}); err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write(img.Pix[start : start+rowBytes]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Writes the given frames as an animated PNG that loops forever. Each frame is
+// shown for the matching entry in delays. All frames must be the same size.
+// Viewers that don't understand APNG show the first frame.
+func EncodeAPNG(w io.Writer, frames []*image.NRGBA, delays []time.Duration) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("EncodeAPNG: no frames")
+	}
+	if len(frames) != len(delays) {
+		return fmt.Errorf("EncodeAPNG: %d frames but %d delays", len(frames), len(delays))
+	}
+
+	size := frames[0].Bounds().Size()
+	for i, frame := range frames {
+		if frame.Bounds().Size() != size {
+			return fmt.Errorf("EncodeAPNG: frame %d is %v but frame 0 is %v", i, frame.Bounds().Size(), size)
+		}
+	}
+
+	aw := &apngWriter{w: w}
+	if _, aw.err = w.Write(pngSignature); aw.err != nil {
+		return aw.err
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(size.X))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(size.Y))
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // colour type: RGBA
+	// compression, filter and interlace methods are all 0
+	aw.chunk("IHDR", ihdr)
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(frames)))
+	// a play count of 0 means loop forever
+	aw.chunk("acTL", actl)
+
+	for i, frame := range frames {
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], aw.nextSequence())
+		binary.BigEndian.PutUint32(fctl[4:], uint32(size.X))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(size.Y))
+		// x and y offsets are 0
+		delayMs := delays[i].Milliseconds()
+		if delayMs > 0xffff {
+			delayMs = 0xffff
+		}
+		binary.BigEndian.PutUint16(fctl[20:], uint16(delayMs))
+		binary.BigEndian.PutUint16(fctl[22:], 1000)
+		// dispose and blend ops are 0: 'none' and 'source'
+		aw.chunk("fcTL", fctl)
+
+		data, err := compressedRows(frame)
+		if err != nil {
+			return fmt.Errorf("EncodeAPNG: couldn't compress frame %d: %w", i, err)
+		}
+
+		if i == 0 {
+			aw.chunk("IDAT", data)
+		} else {
+			fdat := make([]byte, 4, 4+len(data))
+			binary.BigEndian.PutUint32(fdat, aw.nextSequence())
+			aw.chunk("fdAT", append(fdat, data...))
+		}
+	}
+
+	aw.chunk("IEND", nil)
+	return aw.err
+}
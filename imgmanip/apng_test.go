@@ -0,0 +1,51 @@
+package imgmanip_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/caffeine-storm/glop/imgmanip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func solidFrame(c color.NRGBA) *image.NRGBA {
+	ret := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for y := range 2 {
+		for x := range 4 {
+			ret.SetNRGBA(x, y, c)
+		}
+	}
+	return ret
+}
+
+func TestEncodeAPNG(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 128}
+
+	t.Run("plain decoders see the first frame", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := imgmanip.EncodeAPNG(&buf, []*image.NRGBA{solidFrame(red), solidFrame(blue)}, []time.Duration{time.Second / 30, time.Second / 30})
+		require.NoError(t, err)
+
+		assert.Contains(t, buf.String(), "acTL")
+		assert.Contains(t, buf.String(), "fdAT")
+
+		img, err := png.Decode(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 4, 2), img.Bounds())
+		assert.Equal(t, red, color.NRGBAModel.Convert(img.At(3, 1)))
+	})
+
+	t.Run("frames must match", func(t *testing.T) {
+		var buf bytes.Buffer
+		small := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+		assert.Error(t, imgmanip.EncodeAPNG(&buf, []*image.NRGBA{solidFrame(red), small}, []time.Duration{0, 0}))
+		assert.Error(t, imgmanip.EncodeAPNG(&buf, []*image.NRGBA{solidFrame(red)}, nil))
+		assert.Error(t, imgmanip.EncodeAPNG(&buf, nil, nil))
+	})
+}
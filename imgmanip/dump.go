@@ -7,17 +7,41 @@ import (
 	"os"
 )
 
-func MustDumpImage(img image.Image, filePath string) {
+// Writes the image to the given path as a PNG.
+func DumpImage(img image.Image, filePath string) error {
 	// TODO(tmckee:43): we should warn if someone passes in not-an-NRGBA because
 	// the encoding could be lossy in that case.
 	f, err := os.Create(filePath)
 	if err != nil {
-		panic(fmt.Errorf("couldn't os.Create(%q): %w", filePath, err))
+		return fmt.Errorf("couldn't os.Create(%q): %w", filePath, err)
 	}
 	defer f.Close()
 
 	err = png.Encode(f, img)
 	if err != nil {
-		panic(fmt.Errorf("couldn't png.Encode(%q): %w", filePath, err))
+		return fmt.Errorf("couldn't png.Encode(%q): %w", filePath, err)
+	}
+	return f.Close()
+}
+
+func MustDumpImage(img image.Image, filePath string) {
+	if err := DumpImage(img, filePath); err != nil {
+		panic(err)
+	}
+}
+
+// Decodes the image at the given path; any format with a registered decoder
+// will do.
+func LoadImage(filePath string) (image.Image, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open file %q: %w", filePath, err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't image.Decode %q: %w", filePath, err)
 	}
+	return img, nil
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/caffeine-storm/glop/imgmanip"
@@ -28,4 +29,11 @@ func TestDumpImage(t *testing.T) {
 			imgmanip.MustDumpImage(genericImage, f.Name())
 		})
 	})
+
+	t.Run("reports errors instead of panicking", func(t *testing.T) {
+		err := imgmanip.DumpImage(givenAnImage(), filepath.Join(t.TempDir(), "missing-dir", "dump.png"))
+		if err == nil {
+			t.Fatalf("dumping into a directory that doesn't exist should fail")
+		}
+	})
 }
@@ -0,0 +1,100 @@
+package imgmanip
+
+import (
+	"fmt"
+	"image"
+)
+
+// SSIM is computed over square windows of this many pixels on a side that
+// overlap by half.
+const ssimWindow = 8
+
+// Stabilizing constants from the SSIM paper for 8-bit samples.
+const (
+	ssimC1 = (0.01 * 255) * (0.01 * 255)
+	ssimC2 = (0.03 * 255) * (0.03 * 255)
+)
+
+func luma(img *image.NRGBA) []float64 {
+	bounds := img.Bounds()
+	ret := make([]float64, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			r, g, b := float64(img.Pix[i]), float64(img.Pix[i+1]), float64(img.Pix[i+2])
+			ret = append(ret, 0.299*r+0.587*g+0.114*b)
+		}
+	}
+	return ret
+}
+
+// Returns the start of each window along an axis of the given length. The
+// last window is pulled back so that it ends at the edge instead of hanging
+// off of it.
+func windowStarts(length int) []int {
+	if length <= ssimWindow {
+		return []int{0}
+	}
+
+	var ret []int
+	for start := 0; start+ssimWindow < length; start += ssimWindow / 2 {
+		ret = append(ret, start)
+	}
+	return append(ret, length-ssimWindow)
+}
+
+// Returns the mean structural similarity (SSIM) of the luma of two images. It
+// tolerates the small, spread out differences that antialiasing and rounding
+// cause while still catching changes to shapes and edges. The result is in
+// [-1, 1]; 1 means the images are identical. Colours are compared as-is so
+// translucent images should be composed over a background first. Panics if
+// the images aren't the same size.
+func SSIM(lhs, rhs *image.NRGBA) float64 {
+	size := lhs.Bounds().Size()
+	if size != rhs.Bounds().Size() {
+		panic(fmt.Errorf("SSIM: size mismatch: %v vs. %v", size, rhs.Bounds().Size()))
+	}
+	if size.X == 0 || size.Y == 0 {
+		return 1
+	}
+
+	lhsLuma, rhsLuma := luma(lhs), luma(rhs)
+
+	total := 0.0
+	count := 0
+	for _, y0 := range windowStarts(size.Y) {
+		for _, x0 := range windowStarts(size.X) {
+			y1 := min(y0+ssimWindow, size.Y)
+			x1 := min(x0+ssimWindow, size.X)
+			n := float64((y1 - y0) * (x1 - x0))
+
+			var sumL, sumR float64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					sumL += lhsLuma[y*size.X+x]
+					sumR += rhsLuma[y*size.X+x]
+				}
+			}
+			meanL, meanR := sumL/n, sumR/n
+
+			var varL, varR, covar float64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					dl := lhsLuma[y*size.X+x] - meanL
+					dr := rhsLuma[y*size.X+x] - meanR
+					varL += dl * dl
+					varR += dr * dr
+					covar += dl * dr
+				}
+			}
+			varL, varR, covar = varL/n, varR/n, covar/n
+
+			numerator := (2*meanL*meanR + ssimC1) * (2*covar + ssimC2)
+			denominator := (meanL*meanL + meanR*meanR + ssimC1) * (varL + varR + ssimC2)
+			total += numerator / denominator
+			count++
+		}
+	}
+
+	return total / float64(count)
+}
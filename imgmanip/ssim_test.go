@@ -0,0 +1,37 @@
+package imgmanip_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/caffeine-storm/glop/imgmanip"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSIM(t *testing.T) {
+	img := imgmanip.ToNRGBA(givenAnImage())
+
+	t.Run("identical images", func(t *testing.T) {
+		assert.InDelta(t, 1.0, imgmanip.SSIM(img, img), 1e-9)
+	})
+
+	t.Run("inverted images", func(t *testing.T) {
+		inverted := image.NewNRGBA(img.Rect)
+		for i := 0; i < len(img.Pix); i += 4 {
+			inverted.Pix[i] = 255 - img.Pix[i]
+			inverted.Pix[i+1] = 255 - img.Pix[i+1]
+			inverted.Pix[i+2] = 255 - img.Pix[i+2]
+			inverted.Pix[i+3] = img.Pix[i+3]
+		}
+		assert.Less(t, imgmanip.SSIM(img, inverted), 0.5)
+	})
+
+	t.Run("size mismatch", func(t *testing.T) {
+		tiny := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+		tiny.SetNRGBA(0, 0, color.NRGBA{A: 255})
+		assert.Panics(t, func() {
+			imgmanip.SSIM(img, tiny)
+		})
+	})
+}
@@ -0,0 +1,144 @@
+// Package batch collects 2D quads from many callers and draws them with as
+// few draw calls as it can. Quads that share a texture, a shader and a
+// transform are drawn together; a change to any of those flushes what's
+// pending first so the drawing order is preserved.
+//
+// A SpriteBatch draws through render.QuadBatch so it works in core-profile
+// contexts. It doesn't touch blending or depth testing; set those up before
+// anything is flushed.
+package batch
+
+import (
+	"fmt"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/mathgl"
+)
+
+// What a SpriteBatch sent to GL.
+type Stats struct {
+	DrawCalls int
+	Vertices  int
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%d draw calls, %d vertices", s.DrawCalls, s.Vertices)
+}
+
+// The state that a run of quads is drawn with.
+type drawState struct {
+	shader  string
+	texture gl.Texture
+	mvp     render.Matrix
+}
+
+type SpriteBatch struct {
+	// Quads are transformed by these as they're added. Changing the matrices
+	// between calls to Add is fine; the batch notices and flushes. Defaults to
+	// a fresh render.MakeTransforms().
+	Transforms *render.Transforms
+
+	shaders *render.ShaderBank
+	quads   *render.QuadBatch
+
+	pending drawState
+
+	frame, lastFrame Stats
+}
+
+// Must be called on the render thread. Shaders named in AddWithShader are
+// looked up in the given bank.
+func New(shaders *render.ShaderBank) *SpriteBatch {
+	return &SpriteBatch{
+		Transforms: render.MakeTransforms(),
+		shaders:    shaders,
+		quads:      render.MakeQuadBatch(),
+	}
+}
+
+// The bank that shaders named in AddWithShader are looked up in.
+func (b *SpriteBatch) Shaders() *render.ShaderBank {
+	return b.shaders
+}
+
+// Queues a quad to be drawn with render.CoreShaderName.
+func (b *SpriteBatch) Add(texture gl.Texture, quad render.Quad) {
+	b.AddWithShader(render.CoreShaderName, texture, quad)
+}
+
+// Queues a quad to be drawn with the named shader; see
+// render.QuadBatch.DrawWithShader for what it has to look like. The quad's
+// colour is multiplied by the current colour of b.Transforms.
+func (b *SpriteBatch) AddWithShader(shader string, texture gl.Texture, quad render.Quad) {
+	state := drawState{
+		shader:  shader,
+		texture: texture,
+		mvp:     b.Transforms.ModelViewProjection(),
+	}
+	if b.quads.Len() > 0 && state != b.pending {
+		b.Flush()
+	}
+	b.pending = state
+
+	colour := b.Transforms.Colour()
+	quad.Colour = mathgl.Vec4{
+		X: quad.Colour.X * colour.X,
+		Y: quad.Colour.Y * colour.Y,
+		Z: quad.Colour.Z * colour.Z,
+		W: quad.Colour.W * colour.W,
+	}
+	b.quads.Add(quad)
+}
+
+// Draws everything that's pending. Panics if the pending quads' shader isn't
+// usable.
+func (b *SpriteBatch) Flush() {
+	quads := b.quads.Len()
+	if quads == 0 {
+		return
+	}
+
+	// The model-view-projection matrix was captured when the quads were added;
+	// hand it over as the projection so that nothing gets applied twice.
+	transforms := render.MakeTransforms()
+	transforms.Load(render.MatrixModeProjection, &b.pending.mvp)
+
+	var err error
+	if b.pending.shader == render.CoreShaderName {
+		err = b.quads.Draw(b.shaders, transforms, b.pending.texture)
+	} else {
+		err = b.quads.DrawWithShader(b.shaders, transforms, b.pending.shader, b.pending.texture)
+	}
+	if err != nil {
+		panic(fmt.Errorf("SpriteBatch.Flush: %w", err))
+	}
+
+	b.frame.DrawCalls++
+	b.frame.Vertices += 4 * quads
+}
+
+// Returns what's been sent to GL since the last call to EndFrame.
+func (b *SpriteBatch) Stats() Stats {
+	return b.frame
+}
+
+// Returns what was sent to GL between the last two calls to EndFrame.
+func (b *SpriteBatch) LastFrame() Stats {
+	return b.lastFrame
+}
+
+// Flushes and starts counting for the next frame. Returns the counts for the
+// frame that just ended.
+func (b *SpriteBatch) EndFrame() Stats {
+	b.Flush()
+	b.lastFrame = b.frame
+	b.frame = Stats{}
+	return b.lastFrame
+}
+
+// Releases the batch's GL objects, dropping anything that's pending. Must be
+// called on the render thread.
+func (b *SpriteBatch) Delete() {
+	b.quads.Delete()
+}
@@ -0,0 +1,95 @@
+package batch_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/debug"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/batch"
+	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
+	"github.com/caffeine-storm/mathgl"
+	"github.com/stretchr/testify/assert"
+)
+
+var white = mathgl.Vec4{X: 1, Y: 1, Z: 1, W: 1}
+
+func square(x, y, size float32, colour mathgl.Vec4) render.Quad {
+	return render.Quad{X0: x, Y0: y, X1: x + size, Y1: y + size, U1: 1, V1: 1, Colour: colour}
+}
+
+func givenATexture() gl.Texture {
+	tex := gl.GenTexture()
+	tex.Bind(gl.TEXTURE_2D)
+	defer gl.Texture(0).Bind(gl.TEXTURE_2D)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, 1, 1, 0, gl.RGBA, gl.UNSIGNED_BYTE, []byte{255, 255, 255, 255})
+	return tex
+}
+
+func TestSpriteBatchStats(t *testing.T) {
+	var first, second, afterEndFrame, lastFrame batch.Stats
+	testbuilder.Run(func(st render.RenderQueueState) {
+		texA, texB := givenATexture(), givenATexture()
+		defer gl.DeleteTextures([]gl.Texture{texA, texB})
+
+		b := batch.New(st.Shaders())
+		defer b.Delete()
+
+		// Three runs: A, A | B | A
+		b.Add(texA, square(0, 0, 0.1, white))
+		b.Add(texA, square(0.1, 0, 0.1, white))
+		b.Add(texB, square(0.2, 0, 0.1, white))
+		b.Add(texA, square(0.3, 0, 0.1, white))
+		first = b.EndFrame()
+
+		// A change of transform is a change of state too.
+		b.Add(texA, square(0, 0, 0.1, white))
+		b.Transforms.WithMatrixMode(render.MatrixModeModelView, func() {
+			b.Transforms.Translate(render.MatrixModeModelView, 0.5, 0, 0)
+			b.Add(texA, square(0, 0, 0.1, white))
+		})
+		b.Flush()
+		second = b.Stats()
+		afterEndFrame = b.EndFrame()
+		lastFrame = b.LastFrame()
+	})
+
+	assert.Equal(t, batch.Stats{DrawCalls: 3, Vertices: 16}, first)
+	assert.Equal(t, batch.Stats{DrawCalls: 2, Vertices: 8}, second)
+	assert.Equal(t, second, afterEndFrame)
+	assert.Equal(t, second, lastFrame)
+}
+
+func TestSpriteBatchDraws(t *testing.T) {
+	const size = 32
+	var screen *image.NRGBA
+	testbuilder.New().WithSize(size, size).RunForQueueState(func(st render.RenderQueueState) {
+		gl.ClearColor(0, 0, 0, 1)
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+
+		tex := givenATexture()
+		defer tex.Delete()
+
+		b := batch.New(st.Shaders())
+		defer b.Delete()
+		b.Transforms.Ortho(render.MatrixModeProjection, 0, size, 0, size, 1, -1)
+
+		b.Add(tex, square(0, 0, size/2, mathgl.Vec4{X: 1, W: 1}))
+		b.Transforms.WithColour(0, 1, 1, 1, func() {
+			// Cyan times yellow is green.
+			b.Add(tex, square(size/2, size/2, size/2, mathgl.Vec4{X: 1, Y: 1, W: 1}))
+		})
+		b.EndFrame()
+
+		screen = debug.ScreenShotNrgba(size, size)
+	})
+
+	// Screenshots put the top row first.
+	assert.Equal(t, color.NRGBA{R: 255, A: 255}, screen.NRGBAAt(4, size-4))
+	assert.Equal(t, color.NRGBA{G: 255, A: 255}, screen.NRGBAAt(size-4, 4))
+	assert.Equal(t, color.NRGBA{A: 255}, screen.NRGBAAt(4, 4))
+}
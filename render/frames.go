@@ -0,0 +1,192 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// The number of frames that RenderQueueInterface.RecentFrames() remembers.
+const FrameHistorySize = 120
+
+type LatencyPercentiles struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// Computes nearest-rank percentiles over the given durations. Sorts the input
+// in place.
+func computeLatencyPercentiles(latencies []time.Duration) LatencyPercentiles {
+	if len(latencies) == 0 {
+		return LatencyPercentiles{}
+	}
+	slices.Sort(latencies)
+
+	rank := func(p int) time.Duration {
+		// Nearest-rank: the smallest value such that p% of values are <= it.
+		idx := (p*len(latencies) + 99) / 100
+		return latencies[max(idx-1, 0)]
+	}
+
+	return LatencyPercentiles{
+		P50: rank(50),
+		P90: rank(90),
+		P99: rank(99),
+		Max: latencies[len(latencies)-1],
+	}
+}
+
+// A summary of the jobs that were queued between a call to BeginFrame() and
+// the matching call to EndFrame().
+type FrameStats struct {
+	// Frames are numbered sequentially per-queue starting at 1.
+	Number uint64
+
+	// When BeginFrame() and EndFrame() were called, respectively.
+	BeganAt time.Time
+	EndedAt time.Time
+	// When the last of the frame's jobs finished.
+	CompletedAt time.Time
+
+	// The number of jobs that ran and the number that were cancelled instead.
+	// Jobs that never got to run because the queue went defunct count as
+	// cancelled.
+	JobCount       int
+	CancelledCount int
+
+	// The number of jobs that started but didn't return, usually because they
+	// panicked. They're left out of the timing stats below.
+	PanickedCount int
+
+	// The sum of each job's JobTimingInfo.RunTime.
+	TotalRunTime time.Duration
+
+	// Percentiles over each job's JobTimingInfo.QueueTime.
+	QueueLatency LatencyPercentiles
+
+	// The number of GL errors that the frame's jobs left behind.
+	GlErrorCount int
+}
+
+func (fs *FrameStats) String() string {
+	return fmt.Sprintf("{frame %d: jobs: %d cancelled: %d panicked: %d runtime: %s latency(p50/p90/p99/max): %s/%s/%s/%s glerrors: %d}",
+		fs.Number, fs.JobCount, fs.CancelledCount, fs.PanickedCount, fs.TotalRunTime,
+		fs.QueueLatency.P50, fs.QueueLatency.P90, fs.QueueLatency.P99, fs.QueueLatency.Max,
+		fs.GlErrorCount)
+}
+
+// Accumulates stats for a single frame. Jobs may finish on the render thread
+// while the frame is being ended from another goroutine so all access goes
+// through 'mut'.
+type frameRecord struct {
+	mut       sync.Mutex
+	stats     FrameStats
+	latencies []time.Duration
+	pending   int
+	ended     bool
+
+	// Set once the queue has gone defunct; pending jobs won't get to run.
+	abandoned bool
+	// Set once the stats have been handed out; later updates are dropped.
+	finalized bool
+}
+
+func (fr *frameRecord) addPending() {
+	fr.mut.Lock()
+	defer fr.mut.Unlock()
+	fr.pending++
+}
+
+// Records the result of one of the frame's jobs. A nil 'info' means the job
+// was cancelled or didn't complete. Returns true iff this was the last thing
+// the frame was waiting for.
+func (fr *frameRecord) jobDone(info *JobTimingInfo, cancelled bool) bool {
+	fr.mut.Lock()
+	defer fr.mut.Unlock()
+
+	if fr.finalized {
+		return false
+	}
+
+	fr.pending--
+	if cancelled {
+		fr.stats.CancelledCount++
+	} else if info == nil {
+		fr.stats.PanickedCount++
+	} else {
+		fr.stats.JobCount++
+		fr.stats.TotalRunTime += info.RunTime
+		fr.stats.GlErrorCount += info.GlErrorCount
+		fr.latencies = append(fr.latencies, info.QueueTime)
+	}
+
+	return fr.ended && fr.pending == 0
+}
+
+// Returns true iff the frame has no more jobs to wait for.
+func (fr *frameRecord) end() bool {
+	fr.mut.Lock()
+	defer fr.mut.Unlock()
+
+	fr.ended = true
+	fr.stats.EndedAt = time.Now()
+	return fr.pending == 0 || fr.abandoned
+}
+
+// Stops waiting on the frame's pending jobs because the queue has gone
+// defunct. Returns true iff the frame has ended and so can be completed now.
+func (fr *frameRecord) abandon() bool {
+	fr.mut.Lock()
+	defer fr.mut.Unlock()
+
+	fr.abandoned = true
+	return fr.ended && !fr.finalized
+}
+
+// Returns nil if the frame was already finalized.
+func (fr *frameRecord) finalize() *FrameStats {
+	fr.mut.Lock()
+	defer fr.mut.Unlock()
+
+	if fr.finalized {
+		return nil
+	}
+	fr.finalized = true
+
+	// Anything still pending isn't going to run.
+	fr.stats.CancelledCount += fr.pending
+	fr.pending = 0
+
+	fr.stats.CompletedAt = time.Now()
+	fr.stats.QueueLatency = computeLatencyPercentiles(fr.latencies)
+	fr.latencies = nil
+
+	ret := fr.stats
+	return &ret
+}
+
+// A fixed-size ring buffer of the most recently completed frames.
+type frameHistory struct {
+	frames [FrameHistorySize]FrameStats
+	next   int
+	count  int
+}
+
+func (fh *frameHistory) push(stats *FrameStats) {
+	fh.frames[fh.next] = *stats
+	fh.next = (fh.next + 1) % len(fh.frames)
+	fh.count = min(fh.count+1, len(fh.frames))
+}
+
+// Returns the remembered frames from oldest to newest.
+func (fh *frameHistory) list() []FrameStats {
+	ret := make([]FrameStats, 0, fh.count)
+	start := (fh.next - fh.count + len(fh.frames)) % len(fh.frames)
+	for i := range fh.count {
+		ret = append(ret, fh.frames[(start+i)%len(fh.frames)])
+	}
+	return ret
+}
@@ -0,0 +1,210 @@
+package render_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caffeine-storm/glop/glog"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameStats(t *testing.T) {
+	t.Run("groups jobs queued between BeginFrame and EndFrame", func(t *testing.T) {
+		// Not started yet so that the cancelled job can't start before it's
+		// cancelled.
+		queue := GivenAQueue()
+
+		queue.Queue(nop) // not part of any frame
+
+		queue.BeginFrame()
+		for range 3 {
+			queue.Queue(nop)
+		}
+		queue.QueueInLane(render.LaneNormal, nop).Cancel()
+		queue.EndFrame()
+
+		queue.Queue(nop) // not part of any frame
+		queue.StartProcessing()
+		queue.Purge()
+
+		frames := queue.RecentFrames()
+		require.Len(t, frames, 1)
+		assert.Equal(t, uint64(1), frames[0].Number)
+		assert.Equal(t, 3, frames[0].JobCount)
+		assert.Equal(t, 1, frames[0].CancelledCount)
+		assert.False(t, frames[0].CompletedAt.Before(frames[0].EndedAt))
+	})
+
+	t.Run("records run time and queue latency", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		queue.BeginFrame()
+		for range 4 {
+			queue.Queue(func(render.RenderQueueState) {
+				time.Sleep(time.Millisecond)
+			})
+		}
+		queue.EndFrame()
+
+		time.Sleep(5 * time.Millisecond)
+		queue.StartProcessing()
+		queue.Purge()
+
+		frames := queue.RecentFrames()
+		require.Len(t, frames, 1)
+		stats := frames[0]
+
+		assert.GreaterOrEqual(t, stats.TotalRunTime, 4*time.Millisecond)
+		// Each job sat in the queue while we weren't processing.
+		assert.GreaterOrEqual(t, stats.QueueLatency.P50, 5*time.Millisecond)
+		assert.LessOrEqual(t, stats.QueueLatency.P50, stats.QueueLatency.P90)
+		assert.LessOrEqual(t, stats.QueueLatency.P90, stats.QueueLatency.P99)
+		assert.LessOrEqual(t, stats.QueueLatency.P99, stats.QueueLatency.Max)
+	})
+
+	t.Run("an empty frame completes immediately", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		queue.BeginFrame()
+		queue.EndFrame()
+
+		frames := queue.RecentFrames()
+		require.Len(t, frames, 1)
+		assert.Equal(t, 0, frames[0].JobCount)
+	})
+
+	t.Run("only the most recent frames are remembered", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		for range render.FrameHistorySize + 5 {
+			queue.BeginFrame()
+			queue.EndFrame()
+		}
+
+		frames := queue.RecentFrames()
+		require.Len(t, frames, render.FrameHistorySize)
+		assert.Equal(t, uint64(6), frames[0].Number)
+		assert.Equal(t, uint64(render.FrameHistorySize+5), frames[len(frames)-1].Number)
+	})
+
+	t.Run("frames must not overlap", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		assert.Panics(t, queue.EndFrame)
+
+		queue.BeginFrame()
+		assert.Panics(t, queue.BeginFrame)
+	})
+
+	t.Run("a panicking job doesn't stall its frame", func(t *testing.T) {
+		queue := GivenARunningQueue()
+
+		queue.BeginFrame()
+		queue.Queue(func(render.RenderQueueState) {
+			panic(&everythingIsFine{})
+		})
+		queue.Queue(nop)
+		queue.EndFrame()
+		queue.Purge()
+
+		frames := queue.RecentFrames()
+		require.Len(t, frames, 1)
+		assert.Equal(t, 1, frames[0].JobCount)
+		assert.Equal(t, 1, frames[0].PanickedCount)
+	})
+
+	t.Run("frames complete when the queue goes defunct", func(t *testing.T) {
+		// Never started so that none of the jobs get to run.
+		queue := GivenAQueue()
+
+		completed := 0
+		queue.AddTimingListener(&render.JobTimingListener{
+			OnFrame: func(*render.FrameStats) {
+				completed++
+			},
+		})
+
+		queue.BeginFrame()
+		queue.Queue(nop)
+		queue.Queue(nop)
+		queue.EndFrame()
+
+		queue.BeginFrame()
+		queue.Queue(nop)
+		queue.StopProcessing()
+		assert.Equal(t, 1, completed)
+		queue.EndFrame()
+
+		// Frames begun afterwards don't wait on anything either.
+		queue.BeginFrame()
+		queue.Queue(nop)
+		queue.EndFrame()
+
+		frames := queue.RecentFrames()
+		require.Len(t, frames, 3)
+		assert.Equal(t, 3, completed)
+		assert.Equal(t, 2, frames[0].CancelledCount)
+		assert.Equal(t, 1, frames[1].CancelledCount)
+		assert.Equal(t, 0, frames[2].CancelledCount)
+		for _, frame := range frames {
+			assert.Equal(t, 0, frame.JobCount)
+		}
+	})
+
+	t.Run("SetLogger isn't counted in an open frame", func(t *testing.T) {
+		queue := GivenARunningQueue()
+
+		queue.BeginFrame()
+		queue.(render.RenderQueueWithLoggerInterface).SetLogger(glog.VoidLogger())
+		queue.Queue(nop)
+		queue.EndFrame()
+		queue.Purge()
+
+		frames := queue.RecentFrames()
+		require.Len(t, frames, 1)
+		assert.Equal(t, 1, frames[0].JobCount)
+	})
+}
+
+func TestAddTimingListener(t *testing.T) {
+	t.Run("listeners can be added to a running queue", func(t *testing.T) {
+		queue := GivenARunningQueue()
+		queue.Queue(nop)
+		queue.Purge()
+
+		notified := 0
+		queue.AddTimingListener(&render.JobTimingListener{
+			OnNotify: func(*render.JobTimingInfo, string) {
+				notified++
+			},
+			Threshold: 0,
+		})
+
+		queue.Queue(nop)
+		queue.Purge()
+
+		assert.Equal(t, 1, notified)
+	})
+
+	t.Run("listeners are told about completed frames", func(t *testing.T) {
+		queue := GivenARunningQueue()
+
+		frames := []*render.FrameStats{}
+		queue.AddTimingListener(&render.JobTimingListener{
+			OnFrame: func(stats *render.FrameStats) {
+				frames = append(frames, stats)
+			},
+		})
+
+		queue.BeginFrame()
+		queue.Queue(nop)
+		queue.Queue(nop)
+		queue.EndFrame()
+		queue.Purge()
+
+		require.Len(t, frames, 1)
+		assert.Equal(t, 2, frames[0].JobCount)
+	})
+}
@@ -0,0 +1,149 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// A Future holds the eventual result of a job that was Submit'ed to a
+// RenderQueueInterface.
+type Future[T any] interface {
+	// Blocks until the job has completed then returns its result.
+	Wait() (T, error)
+
+	// Like Wait but gives up early, returning ctx.Err(), if the given context is
+	// done before the job completes.
+	WaitContext(ctx context.Context) (T, error)
+
+	// Returns a channel that is closed once the job's result is available.
+	Done() <-chan struct{}
+}
+
+const (
+	futurePending int32 = iota
+	futureRunning
+	futureAbandoned
+)
+
+type future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+
+	// One of futurePending, futureRunning or futureAbandoned. A job only runs if
+	// it gets to futureRunning before the queue abandons it.
+	state atomic.Int32
+}
+
+var _ Future[int] = (*future[int])(nil)
+
+func (f *future[T]) resolve(value T, err error) {
+	f.value = value
+	f.err = err
+	close(f.done)
+}
+
+func (f *future[T]) abandon() {
+	if f.state.CompareAndSwap(futurePending, futureAbandoned) {
+		var zero T
+		f.resolve(zero, QueueShutdownError)
+	}
+}
+
+func (f *future[T]) Wait() (T, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+func (f *future[T]) WaitContext(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+func (f *future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Queues the given function on the render queue and returns a Future for its
+// result. Waiting on the Future only waits for this job, unlike Purge() which
+// also waits for every other goroutine's jobs.
+//
+// If the function panics, the Future resolves with an error describing the
+// panic and the panic is still reported through the queue's error callbacks.
+// If the queue is defunct, or becomes defunct before the function runs, the
+// Future resolves with QueueShutdownError and the function won't be run. Only
+// queues made by this package report going defunct; with other
+// RenderQueueInterface implementations, callers that can't block forever
+// should use WaitContext.
+func Submit[T any](queue RenderQueueInterface, fn func(RenderQueueState) (T, error)) Future[T] {
+	result := &future[T]{
+		done: make(chan struct{}),
+	}
+
+	tracker, tracked := queue.(abandonTracker)
+	var defunct bool
+	if tracked {
+		defunct = !tracker.track(result)
+	} else {
+		defunct = queue.IsDefunct()
+	}
+	if defunct {
+		var zero T
+		result.resolve(zero, QueueShutdownError)
+		return result
+	}
+
+	job := func(st RenderQueueState) {
+		if tracked {
+			tracker.forget(result)
+		}
+		if !result.state.CompareAndSwap(futurePending, futureRunning) {
+			// The queue went defunct first; the future's already resolved.
+			return
+		}
+
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+
+			var zero T
+			e := recover()
+			if e == nil {
+				// The function called runtime.Goexit; the render thread is going
+				// away.
+				result.resolve(zero, QueueShutdownError)
+				return
+			}
+
+			if err, ok := e.(error); ok {
+				result.resolve(zero, fmt.Errorf("render.Submit: job panicked: %w", err))
+			} else {
+				result.resolve(zero, fmt.Errorf("render.Submit: job panicked: %v", e))
+			}
+
+			// Re-raise so that the queue's error callbacks get notified.
+			panic(e)
+		}()
+
+		value, err := fn(st)
+		completed = true
+		result.resolve(value, err)
+	}
+
+	// Timing, GL errors and the like should point at fn, not at the job above.
+	if attributed, ok := queue.(RenderQueueWithAttributionInterface); ok {
+		attributed.QueueInLaneAttributed(LaneNormal, fn, job)
+	} else {
+		queue.Queue(job)
+	}
+
+	return result
+}
@@ -0,0 +1,205 @@
+package render_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/caffeine-storm/glop/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmit(t *testing.T) {
+	t.Run("resolves with the job's result", func(t *testing.T) {
+		queue := GivenARunningQueue()
+
+		fut := render.Submit(queue, func(render.RenderQueueState) (int, error) {
+			return 42, nil
+		})
+		value, err := fut.Wait()
+
+		require.NoError(t, err)
+		assert.Equal(t, 42, value)
+	})
+
+	t.Run("resolves with the job's error", func(t *testing.T) {
+		queue := GivenARunningQueue()
+		thisIsFine := &everythingIsFine{}
+
+		fut := render.Submit(queue, func(render.RenderQueueState) (string, error) {
+			return "", thisIsFine
+		})
+		_, err := fut.Wait()
+
+		assert.ErrorIs(t, err, thisIsFine)
+	})
+
+	t.Run("Done is closed once the result is available", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		fut := render.Submit(queue, func(render.RenderQueueState) (bool, error) {
+			return true, nil
+		})
+
+		select {
+		case <-fut.Done():
+			t.Fatalf("the queue hasn't started; the future must not be done")
+		default:
+		}
+
+		queue.StartProcessing()
+		<-fut.Done()
+
+		value, err := fut.Wait()
+		require.NoError(t, err)
+		assert.True(t, value)
+	})
+
+	t.Run("a panicking job resolves with an error and is still reported", func(t *testing.T) {
+		queue := GivenAQueue()
+		thisIsFine := &everythingIsFine{}
+
+		reported := make(chan error, 1)
+		queue.AddErrorCallback(func(q render.RenderQueueInterface, e error) {
+			reported <- e
+		})
+		queue.StartProcessing()
+
+		fut := render.Submit(queue, func(render.RenderQueueState) (int, error) {
+			panic(thisIsFine)
+		})
+		_, err := fut.Wait()
+
+		assert.ErrorIs(t, err, thisIsFine)
+		assert.ErrorIs(t, <-reported, thisIsFine)
+
+		// The queue must still be usable afterwards.
+		value, err := render.Submit(queue, func(render.RenderQueueState) (int, error) {
+			return 7, nil
+		}).Wait()
+		require.NoError(t, err)
+		assert.Equal(t, 7, value)
+	})
+
+	t.Run("a job that exits the render thread resolves with QueueShutdownError", func(t *testing.T) {
+		queue := GivenARunningQueue()
+
+		fut := render.Submit(queue, func(render.RenderQueueState) (int, error) {
+			runtime.Goexit()
+			return 0, nil
+		})
+		_, err := fut.Wait()
+
+		assert.ErrorIs(t, err, render.QueueShutdownError)
+	})
+
+	t.Run("jobs queued behind one that exits the render thread resolve with QueueShutdownError", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		render.Submit(queue, func(render.RenderQueueState) (int, error) {
+			runtime.Goexit()
+			return 0, nil
+		})
+		ran := false
+		fut := render.Submit(queue, func(render.RenderQueueState) (int, error) {
+			ran = true
+			return 1, nil
+		})
+		queue.StartProcessing()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := fut.WaitContext(ctx)
+
+		assert.ErrorIs(t, err, render.QueueShutdownError)
+		assert.False(t, ran)
+	})
+
+	t.Run("stopping the queue resolves pending jobs with QueueShutdownError", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		fut := render.Submit(queue, func(render.RenderQueueState) (int, error) {
+			return 0, nil
+		})
+		queue.StopProcessing()
+
+		select {
+		case <-fut.Done():
+		default:
+			t.Fatalf("a stopped queue won't run the job; the future must be done")
+		}
+		_, err := fut.Wait()
+		assert.ErrorIs(t, err, render.QueueShutdownError)
+	})
+
+	t.Run("submitting to a defunct queue fails fast", func(t *testing.T) {
+		queue := GivenAQueue()
+		queue.StopProcessing()
+
+		_, err := render.Submit(queue, func(render.RenderQueueState) (int, error) {
+			return 0, nil
+		}).Wait()
+
+		assert.ErrorIs(t, err, render.QueueShutdownError)
+	})
+
+	t.Run("WaitContext gives up when the context is done", func(t *testing.T) {
+		// Never started so the job will never run.
+		queue := GivenAQueue()
+
+		fut := render.Submit(queue, func(render.RenderQueueState) (int, error) {
+			return 0, nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		_, err := fut.WaitContext(ctx)
+
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("jobs are attributed to the submitted function", func(t *testing.T) {
+		attributions := make(chan string, 4)
+		queue := GivenATimedQueue(&render.JobTimingListener{
+			OnNotify: func(_ *render.JobTimingInfo, attribution string) {
+				attributions <- attribution
+			},
+		})
+		queue.StartProcessing()
+		<-attributions // the queue's initialization job
+
+		_, err := render.Submit(queue, func(render.RenderQueueState) (int, error) {
+			return 0, nil
+		}).Wait()
+		require.NoError(t, err)
+
+		attribution := <-attributions
+		assert.Contains(t, attribution, "render/future_test.go")
+	})
+
+	t.Run("does not wait for unrelated jobs", func(t *testing.T) {
+		queue := GivenARunningQueue()
+
+		unblock := make(chan bool)
+		defer close(unblock)
+
+		fut := render.Submit(queue, func(render.RenderQueueState) (int, error) {
+			return 1, nil
+		})
+
+		// Queue a job that blocks the render thread; a Purge() would hang but
+		// waiting on the future must not.
+		queue.Queue(func(render.RenderQueueState) {
+			<-unblock
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		value, err := fut.WaitContext(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, value)
+	})
+}
@@ -11,12 +11,15 @@ import (
 	"github.com/caffeine-storm/glu"
 )
 
-func logErrorsWithAttribution(logger glog.Logger, file string, line int) {
+// Returns the number of errors that were logged.
+func logErrorsWithAttribution(logger glog.Logger, file string, line int) int {
+	count := 0
 	for {
 		glErr := gl.GetError()
 		if glErr == gl.NO_ERROR {
-			return
+			return count
 		}
+		count++
 
 		glErrHex := fmt.Sprintf("0x%04x", glErr)
 		glErrMsg, err := glu.ErrorString(glErr)
@@ -41,6 +44,10 @@ func LogAndClearGlErrors(logger glog.Logger) {
 }
 
 func LogAndClearGlErrorsWithAttribution(logger glog.Logger, fn any) {
+	logAndCountGlErrorsWithAttribution(logger, fn)
+}
+
+func logAndCountGlErrorsWithAttribution(logger glog.Logger, fn any) int {
 	file, line := gloptest.FileLineForClosure(fn)
-	logErrorsWithAttribution(logger, file, line)
+	return logErrorsWithAttribution(logger, file, line)
 }
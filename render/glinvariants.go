@@ -1,4 +1,4 @@
-package rendertest
+package render
 
 import (
 	"errors"
@@ -8,10 +8,25 @@ import (
 
 	"github.com/caffeine-storm/gl"
 	"github.com/caffeine-storm/glop/glog"
-	"github.com/caffeine-storm/glop/render"
 	"github.com/caffeine-storm/mathgl"
 )
 
+// Reported through a RenderQueueInterface's error callbacks when a job leaves
+// GL state behind that it should have cleaned up. See SetGlInvariantChecks.
+type GlInvariantError struct {
+	// The RenderJob's GetSourceAttribution().
+	Attribution string
+	Err         error
+}
+
+func (e *GlInvariantError) Error() string {
+	return fmt.Sprintf("render job at %s leaked GL state: %v", e.Attribution, e.Err)
+}
+
+func (e *GlInvariantError) Unwrap() error {
+	return e.Err
+}
+
 func getBadMatrixStackSizes() map[string]int {
 	sizes := [3]int{
 		gl.GetInteger(gl.MODELVIEW_STACK_DEPTH),
@@ -56,18 +71,25 @@ func getBadMatrixValues() map[string]mathgl.Mat4 {
 	return ret
 }
 
-func checkMatrixInvariants() error {
-	// If the matrix stacks are size 1 with the identity on top, something is
-	// wrong.
+func checkMatrixStackInvariants() error {
 	mp := getBadMatrixStackSizes()
 	if len(mp) > 0 {
 		return fmt.Errorf("matrix stacks needed to all be size 1: stack sizes: %+v", mp)
 	}
+	return nil
+}
+
+func checkMatrixInvariants() error {
+	// If the matrix stacks are size 1 with the identity on top, something is
+	// wrong.
+	if err := checkMatrixStackInvariants(); err != nil {
+		return err
+	}
 	mpp := getBadMatrixValues()
 	if len(mpp) > 0 {
 		reports := []string{}
 		for key, val := range mpp {
-			reports = append(reports, fmt.Sprintf("%s:\n%v", key, render.Showmat(val)))
+			reports = append(reports, fmt.Sprintf("%s:\n%v", key, Showmat(val)))
 		}
 		return fmt.Errorf("matrix stacks needed to be topped with identity matrices:\n%s", strings.Join(reports, "\n"))
 	}
@@ -104,28 +126,37 @@ func checkBindingsInvariants() error {
 	return fmt.Errorf("need bindings unset but found bindings for: %v", badvals)
 }
 
-func checkColourInvariants() error {
-	// default fg/bg is white on black
-	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
-	black := color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+// default fg/bg is white on black
+var (
+	invariantForeground = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	invariantBackground = color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+)
 
-	fg := render.GetCurrentForegroundColour()
-	errs := []error{}
-	if fg != white {
-		errs = append(errs, fmt.Errorf("bad foreground colour: %v expected: %v (white)", fg, white))
+func checkForegroundInvariants() error {
+	fg := GetCurrentForegroundColour()
+	if fg != invariantForeground {
+		return fmt.Errorf("bad foreground colour: %v expected: %v (white)", fg, invariantForeground)
 	}
+	return nil
+}
+
+func checkColourInvariants() error {
+	errs := []error{checkForegroundInvariants()}
 
-	bg := render.GetCurrentBackgroundColor()
+	bg := GetCurrentBackgroundColor()
 	// Alpha doesn't matter for background/clearing
-	bg.A = black.A
-	if bg != black {
-		errs = append(errs, fmt.Errorf("bad background colour: %v expected %v (black)", bg, black))
+	bg.A = invariantBackground.A
+	if bg != invariantBackground {
+		errs = append(errs, fmt.Errorf("bad background colour: %v expected %v (black)", bg, invariantBackground))
 	}
 
 	return errors.Join(errs...)
 }
 
-func checkInvariants() error {
+// Checks that the matrix stacks each hold only an identity matrix, that no
+// buffers or 2D textures are bound and that we're drawing white on black.
+// Must be called on the render thread of a compatibility-profile context.
+func CheckGlInvariants() error {
 	return errors.Join(
 		checkMatrixInvariants(),
 		checkBindingsInvariants(),
@@ -133,16 +164,41 @@ func checkInvariants() error {
 	)
 }
 
+// Queues without a context, like those in tests of the queue itself, have
+// nothing to check.
+func hasCurrentContext() bool {
+	return gl.GetString(gl.VERSION) != ""
+}
+
+func isCoreProfile() bool {
+	return gl.GetInteger(gl.CONTEXT_PROFILE_MASK)&gl.CONTEXT_CORE_PROFILE_BIT != 0
+}
+
+// The part of CheckGlInvariants that's cheap enough to run after every job
+// and that's still meaningful in a running game; it's fine for a game to
+// leave a projection matrix or a clear colour set up, for example. In
+// core-profile contexts, only the bindings are checked.
+func checkCheapGlInvariants() error {
+	if isCoreProfile() {
+		return checkBindingsInvariants()
+	}
+	return errors.Join(
+		checkMatrixStackInvariants(),
+		checkBindingsInvariants(),
+		checkForegroundInvariants(),
+	)
+}
+
 func enforceMatrixStacksMustBeIdentitySingletons() {
 	sizes := [3]int{
 		gl.GetInteger(gl.MODELVIEW_STACK_DEPTH),
 		gl.GetInteger(gl.PROJECTION_STACK_DEPTH),
 		gl.GetInteger(gl.TEXTURE_STACK_DEPTH),
 	}
-	modes := [3]render.MatrixMode{
-		render.MatrixModeModelView,
-		render.MatrixModeProjection,
-		render.MatrixModeTexture,
+	modes := [3]MatrixMode{
+		MatrixModeModelView,
+		MatrixModeProjection,
+		MatrixModeTexture,
 	}
 
 	for i, sizei := range sizes {
@@ -166,12 +222,7 @@ func enforceMatrixStacksMustBeIdentitySingletons() {
 	}
 }
 
-func enforceClearBindingsSet() {
-	badBindings := getImproperlyBoundState()
-	if len(badBindings) > 0 {
-		glog.WarningLogger().Warn("rendertest enforcing bindings invariant", "state leakage", badBindings)
-	}
-
+func clearBindings() {
 	bufferBindings := []gl.GLenum{
 		gl.ARRAY_BUFFER,
 		gl.ELEMENT_ARRAY_BUFFER,
@@ -190,13 +241,54 @@ func enforceClearBindingsSet() {
 	}
 }
 
+func enforceClearBindingsSet() {
+	badBindings := getImproperlyBoundState()
+	if len(badBindings) > 0 {
+		glog.WarningLogger().Warn("rendertest enforcing bindings invariant", "state leakage", badBindings)
+	}
+
+	clearBindings()
+}
+
 func enforceColourInvariants() {
 	gl.Color4f(1, 1, 1, 1)
 	gl.ClearColor(0, 0, 0, 1)
 }
 
-func enforceInvariants() {
+// Puts back everything that CheckGlInvariants checks, logging a warning for
+// state that had leaked. Must be called on the render thread of a
+// compatibility-profile context.
+func EnforceGlInvariants() {
 	enforceMatrixStacksMustBeIdentitySingletons()
 	enforceClearBindingsSet()
 	enforceColourInvariants()
 }
+
+// Undoes whatever checkCheapGlInvariants complains about so that one leaky
+// job doesn't get every later job blamed too. The current matrix mode is left
+// alone.
+func enforceCheapGlInvariants() {
+	clearBindings()
+	if isCoreProfile() {
+		return
+	}
+
+	mode := gl.GetInteger(gl.MATRIX_MODE)
+	for stack, matrixMode := range map[gl.GLenum]MatrixMode{
+		gl.MODELVIEW_STACK_DEPTH:  MatrixModeModelView,
+		gl.PROJECTION_STACK_DEPTH: MatrixModeProjection,
+		gl.TEXTURE_STACK_DEPTH:    MatrixModeTexture,
+	} {
+		depth := gl.GetInteger(stack)
+		if depth <= 1 {
+			continue
+		}
+		gl.MatrixMode(gl.GLenum(matrixMode))
+		for ; depth > 1; depth-- {
+			gl.PopMatrix()
+		}
+	}
+	gl.MatrixMode(gl.GLenum(mode))
+
+	gl.Color4f(1, 1, 1, 1)
+}
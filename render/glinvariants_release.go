@@ -0,0 +1,5 @@
+//go:build !glopdebug
+
+package render
+
+const glInvariantChecksByDefault = false
@@ -0,0 +1,110 @@
+package render_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/gos"
+	"github.com/caffeine-storm/glop/gos/headless"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The rendertest harness keeps its own state on the matrix stacks between
+// jobs so we need a queue of our own.
+func givenAQueueWithAContext(t *testing.T) render.RenderQueueWithInvariantChecksInterface {
+	sys := gos.NewSystemInterface()
+	sys.Startup()
+	var initErr error
+	queue := render.MakeQueue(func(render.RenderQueueState) {
+		sys.CreateWindow(0, 0, 16, 16)
+		initErr = headless.CheckGlInit(gl.Init())
+	})
+	queue.StartProcessing()
+	queue.Purge()
+	t.Cleanup(queue.StopProcessing)
+	require.NoError(t, initErr)
+
+	return queue.(render.RenderQueueWithInvariantChecksInterface)
+}
+
+func TestGlInvariantChecks(t *testing.T) {
+	queue := givenAQueueWithAContext(t)
+
+	var reported []error
+	queue.AddErrorCallback(func(_ render.RenderQueueInterface, e error) {
+		reported = append(reported, e)
+	})
+
+	t.Run("can be turned off", func(t *testing.T) {
+		reported = nil
+		queue.SetGlInvariantChecks(false)
+		queue.Queue(func(render.RenderQueueState) {
+			gl.Color4f(1, 0, 0, 1)
+		})
+		queue.Queue(func(render.RenderQueueState) {
+			gl.Color4f(1, 1, 1, 1)
+		})
+		queue.Purge()
+
+		assert.Empty(t, reported)
+	})
+
+	t.Run("reports leaks against the leaky job", func(t *testing.T) {
+		reported = nil
+		queue.SetGlInvariantChecks(true)
+		defer queue.SetGlInvariantChecks(false)
+
+		var depthAfter int
+		var colourAfter [4]float32
+		queue.Queue(func(render.RenderQueueState) {
+			// Fine; everything is put back.
+			render.WithColour(0.5, 0.5, 0.5, 1, func() {})
+		})
+		queue.Queue(func(render.RenderQueueState) {
+			gl.MatrixMode(gl.MODELVIEW)
+			gl.PushMatrix()
+			gl.Color4f(1, 0, 0, 1)
+		})
+		queue.Queue(func(render.RenderQueueState) {
+			depthAfter = gl.GetInteger(gl.MODELVIEW_STACK_DEPTH)
+			gl.GetFloatv(gl.CURRENT_COLOR, colourAfter[:])
+		})
+		queue.Purge()
+
+		require.Len(t, reported, 1)
+		var invariantErr *render.GlInvariantError
+		require.True(t, errors.As(reported[0], &invariantErr))
+		assert.Contains(t, invariantErr.Attribution, "glinvariants_test.go")
+		assert.ErrorContains(t, invariantErr, "modelview")
+		assert.ErrorContains(t, invariantErr, "foreground")
+
+		// The leak was undone so later jobs start out clean.
+		assert.Equal(t, 1, depthAfter)
+		assert.Equal(t, [4]float32{1, 1, 1, 1}, colourAfter)
+	})
+
+	t.Run("run after the job's listeners", func(t *testing.T) {
+		queue.SetGlInvariantChecks(true)
+		defer queue.SetGlInvariantChecks(false)
+
+		var order []string
+		queue.AddTimingListener(&render.JobTimingListener{
+			OnNotify: func(*render.JobTimingInfo, string) {
+				order = append(order, "notified")
+			},
+		})
+		queue.AddErrorCallback(func(render.RenderQueueInterface, error) {
+			order = append(order, "reported")
+		})
+
+		queue.Queue(func(render.RenderQueueState) {
+			gl.Color4f(1, 0, 0, 1)
+		})
+		queue.Purge()
+
+		assert.Equal(t, []string{"notified", "reported"}, order)
+	})
+}
@@ -0,0 +1,174 @@
+package gltrace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/caffeine-storm/glop/render"
+)
+
+// Trace files start with this magic string followed by a format version.
+const magic = "GLOPTRACE"
+
+const formatVersion = 1
+
+// Everything recorded for a single RenderJob.
+type JobRecord struct {
+	// Counts up from 1 in the order that jobs ran.
+	Seq uint64
+
+	// The Lane that the job was queued in.
+	Lane render.Lane
+
+	// The job's RenderJob.GetSourceAttribution(). Empty for jobs that panicked;
+	// the render queue doesn't report which job that was.
+	Attribution string
+
+	// If the job panicked, this is the error it panicked with.
+	Err string
+
+	// The GL calls that the job issued, encoded by the native tracer. Use
+	// Describe to make sense of them.
+	Calls []byte
+}
+
+func (r *JobRecord) Panicked() bool {
+	return r.Err != ""
+}
+
+// Writes a trace file one JobRecord at a time.
+type Writer struct {
+	out *bufio.Writer
+	buf []byte
+}
+
+func NewWriter(out io.Writer) (*Writer, error) {
+	w := &Writer{
+		out: bufio.NewWriter(out),
+	}
+	w.buf = append(w.buf, magic...)
+	w.buf = binary.AppendUvarint(w.buf, formatVersion)
+	if _, err := w.out.Write(w.buf); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) Write(record *JobRecord) error {
+	w.buf = w.buf[:0]
+	w.buf = binary.AppendUvarint(w.buf, record.Seq)
+	w.buf = binary.AppendUvarint(w.buf, uint64(record.Lane))
+	w.buf = appendBytes(w.buf, []byte(record.Attribution))
+	w.buf = appendBytes(w.buf, []byte(record.Err))
+	w.buf = appendBytes(w.buf, record.Calls)
+	_, err := w.out.Write(w.buf)
+	return err
+}
+
+func (w *Writer) Flush() error {
+	return w.out.Flush()
+}
+
+func appendBytes(buf, data []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+// Reads back the JobRecords that a Writer wrote.
+type Reader struct {
+	in *bufio.Reader
+}
+
+func NewReader(in io.Reader) (*Reader, error) {
+	r := &Reader{
+		in: bufio.NewReader(in),
+	}
+
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(r.in, header); err != nil {
+		return nil, fmt.Errorf("couldn't read trace header: %w", err)
+	}
+	if !bytes.Equal(header, []byte(magic)) {
+		return nil, fmt.Errorf("not a trace file")
+	}
+	version, err := binary.ReadUvarint(r.in)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read trace version: %w", err)
+	}
+	if version != formatVersion {
+		return nil, fmt.Errorf("unsupported trace version %d", version)
+	}
+
+	return r, nil
+}
+
+// Returns io.EOF once every record has been read.
+func (r *Reader) Read() (*JobRecord, error) {
+	seq, err := binary.ReadUvarint(r.in)
+	if err != nil {
+		// A clean EOF before a record starts is the end of the trace.
+		return nil, err
+	}
+
+	record := &JobRecord{Seq: seq}
+	lane, err := binary.ReadUvarint(r.in)
+	if err != nil {
+		return nil, truncated(err)
+	}
+	record.Lane = render.Lane(lane)
+
+	attribution, err := r.readBytes()
+	if err != nil {
+		return nil, truncated(err)
+	}
+	record.Attribution = string(attribution)
+
+	jobErr, err := r.readBytes()
+	if err != nil {
+		return nil, truncated(err)
+	}
+	record.Err = string(jobErr)
+
+	record.Calls, err = r.readBytes()
+	if err != nil {
+		return nil, truncated(err)
+	}
+
+	return record, nil
+}
+
+func (r *Reader) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(r.in)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]byte, n)
+	_, err = io.ReadFull(r.in, ret)
+	return ret, err
+}
+
+func truncated(err error) error {
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("truncated trace record: %w", err)
+}
+
+// Reads every remaining record.
+func (r *Reader) ReadAll() ([]*JobRecord, error) {
+	var ret []*JobRecord
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return ret, nil
+		}
+		if err != nil {
+			return ret, err
+		}
+		ret = append(ret, record)
+	}
+}
@@ -0,0 +1,52 @@
+// Package gltrace records the GL calls that render jobs make so that they can
+// be inspected or replayed later; see tools/gl-replay.
+//
+// Tracing works by interposing on the GL entry points that glop uses. The
+// OpenGL 1.1 entry points are defined by this package so linking it into a
+// binary interposes on libGL's definitions for the whole process, whether or
+// not a Tracer is ever used. Newer entry points are reached through GLEW's
+// function pointers; Attach queues a job that swaps those for the tracer's
+// wrappers and turns recording on. Until then, and after Stop, the wrappers
+// forward straight to the driver so the cost of an idle tracer is one extra
+// call per GL call.
+//
+// Some calls can't be captured faithfully. Vertex arrays in client memory
+// have no size that the tracer can learn, for example. Such calls are
+// recorded as 'untraceable' and replaying stops at the first one in a job.
+package gltrace
+
+// #cgo LDFLAGS: -lGLEW -lGL -ldl
+// #include <stdlib.h>
+// #include "include/gltrace.h"
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+)
+
+// Returns the calls recorded since the last call to takeCalls.
+func takeCalls() []byte {
+	var data *C.uint8_t
+	var length C.size_t
+	C.GlopTraceTakeCalls(&data, &length)
+	defer C.free(unsafe.Pointer(data))
+
+	if length == 0 {
+		return nil
+	}
+	return C.GoBytes(unsafe.Pointer(data), C.int(length))
+}
+
+// Returns one line per call in the given JobRecord.Calls, like
+// 'glBindTexture(0x0DE1, 3)'.
+func Describe(calls []byte) []string {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	described := C.GlopTraceDescribe((*C.uint8_t)(unsafe.SliceData(calls)), C.size_t(len(calls)))
+	defer C.free(unsafe.Pointer(described))
+
+	return strings.Split(strings.TrimSuffix(C.GoString(described), "\n"), "\n")
+}
@@ -0,0 +1,218 @@
+package gltrace_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/gltrace"
+	"github.com/caffeine-storm/glop/render/rendertest"
+	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceFormat(t *testing.T) {
+	records := []*gltrace.JobRecord{
+		{
+			Seq:         1,
+			Lane:        render.LaneNormal,
+			Attribution: "some/file.go: 12",
+			Calls:       []byte{1, 2, 3},
+		},
+		{
+			Seq:         3,
+			Lane:        render.LaneBackground,
+			Attribution: "",
+			Err:         "oh no",
+		},
+	}
+
+	t.Run("round trips", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		writer, err := gltrace.NewWriter(buf)
+		require.NoError(t, err)
+		for _, rec := range records {
+			require.NoError(t, writer.Write(rec))
+		}
+		require.NoError(t, writer.Flush())
+
+		reader, err := gltrace.NewReader(buf)
+		require.NoError(t, err)
+		readBack, err := reader.ReadAll()
+		require.NoError(t, err)
+
+		require.Len(t, readBack, len(records))
+		assert.Equal(t, records[0], readBack[0])
+		assert.Equal(t, records[1].Err, readBack[1].Err)
+		assert.True(t, readBack[1].Panicked())
+		assert.Empty(t, readBack[1].Calls)
+	})
+
+	t.Run("rejects other files", func(t *testing.T) {
+		_, err := gltrace.NewReader(bytes.NewBufferString("definitely not a trace"))
+		assert.Error(t, err)
+	})
+
+	t.Run("reports truncation", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		writer, err := gltrace.NewWriter(buf)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(records[0]))
+		require.NoError(t, writer.Flush())
+
+		reader, err := gltrace.NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+		require.NoError(t, err)
+		_, err = reader.Read()
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
+
+func readPixel(x, y int) []byte {
+	pixel := make([]byte, 4)
+	gl.ReadPixels(x, y, 1, 1, gl.RGBA, gl.UNSIGNED_BYTE, pixel)
+	return pixel
+}
+
+func traceJobs(t *testing.T, queue render.RenderQueueInterface, jobs ...render.RenderJob) []*gltrace.JobRecord {
+	buf := &bytes.Buffer{}
+	tracer := gltrace.Attach(queue, buf)
+	for _, job := range jobs {
+		queue.Queue(job)
+	}
+	require.NoError(t, tracer.Stop())
+
+	reader, err := gltrace.NewReader(buf)
+	require.NoError(t, err)
+	records, err := reader.ReadAll()
+	require.NoError(t, err)
+	return records
+}
+
+func replay(t *testing.T, queue render.RenderQueueInterface, records []*gltrace.JobRecord) {
+	queue.Queue(func(render.RenderQueueState) {
+		gl.ClearColor(0, 0, 1, 1)
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		gl.ClearColor(0, 0, 0, 0)
+
+		replayer := gltrace.NewReplayer()
+		defer replayer.Close()
+		for _, rec := range records {
+			assert.NoError(t, replayer.Replay(rec))
+		}
+	})
+	queue.Purge()
+}
+
+func TestTraceAndReplay(t *testing.T) {
+	t.Run("records each job with its attribution", func(t *testing.T) {
+		testbuilder.New().WithSize(64, 64).WithQueue().Run(func(queue render.RenderQueueInterface) {
+			records := traceJobs(t, queue, func(render.RenderQueueState) {
+				gl.Enable(gl.BLEND)
+				gl.Disable(gl.BLEND)
+			})
+
+			require.Len(t, records, 1)
+			assert.Contains(t, records[0].Attribution, "gltrace_test.go")
+			assert.Equal(t, render.LaneNormal, records[0].Lane)
+			assert.False(t, records[0].Panicked())
+			assert.Equal(t, []string{
+				"glEnable(0x0BE2)",
+				"glDisable(0x0BE2)",
+			}, gltrace.Describe(records[0].Calls))
+		})
+	})
+
+	t.Run("records panicking jobs with their lane and attribution", func(t *testing.T) {
+		testbuilder.New().WithSize(64, 64).WithQueue().Run(func(queue render.RenderQueueInterface) {
+			buf := &bytes.Buffer{}
+			tracer := gltrace.Attach(queue, buf)
+			queue.QueueInLane(render.LaneBackground, func(render.RenderQueueState) {
+				gl.Enable(gl.BLEND)
+				gl.Disable(gl.BLEND)
+				panic(fmt.Errorf("oh no"))
+			})
+			// The test queue re-raises job errors from Purge.
+			assert.Panics(t, queue.Purge)
+			require.NoError(t, tracer.Stop())
+
+			reader, err := gltrace.NewReader(buf)
+			require.NoError(t, err)
+			records, err := reader.ReadAll()
+			require.NoError(t, err)
+
+			require.Len(t, records, 1)
+			assert.True(t, records[0].Panicked())
+			assert.Contains(t, records[0].Err, "oh no")
+			assert.Equal(t, render.LaneBackground, records[0].Lane)
+			assert.Contains(t, records[0].Attribution, "gltrace_test.go")
+			assert.Equal(t, []string{
+				"glEnable(0x0BE2)",
+				"glDisable(0x0BE2)",
+			}, gltrace.Describe(records[0].Calls))
+		})
+	})
+
+	t.Run("replays immediate mode drawing", func(t *testing.T) {
+		testbuilder.New().WithSize(64, 64).WithQueue().Run(func(queue render.RenderQueueInterface) {
+			records := traceJobs(t, queue, func(render.RenderQueueState) {
+				rendertest.BlankAndDrawRectNdc(-1, -1, 0, 0)
+			})
+
+			replay(t, queue, records)
+
+			queue.Queue(func(render.RenderQueueState) {
+				assert.Equal(t, []byte{255, 0, 0, 255}, readPixel(10, 10))
+				assert.Equal(t, []byte{0, 0, 0, 255}, readPixel(50, 50))
+			})
+			queue.Purge()
+		})
+	})
+
+	t.Run("replays textures under new names", func(t *testing.T) {
+		testbuilder.New().WithSize(64, 64).WithQueue().Run(func(queue render.RenderQueueInterface) {
+			var original gl.Texture
+			records := traceJobs(t, queue, func(render.RenderQueueState) {
+				original = gl.GenTexture()
+				original.Bind(gl.TEXTURE_2D)
+				gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+				gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+				gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, 1, 1, 0, gl.RGBA, gl.UNSIGNED_BYTE, []byte{0, 255, 0, 255})
+				gl.Texture(0).Bind(gl.TEXTURE_2D)
+			}, func(render.RenderQueueState) {
+				render.WithFreshMatrices(func() {
+					gl.Enable(gl.TEXTURE_2D)
+					original.Bind(gl.TEXTURE_2D)
+					gl.Color4d(1, 1, 1, 1)
+					gl.Begin(gl.QUADS)
+					gl.TexCoord2d(0, 0)
+					gl.Vertex2d(-1, -1)
+					gl.TexCoord2d(1, 0)
+					gl.Vertex2d(1, -1)
+					gl.TexCoord2d(1, 1)
+					gl.Vertex2d(1, 1)
+					gl.TexCoord2d(0, 1)
+					gl.Vertex2d(-1, 1)
+					gl.End()
+					gl.Texture(0).Bind(gl.TEXTURE_2D)
+					gl.Disable(gl.TEXTURE_2D)
+				})
+			})
+			require.Len(t, records, 2)
+			assert.Contains(t, gltrace.Describe(records[0].Calls), "glTexImage2D(0x0DE1, 0, 6408, 1, 1, 0, 0x1908, 0x1401, <4 bytes>)")
+
+			// The original texture still exists so the replayed one has to get a
+			// different name.
+			replay(t, queue, records)
+
+			queue.Queue(func(render.RenderQueueState) {
+				assert.Equal(t, []byte{0, 255, 0, 255}, readPixel(32, 32))
+				original.Delete()
+			})
+			queue.Purge()
+		})
+	})
+}
@@ -0,0 +1,44 @@
+#ifndef GLOP_RENDER_GLTRACE_GLTRACE_H
+#define GLOP_RENDER_GLTRACE_GLTRACE_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+// Routes GL entry points that GLEW resolves at runtime through the tracer.
+// Must be called on the render thread after glewInit. Calling it more than
+// once is harmless.
+void GlopTraceInstall();
+
+// While enabled, traced GL calls are appended to an internal buffer.
+void GlopTraceSetEnabled(int enabled);
+
+// Hands over the calls recorded since the last call to GlopTraceTakeCalls.
+// The caller is responsible for calling free(*data).
+void GlopTraceTakeCalls(uint8_t **data, size_t *len);
+
+// Returns a newline-separated, human readable listing of the given calls. The
+// caller is responsible for calling free() on the result.
+char *GlopTraceDescribe(uint8_t const *data, size_t len);
+
+// Replays calls into the current GL context. A replayer remembers how object
+// names in the trace map to object names in the current context so use one
+// replayer per trace.
+struct GlopTraceReplayer;
+struct GlopTraceReplayer *GlopTraceNewReplayer();
+void GlopTraceFreeReplayer(struct GlopTraceReplayer *);
+
+// Returns the number of calls replayed. If the calls can't be replayed in
+// full, *err is set to a description of the problem that the caller must
+// free().
+size_t GlopTraceReplay(struct GlopTraceReplayer *, uint8_t const *data,
+                       size_t len, char **err);
+
+#ifdef __cplusplus
+}  // extern "C"
+#endif
+
+#endif  // GLOP_RENDER_GLTRACE_GLTRACE_H
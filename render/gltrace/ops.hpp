@@ -0,0 +1,178 @@
+#ifndef GLOP_RENDER_GLTRACE_OPS_HPP
+#define GLOP_RENDER_GLTRACE_OPS_HPP
+
+#include <cstddef>
+#include <cstdint>
+#include <cstring>
+#include <string>
+#include <vector>
+
+// Each traced call is encoded as a 16-bit op id followed by its arguments.
+// An op's signature describes its arguments, one character per argument:
+//
+//   'e' enum          'i' signed int      'u' unsigned int/bitfield/boolean
+//   'o' size/offset   'f' float           'd' double
+//   't' texture       'r' buffer          'v' vertex array
+//   'F' framebuffer   's' shader          'p' program
+//   'l' uniform location
+//   'B' blob of bytes
+//   'T'/'R'/'V'/'N' an array of texture/buffer/vertex array/framebuffer names
+//
+// Scalars and single names take 8 bytes (little-endian; floats are widened to
+// doubles). Blobs and arrays are a 32-bit length followed by their contents.
+//
+// Op ids are written to trace files; only ever append to this list.
+#define GLTRACE_OPS(X)                            \
+  X(Untraceable, "B")                             \
+  X(Enable, "e")                                  \
+  X(Disable, "e")                                 \
+  X(BlendFunc, "ee")                              \
+  X(MatrixMode, "e")                              \
+  X(LoadIdentity, "")                             \
+  X(PushMatrix, "")                               \
+  X(PopMatrix, "")                                \
+  X(Ortho, "dddddd")                              \
+  X(LoadMatrixf, "B")                             \
+  X(MultMatrixf, "B")                             \
+  X(Translated, "ddd")                            \
+  X(Scaled, "ddd")                                \
+  X(Rotated, "dddd")                              \
+  X(Begin, "e")                                   \
+  X(End, "")                                      \
+  X(Vertex2i, "ii")                               \
+  X(Vertex2f, "ff")                               \
+  X(Vertex2d, "dd")                               \
+  X(Vertex3f, "fff")                              \
+  X(Vertex3d, "ddd")                              \
+  X(Color3f, "fff")                               \
+  X(Color3d, "ddd")                               \
+  X(Color4f, "ffff")                              \
+  X(Color4d, "dddd")                              \
+  X(Color4ub, "uuuu")                             \
+  X(TexCoord2f, "ff")                             \
+  X(TexCoord2d, "dd")                             \
+  X(Clear, "u")                                   \
+  X(ClearColor, "ffff")                           \
+  X(ClearDepth, "d")                              \
+  X(ClearStencil, "i")                            \
+  X(Viewport, "iiii")                             \
+  X(Scissor, "iiii")                              \
+  X(PushAttrib, "u")                              \
+  X(PopAttrib, "")                                \
+  X(TexParameterf, "eef")                         \
+  X(TexParameteri, "eei")                         \
+  X(TexEnvf, "eef")                               \
+  X(TexEnvi, "eei")                               \
+  X(BindTexture, "et")                            \
+  X(GenTextures, "T")                             \
+  X(DeleteTextures, "T")                          \
+  X(TexImage2D, "eiiiiieeB")                      \
+  X(TexSubImage2D, "eiiiiieeB")                   \
+  X(PixelStorei, "ei")                            \
+  X(EnableClientState, "e")                       \
+  X(DisableClientState, "e")                      \
+  X(VertexPointer, "ieio")                        \
+  X(TexCoordPointer, "ieio")                      \
+  X(ColorPointer, "ieio")                         \
+  X(DrawArrays, "eii")                            \
+  X(DrawElements, "eieoB")                        \
+  X(PolygonMode, "ee")                            \
+  X(DepthFunc, "e")                               \
+  X(DepthMask, "u")                               \
+  X(ColorMask, "uuuu")                            \
+  X(ClipPlane, "eB")                              \
+  X(LineWidth, "f")                               \
+  X(PointSize, "f")                               \
+  X(ShadeModel, "e")                              \
+  X(AlphaFunc, "ef")                              \
+  X(Finish, "")                                   \
+  X(Flush, "")                                    \
+  X(ActiveTexture, "e")                           \
+  X(BlendFuncSeparate, "eeee")                    \
+  X(BlendEquation, "e")                           \
+  X(BindBuffer, "er")                             \
+  X(GenBuffers, "R")                              \
+  X(DeleteBuffers, "R")                           \
+  X(BufferData, "eoBe")                           \
+  X(BufferSubData, "eoB")                         \
+  X(CreateShader, "es")                           \
+  X(ShaderSource, "sB")                           \
+  X(CompileShader, "s")                           \
+  X(DeleteShader, "s")                            \
+  X(CreateProgram, "p")                           \
+  X(AttachShader, "ps")                           \
+  X(DetachShader, "ps")                           \
+  X(LinkProgram, "p")                             \
+  X(UseProgram, "p")                              \
+  X(DeleteProgram, "p")                           \
+  X(BindAttribLocation, "puB")                    \
+  X(GetUniformLocation, "pBl")                    \
+  X(Uniform1i, "li")                              \
+  X(Uniform1f, "lf")                              \
+  X(Uniform2f, "lff")                             \
+  X(Uniform3f, "lfff")                            \
+  X(Uniform4f, "lffff")                           \
+  X(Uniform1iv, "liB")                            \
+  X(Uniform1fv, "liB")                            \
+  X(Uniform2fv, "liB")                            \
+  X(Uniform3fv, "liB")                            \
+  X(Uniform4fv, "liB")                            \
+  X(UniformMatrix3fv, "liuB")                     \
+  X(UniformMatrix4fv, "liuB")                     \
+  X(VertexAttribPointer, "uieuio")                \
+  X(EnableVertexAttribArray, "u")                 \
+  X(DisableVertexAttribArray, "u")                \
+  X(GenVertexArrays, "V")                         \
+  X(DeleteVertexArrays, "V")                      \
+  X(BindVertexArray, "v")                         \
+  X(GenFramebuffers, "N")                         \
+  X(DeleteFramebuffers, "N")                      \
+  X(BindFramebuffer, "eF")                        \
+  X(FramebufferTexture2D, "eeeti")                \
+  X(GenerateMipmap, "e")                          \
+  X(BlitFramebuffer, "iiiiiiiiue")
+
+enum GlTraceOp : uint16_t {
+#define GLTRACE_ENUM(name, sig) kOp##name,
+  GLTRACE_OPS(GLTRACE_ENUM)
+#undef GLTRACE_ENUM
+      kNumOps
+};
+
+struct GlTraceOpInfo {
+  char const *name;
+  char const *signature;
+};
+
+extern GlTraceOpInfo const kGlTraceOps[kNumOps];
+
+// One decoded argument. Which field is meaningful depends on the op's
+// signature.
+struct GlTraceArg {
+  uint64_t bits = 0;
+  std::string blob;
+  std::vector<uint32_t> names;
+
+  int64_t asInt() const { return static_cast<int64_t>(bits); }
+  uint32_t asUint() const { return static_cast<uint32_t>(bits); }
+  double asDouble() const {
+    double ret;
+    std::memcpy(&ret, &bits, sizeof(ret));
+    return ret;
+  }
+};
+
+struct GlTraceCall {
+  GlTraceOp op;
+  std::vector<GlTraceArg> args;
+};
+
+// Decodes the call starting at data[*offset] and advances *offset past it.
+// Returns false if the data is truncated or names an unknown op.
+bool decodeCall(uint8_t const *data, size_t len, size_t *offset,
+                GlTraceCall *out);
+
+// Renders a call like 'glEnable(0x0BE2)' for humans.
+std::string describeCall(GlTraceCall const &call);
+
+#endif  // GLOP_RENDER_GLTRACE_OPS_HPP
@@ -0,0 +1,1039 @@
+#include <GL/glew.h>
+#include <dlfcn.h>
+
+#include <cstddef>
+#include <cstdint>
+#include <cstdlib>
+#include <cstring>
+#include <map>
+#include <string>
+#include <vector>
+
+#include "../../gos/linux/logging.hpp"
+#include "include/gltrace.h"
+#include "ops.hpp"
+
+// Real GLEW builds route everything newer than OpenGL 1.1 through function
+// pointers that glewInit fills in; those get swapped for our wrappers in
+// GlopTraceInstall. Otherwise, every entry point is linked directly against
+// libGL and defining it here interposes on libGL's definition.
+#if defined(glUseProgram)
+#define GLTRACE_GLEW_POINTERS 1
+#else
+#define GLTRACE_GLEW_POINTERS 0
+#endif
+
+static bool enabled = false;
+static std::vector<uint8_t> recorded;
+
+static void *resolveNext(char const *name) {
+  void *ret = dlsym(RTLD_NEXT, name);
+  if (ret == nullptr) {
+    LOG_FATAL("gltrace: couldn't resolve " << name);
+    std::abort();
+  }
+  return ret;
+}
+
+#define GLTRACE_CORE(ret, n, params)              \
+  static decltype(&::gl##n) real_gl##n = nullptr; \
+  extern "C" ret GLAPIENTRY gl##n params
+
+// Resolves the next definition of an interposed entry point on first use.
+template <typename Fn>
+static Fn resolved(Fn *slot, char const *name) {
+  if (*slot == nullptr) {
+    *slot = reinterpret_cast<Fn>(resolveNext(name));
+  }
+  return *slot;
+}
+#define REAL(n) resolved(&real_gl##n, "gl" #n)
+
+#if GLTRACE_GLEW_POINTERS
+#define GLTRACE_EXT(ret, n, PFN, params) \
+  static PFN real_gl##n = nullptr;      \
+  static ret GLAPIENTRY traced_gl##n params
+#define REAL_EXT(n) real_gl##n
+#else
+#define GLTRACE_EXT(ret, n, PFN, params) \
+  static PFN real_gl##n = nullptr;      \
+  extern "C" ret GLAPIENTRY gl##n params
+#define REAL_EXT(n) REAL(n)
+#endif
+
+namespace {
+
+// Appends a single call to the recording. Assumes a little-endian host, like
+// every platform glop runs on.
+class Rec {
+ public:
+  explicit Rec(GlTraceOp op) {
+    uint16_t raw = op;
+    put(&raw, sizeof(raw));
+  }
+
+  Rec &u(uint64_t val) {
+    put(&val, sizeof(val));
+    return *this;
+  }
+
+  Rec &i(int64_t val) { return u(static_cast<uint64_t>(val)); }
+
+  Rec &d(double val) {
+    uint64_t bits;
+    std::memcpy(&bits, &val, sizeof(bits));
+    return u(bits);
+  }
+
+  Rec &o(void const *ptr) { return u(reinterpret_cast<uintptr_t>(ptr)); }
+
+  Rec &blob(void const *data, size_t len) {
+    uint32_t len32 = len;
+    put(&len32, sizeof(len32));
+    put(data, len);
+    return *this;
+  }
+
+  Rec &str(char const *s) { return blob(s, s == nullptr ? 0 : strlen(s)); }
+
+  Rec &names(GLsizei n, GLuint const *names) {
+    return blob(names, n < 0 ? 0 : n * sizeof(GLuint));
+  }
+
+ private:
+  static void put(void const *data, size_t len) {
+    if (len == 0) {
+      return;
+    }
+    auto bytes = static_cast<uint8_t const *>(data);
+    recorded.insert(recorded.end(), bytes, bytes + len);
+  }
+};
+
+GLint getInt(GLenum pname) {
+  GLint ret = 0;
+  glGetIntegerv(pname, &ret);
+  return ret;
+}
+
+void untraceable(char const *what) { Rec(kOpUntraceable).str(what); }
+
+// Pointer arguments are offsets when a buffer is bound; client-side memory
+// can't be sized so it can't be recorded.
+bool isClientMemory(GLenum binding, void const *ptr) {
+  return ptr != nullptr && getInt(binding) == 0;
+}
+
+size_t componentCount(GLenum format) {
+  switch (format) {
+    case GL_RED:
+    case GL_GREEN:
+    case GL_BLUE:
+    case GL_ALPHA:
+    case GL_LUMINANCE:
+    case GL_DEPTH_COMPONENT:
+    case GL_STENCIL_INDEX:
+      return 1;
+    case GL_RG:
+    case GL_LUMINANCE_ALPHA:
+      return 2;
+    case GL_RGB:
+    case GL_BGR:
+      return 3;
+    case GL_RGBA:
+    case GL_BGRA:
+      return 4;
+  }
+  return 0;
+}
+
+size_t bytesPerPixel(GLenum format, GLenum type) {
+  switch (type) {
+    case GL_UNSIGNED_BYTE:
+    case GL_BYTE:
+      return componentCount(format);
+    case GL_UNSIGNED_SHORT:
+    case GL_SHORT:
+    case GL_HALF_FLOAT:
+      return 2 * componentCount(format);
+    case GL_UNSIGNED_INT:
+    case GL_INT:
+    case GL_FLOAT:
+      return 4 * componentCount(format);
+    case GL_UNSIGNED_BYTE_3_3_2:
+    case GL_UNSIGNED_BYTE_2_3_3_REV:
+      return 1;
+    case GL_UNSIGNED_SHORT_5_6_5:
+    case GL_UNSIGNED_SHORT_5_6_5_REV:
+    case GL_UNSIGNED_SHORT_4_4_4_4:
+    case GL_UNSIGNED_SHORT_4_4_4_4_REV:
+    case GL_UNSIGNED_SHORT_5_5_5_1:
+    case GL_UNSIGNED_SHORT_1_5_5_5_REV:
+      return 2;
+    case GL_UNSIGNED_INT_8_8_8_8:
+    case GL_UNSIGNED_INT_8_8_8_8_REV:
+    case GL_UNSIGNED_INT_10_10_10_2:
+    case GL_UNSIGNED_INT_2_10_10_10_REV:
+      return 4;
+  }
+  return 0;
+}
+
+// Returns the number of bytes that GL will read for an image upload given the
+// current unpack state. Returns false if we can't tell.
+bool imageSize(GLsizei width, GLsizei height, GLenum format, GLenum type,
+               size_t *out) {
+  size_t bpp = bytesPerPixel(format, type);
+  if (bpp == 0 || getInt(GL_UNPACK_SKIP_ROWS) != 0 ||
+      getInt(GL_UNPACK_SKIP_PIXELS) != 0) {
+    return false;
+  }
+  if (width <= 0 || height <= 0) {
+    *out = 0;
+    return true;
+  }
+
+  size_t alignment = getInt(GL_UNPACK_ALIGNMENT);
+  GLint rowLength = getInt(GL_UNPACK_ROW_LENGTH);
+  size_t rowPixels = rowLength > 0 ? rowLength : width;
+  size_t stride = (rowPixels * bpp + alignment - 1) / alignment * alignment;
+  *out = stride * (height - 1) + width * bpp;
+  return true;
+}
+
+size_t indexSize(GLenum type) {
+  switch (type) {
+    case GL_UNSIGNED_BYTE:
+      return 1;
+    case GL_UNSIGNED_SHORT:
+      return 2;
+    case GL_UNSIGNED_INT:
+      return 4;
+  }
+  return 0;
+}
+
+struct Mapping {
+  void *ptr;
+  GLenum access;
+  GLint size;
+};
+std::map<GLenum, Mapping> mappings;
+
+}  // namespace
+
+GLTRACE_CORE(void, Enable, (GLenum cap)) {
+  REAL(Enable)(cap);
+  if (enabled) Rec(kOpEnable).u(cap);
+}
+
+GLTRACE_CORE(void, Disable, (GLenum cap)) {
+  REAL(Disable)(cap);
+  if (enabled) Rec(kOpDisable).u(cap);
+}
+
+GLTRACE_CORE(void, BlendFunc, (GLenum sfactor, GLenum dfactor)) {
+  REAL(BlendFunc)(sfactor, dfactor);
+  if (enabled) Rec(kOpBlendFunc).u(sfactor).u(dfactor);
+}
+
+GLTRACE_CORE(void, MatrixMode, (GLenum mode)) {
+  REAL(MatrixMode)(mode);
+  if (enabled) Rec(kOpMatrixMode).u(mode);
+}
+
+GLTRACE_CORE(void, LoadIdentity, ()) {
+  REAL(LoadIdentity)();
+  if (enabled) Rec{kOpLoadIdentity};
+}
+
+GLTRACE_CORE(void, PushMatrix, ()) {
+  REAL(PushMatrix)();
+  if (enabled) Rec{kOpPushMatrix};
+}
+
+GLTRACE_CORE(void, PopMatrix, ()) {
+  REAL(PopMatrix)();
+  if (enabled) Rec{kOpPopMatrix};
+}
+
+GLTRACE_CORE(void, Ortho,
+             (GLdouble left, GLdouble right, GLdouble bottom, GLdouble top,
+              GLdouble near_val, GLdouble far_val)) {
+  REAL(Ortho)(left, right, bottom, top, near_val, far_val);
+  if (enabled)
+    Rec(kOpOrtho).d(left).d(right).d(bottom).d(top).d(near_val).d(far_val);
+}
+
+GLTRACE_CORE(void, LoadMatrixf, (GLfloat const *m)) {
+  REAL(LoadMatrixf)(m);
+  if (enabled) Rec(kOpLoadMatrixf).blob(m, 16 * sizeof(GLfloat));
+}
+
+GLTRACE_CORE(void, MultMatrixf, (GLfloat const *m)) {
+  REAL(MultMatrixf)(m);
+  if (enabled) Rec(kOpMultMatrixf).blob(m, 16 * sizeof(GLfloat));
+}
+
+GLTRACE_CORE(void, Translated, (GLdouble x, GLdouble y, GLdouble z)) {
+  REAL(Translated)(x, y, z);
+  if (enabled) Rec(kOpTranslated).d(x).d(y).d(z);
+}
+
+GLTRACE_CORE(void, Scaled, (GLdouble x, GLdouble y, GLdouble z)) {
+  REAL(Scaled)(x, y, z);
+  if (enabled) Rec(kOpScaled).d(x).d(y).d(z);
+}
+
+GLTRACE_CORE(void, Rotated,
+             (GLdouble angle, GLdouble x, GLdouble y, GLdouble z)) {
+  REAL(Rotated)(angle, x, y, z);
+  if (enabled) Rec(kOpRotated).d(angle).d(x).d(y).d(z);
+}
+
+GLTRACE_CORE(void, Begin, (GLenum mode)) {
+  REAL(Begin)(mode);
+  if (enabled) Rec(kOpBegin).u(mode);
+}
+
+GLTRACE_CORE(void, End, ()) {
+  REAL(End)();
+  if (enabled) Rec{kOpEnd};
+}
+
+GLTRACE_CORE(void, Vertex2i, (GLint x, GLint y)) {
+  REAL(Vertex2i)(x, y);
+  if (enabled) Rec(kOpVertex2i).i(x).i(y);
+}
+
+GLTRACE_CORE(void, Vertex2f, (GLfloat x, GLfloat y)) {
+  REAL(Vertex2f)(x, y);
+  if (enabled) Rec(kOpVertex2f).d(x).d(y);
+}
+
+GLTRACE_CORE(void, Vertex2d, (GLdouble x, GLdouble y)) {
+  REAL(Vertex2d)(x, y);
+  if (enabled) Rec(kOpVertex2d).d(x).d(y);
+}
+
+GLTRACE_CORE(void, Vertex3f, (GLfloat x, GLfloat y, GLfloat z)) {
+  REAL(Vertex3f)(x, y, z);
+  if (enabled) Rec(kOpVertex3f).d(x).d(y).d(z);
+}
+
+GLTRACE_CORE(void, Vertex3d, (GLdouble x, GLdouble y, GLdouble z)) {
+  REAL(Vertex3d)(x, y, z);
+  if (enabled) Rec(kOpVertex3d).d(x).d(y).d(z);
+}
+
+GLTRACE_CORE(void, Color3f, (GLfloat r, GLfloat g, GLfloat b)) {
+  REAL(Color3f)(r, g, b);
+  if (enabled) Rec(kOpColor3f).d(r).d(g).d(b);
+}
+
+GLTRACE_CORE(void, Color3d, (GLdouble r, GLdouble g, GLdouble b)) {
+  REAL(Color3d)(r, g, b);
+  if (enabled) Rec(kOpColor3d).d(r).d(g).d(b);
+}
+
+GLTRACE_CORE(void, Color4f, (GLfloat r, GLfloat g, GLfloat b, GLfloat a)) {
+  REAL(Color4f)(r, g, b, a);
+  if (enabled) Rec(kOpColor4f).d(r).d(g).d(b).d(a);
+}
+
+GLTRACE_CORE(void, Color4d,
+             (GLdouble r, GLdouble g, GLdouble b, GLdouble a)) {
+  REAL(Color4d)(r, g, b, a);
+  if (enabled) Rec(kOpColor4d).d(r).d(g).d(b).d(a);
+}
+
+GLTRACE_CORE(void, Color4ub, (GLubyte r, GLubyte g, GLubyte b, GLubyte a)) {
+  REAL(Color4ub)(r, g, b, a);
+  if (enabled) Rec(kOpColor4ub).u(r).u(g).u(b).u(a);
+}
+
+GLTRACE_CORE(void, TexCoord2f, (GLfloat s, GLfloat t)) {
+  REAL(TexCoord2f)(s, t);
+  if (enabled) Rec(kOpTexCoord2f).d(s).d(t);
+}
+
+GLTRACE_CORE(void, TexCoord2d, (GLdouble s, GLdouble t)) {
+  REAL(TexCoord2d)(s, t);
+  if (enabled) Rec(kOpTexCoord2d).d(s).d(t);
+}
+
+GLTRACE_CORE(void, Clear, (GLbitfield mask)) {
+  REAL(Clear)(mask);
+  if (enabled) Rec(kOpClear).u(mask);
+}
+
+GLTRACE_CORE(void, ClearColor,
+             (GLclampf red, GLclampf green, GLclampf blue, GLclampf alpha)) {
+  REAL(ClearColor)(red, green, blue, alpha);
+  if (enabled) Rec(kOpClearColor).d(red).d(green).d(blue).d(alpha);
+}
+
+GLTRACE_CORE(void, ClearDepth, (GLclampd depth)) {
+  REAL(ClearDepth)(depth);
+  if (enabled) Rec(kOpClearDepth).d(depth);
+}
+
+GLTRACE_CORE(void, ClearStencil, (GLint s)) {
+  REAL(ClearStencil)(s);
+  if (enabled) Rec(kOpClearStencil).i(s);
+}
+
+GLTRACE_CORE(void, Viewport,
+             (GLint x, GLint y, GLsizei width, GLsizei height)) {
+  REAL(Viewport)(x, y, width, height);
+  if (enabled) Rec(kOpViewport).i(x).i(y).i(width).i(height);
+}
+
+GLTRACE_CORE(void, Scissor, (GLint x, GLint y, GLsizei width, GLsizei height)) {
+  REAL(Scissor)(x, y, width, height);
+  if (enabled) Rec(kOpScissor).i(x).i(y).i(width).i(height);
+}
+
+GLTRACE_CORE(void, PushAttrib, (GLbitfield mask)) {
+  REAL(PushAttrib)(mask);
+  if (enabled) Rec(kOpPushAttrib).u(mask);
+}
+
+GLTRACE_CORE(void, PopAttrib, ()) {
+  REAL(PopAttrib)();
+  if (enabled) Rec{kOpPopAttrib};
+}
+
+GLTRACE_CORE(void, TexParameterf,
+             (GLenum target, GLenum pname, GLfloat param)) {
+  REAL(TexParameterf)(target, pname, param);
+  if (enabled) Rec(kOpTexParameterf).u(target).u(pname).d(param);
+}
+
+GLTRACE_CORE(void, TexParameteri, (GLenum target, GLenum pname, GLint param)) {
+  REAL(TexParameteri)(target, pname, param);
+  if (enabled) Rec(kOpTexParameteri).u(target).u(pname).i(param);
+}
+
+GLTRACE_CORE(void, TexEnvf, (GLenum target, GLenum pname, GLfloat param)) {
+  REAL(TexEnvf)(target, pname, param);
+  if (enabled) Rec(kOpTexEnvf).u(target).u(pname).d(param);
+}
+
+GLTRACE_CORE(void, TexEnvi, (GLenum target, GLenum pname, GLint param)) {
+  REAL(TexEnvi)(target, pname, param);
+  if (enabled) Rec(kOpTexEnvi).u(target).u(pname).i(param);
+}
+
+GLTRACE_CORE(void, BindTexture, (GLenum target, GLuint texture)) {
+  REAL(BindTexture)(target, texture);
+  if (enabled) Rec(kOpBindTexture).u(target).u(texture);
+}
+
+GLTRACE_CORE(void, GenTextures, (GLsizei n, GLuint *textures)) {
+  REAL(GenTextures)(n, textures);
+  if (enabled) Rec(kOpGenTextures).names(n, textures);
+}
+
+GLTRACE_CORE(void, DeleteTextures, (GLsizei n, GLuint const *textures)) {
+  if (enabled) Rec(kOpDeleteTextures).names(n, textures);
+  REAL(DeleteTextures)(n, textures);
+}
+
+GLTRACE_CORE(void, TexImage2D,
+             (GLenum target, GLint level, GLint internalFormat, GLsizei width,
+              GLsizei height, GLint border, GLenum format, GLenum type,
+              GLvoid const *pixels)) {
+  REAL(TexImage2D)
+  (target, level, internalFormat, width, height, border, format, type,
+   pixels);
+  if (!enabled) return;
+
+  size_t len = 0;
+  if (pixels != nullptr &&
+      (getInt(GL_PIXEL_UNPACK_BUFFER_BINDING) != 0 ||
+       !imageSize(width, height, format, type, &len))) {
+    untraceable("glTexImage2D");
+    return;
+  }
+  Rec(kOpTexImage2D)
+      .u(target)
+      .i(level)
+      .i(internalFormat)
+      .i(width)
+      .i(height)
+      .i(border)
+      .u(format)
+      .u(type)
+      .blob(pixels, len);
+}
+
+GLTRACE_CORE(void, TexSubImage2D,
+             (GLenum target, GLint level, GLint xoffset, GLint yoffset,
+              GLsizei width, GLsizei height, GLenum format, GLenum type,
+              GLvoid const *pixels)) {
+  REAL(TexSubImage2D)
+  (target, level, xoffset, yoffset, width, height, format, type, pixels);
+  if (!enabled) return;
+
+  size_t len = 0;
+  if (pixels != nullptr &&
+      (getInt(GL_PIXEL_UNPACK_BUFFER_BINDING) != 0 ||
+       !imageSize(width, height, format, type, &len))) {
+    untraceable("glTexSubImage2D");
+    return;
+  }
+  Rec(kOpTexSubImage2D)
+      .u(target)
+      .i(level)
+      .i(xoffset)
+      .i(yoffset)
+      .i(width)
+      .i(height)
+      .u(format)
+      .u(type)
+      .blob(pixels, len);
+}
+
+GLTRACE_CORE(void, PixelStorei, (GLenum pname, GLint param)) {
+  REAL(PixelStorei)(pname, param);
+  if (enabled) Rec(kOpPixelStorei).u(pname).i(param);
+}
+
+GLTRACE_CORE(void, EnableClientState, (GLenum cap)) {
+  REAL(EnableClientState)(cap);
+  if (enabled) Rec(kOpEnableClientState).u(cap);
+}
+
+GLTRACE_CORE(void, DisableClientState, (GLenum cap)) {
+  REAL(DisableClientState)(cap);
+  if (enabled) Rec(kOpDisableClientState).u(cap);
+}
+
+GLTRACE_CORE(void, VertexPointer,
+             (GLint size, GLenum type, GLsizei stride, GLvoid const *ptr)) {
+  REAL(VertexPointer)(size, type, stride, ptr);
+  if (!enabled) return;
+  if (isClientMemory(GL_ARRAY_BUFFER_BINDING, ptr)) {
+    untraceable("glVertexPointer with client-side memory");
+    return;
+  }
+  Rec(kOpVertexPointer).i(size).u(type).i(stride).o(ptr);
+}
+
+GLTRACE_CORE(void, TexCoordPointer,
+             (GLint size, GLenum type, GLsizei stride, GLvoid const *ptr)) {
+  REAL(TexCoordPointer)(size, type, stride, ptr);
+  if (!enabled) return;
+  if (isClientMemory(GL_ARRAY_BUFFER_BINDING, ptr)) {
+    untraceable("glTexCoordPointer with client-side memory");
+    return;
+  }
+  Rec(kOpTexCoordPointer).i(size).u(type).i(stride).o(ptr);
+}
+
+GLTRACE_CORE(void, ColorPointer,
+             (GLint size, GLenum type, GLsizei stride, GLvoid const *ptr)) {
+  REAL(ColorPointer)(size, type, stride, ptr);
+  if (!enabled) return;
+  if (isClientMemory(GL_ARRAY_BUFFER_BINDING, ptr)) {
+    untraceable("glColorPointer with client-side memory");
+    return;
+  }
+  Rec(kOpColorPointer).i(size).u(type).i(stride).o(ptr);
+}
+
+GLTRACE_CORE(void, DrawArrays, (GLenum mode, GLint first, GLsizei count)) {
+  REAL(DrawArrays)(mode, first, count);
+  if (enabled) Rec(kOpDrawArrays).u(mode).i(first).i(count);
+}
+
+GLTRACE_CORE(void, DrawElements,
+             (GLenum mode, GLsizei count, GLenum type, GLvoid const *indices)) {
+  REAL(DrawElements)(mode, count, type, indices);
+  if (!enabled) return;
+
+  if (isClientMemory(GL_ELEMENT_ARRAY_BUFFER_BINDING, indices)) {
+    size_t len = count * indexSize(type);
+    if (len == 0) {
+      untraceable("glDrawElements with client-side memory");
+      return;
+    }
+    Rec(kOpDrawElements)
+        .u(mode)
+        .i(count)
+        .u(type)
+        .o(nullptr)
+        .blob(indices, len);
+    return;
+  }
+  Rec(kOpDrawElements).u(mode).i(count).u(type).o(indices).blob(nullptr, 0);
+}
+
+GLTRACE_CORE(void, PolygonMode, (GLenum face, GLenum mode)) {
+  REAL(PolygonMode)(face, mode);
+  if (enabled) Rec(kOpPolygonMode).u(face).u(mode);
+}
+
+GLTRACE_CORE(void, DepthFunc, (GLenum func)) {
+  REAL(DepthFunc)(func);
+  if (enabled) Rec(kOpDepthFunc).u(func);
+}
+
+GLTRACE_CORE(void, DepthMask, (GLboolean flag)) {
+  REAL(DepthMask)(flag);
+  if (enabled) Rec(kOpDepthMask).u(flag);
+}
+
+GLTRACE_CORE(void, ColorMask,
+             (GLboolean red, GLboolean green, GLboolean blue,
+              GLboolean alpha)) {
+  REAL(ColorMask)(red, green, blue, alpha);
+  if (enabled) Rec(kOpColorMask).u(red).u(green).u(blue).u(alpha);
+}
+
+GLTRACE_CORE(void, ClipPlane, (GLenum plane, GLdouble const *equation)) {
+  REAL(ClipPlane)(plane, equation);
+  if (enabled) Rec(kOpClipPlane).u(plane).blob(equation, 4 * sizeof(GLdouble));
+}
+
+GLTRACE_CORE(void, LineWidth, (GLfloat width)) {
+  REAL(LineWidth)(width);
+  if (enabled) Rec(kOpLineWidth).d(width);
+}
+
+GLTRACE_CORE(void, PointSize, (GLfloat size)) {
+  REAL(PointSize)(size);
+  if (enabled) Rec(kOpPointSize).d(size);
+}
+
+GLTRACE_CORE(void, ShadeModel, (GLenum mode)) {
+  REAL(ShadeModel)(mode);
+  if (enabled) Rec(kOpShadeModel).u(mode);
+}
+
+GLTRACE_CORE(void, AlphaFunc, (GLenum func, GLclampf ref)) {
+  REAL(AlphaFunc)(func, ref);
+  if (enabled) Rec(kOpAlphaFunc).u(func).d(ref);
+}
+
+GLTRACE_CORE(void, Finish, ()) {
+  REAL(Finish)();
+  if (enabled) Rec{kOpFinish};
+}
+
+GLTRACE_CORE(void, Flush, ()) {
+  REAL(Flush)();
+  if (enabled) Rec{kOpFlush};
+}
+
+GLTRACE_EXT(void, ActiveTexture, PFNGLACTIVETEXTUREPROC, (GLenum texture)) {
+  REAL_EXT(ActiveTexture)(texture);
+  if (enabled) Rec(kOpActiveTexture).u(texture);
+}
+
+GLTRACE_EXT(void, BlendFuncSeparate, PFNGLBLENDFUNCSEPARATEPROC,
+            (GLenum sfactorRGB, GLenum dfactorRGB, GLenum sfactorAlpha,
+             GLenum dfactorAlpha)) {
+  REAL_EXT(BlendFuncSeparate)(sfactorRGB, dfactorRGB, sfactorAlpha,
+                              dfactorAlpha);
+  if (enabled)
+    Rec(kOpBlendFuncSeparate)
+        .u(sfactorRGB)
+        .u(dfactorRGB)
+        .u(sfactorAlpha)
+        .u(dfactorAlpha);
+}
+
+GLTRACE_EXT(void, BlendEquation, PFNGLBLENDEQUATIONPROC, (GLenum mode)) {
+  REAL_EXT(BlendEquation)(mode);
+  if (enabled) Rec(kOpBlendEquation).u(mode);
+}
+
+GLTRACE_EXT(void, BindBuffer, PFNGLBINDBUFFERPROC,
+            (GLenum target, GLuint buffer)) {
+  REAL_EXT(BindBuffer)(target, buffer);
+  if (enabled) Rec(kOpBindBuffer).u(target).u(buffer);
+}
+
+GLTRACE_EXT(void, GenBuffers, PFNGLGENBUFFERSPROC,
+            (GLsizei n, GLuint *buffers)) {
+  REAL_EXT(GenBuffers)(n, buffers);
+  if (enabled) Rec(kOpGenBuffers).names(n, buffers);
+}
+
+GLTRACE_EXT(void, DeleteBuffers, PFNGLDELETEBUFFERSPROC,
+            (GLsizei n, GLuint const *buffers)) {
+  if (enabled) Rec(kOpDeleteBuffers).names(n, buffers);
+  REAL_EXT(DeleteBuffers)(n, buffers);
+}
+
+GLTRACE_EXT(void, BufferData, PFNGLBUFFERDATAPROC,
+            (GLenum target, GLsizeiptr size, void const *data,
+             GLenum usage)) {
+  REAL_EXT(BufferData)(target, size, data, usage);
+  if (enabled)
+    Rec(kOpBufferData)
+        .u(target)
+        .i(size)
+        .blob(data, data == nullptr ? 0 : size)
+        .u(usage);
+}
+
+GLTRACE_EXT(void, BufferSubData, PFNGLBUFFERSUBDATAPROC,
+            (GLenum target, GLintptr offset, GLsizeiptr size,
+             void const *data)) {
+  REAL_EXT(BufferSubData)(target, offset, size, data);
+  if (enabled) Rec(kOpBufferSubData).u(target).i(offset).blob(data, size);
+}
+
+GLTRACE_EXT(void *, MapBuffer, PFNGLMAPBUFFERPROC,
+            (GLenum target, GLenum access)) {
+  void *ret = REAL_EXT(MapBuffer)(target, access);
+  if (enabled && ret != nullptr) {
+    GLint size = 0;
+    glGetBufferParameteriv(target, GL_BUFFER_SIZE, &size);
+    mappings[target] = Mapping{ret, access, size};
+  }
+  return ret;
+}
+
+GLTRACE_EXT(GLboolean, UnmapBuffer, PFNGLUNMAPBUFFERPROC, (GLenum target)) {
+  auto itr = mappings.find(target);
+  if (itr != mappings.end()) {
+    // Writes through the mapping are invisible to us; snapshot the contents
+    // instead.
+    Mapping const &mapping = itr->second;
+    if (enabled && mapping.access != GL_READ_ONLY) {
+      Rec(kOpBufferSubData)
+          .u(target)
+          .i(0)
+          .blob(mapping.ptr, mapping.size);
+    }
+    mappings.erase(itr);
+  }
+  return REAL_EXT(UnmapBuffer)(target);
+}
+
+GLTRACE_EXT(GLuint, CreateShader, PFNGLCREATESHADERPROC, (GLenum type)) {
+  GLuint ret = REAL_EXT(CreateShader)(type);
+  if (enabled) Rec(kOpCreateShader).u(type).u(ret);
+  return ret;
+}
+
+GLTRACE_EXT(void, ShaderSource, PFNGLSHADERSOURCEPROC,
+            (GLuint shader, GLsizei count, GLchar const *const *string,
+             GLint const *length)) {
+  REAL_EXT(ShaderSource)(shader, count, string, length);
+  if (!enabled) return;
+
+  std::string source;
+  for (GLsizei i = 0; i < count; i++) {
+    if (length == nullptr || length[i] < 0) {
+      source.append(string[i]);
+    } else {
+      source.append(string[i], length[i]);
+    }
+  }
+  Rec(kOpShaderSource).u(shader).blob(source.data(), source.size());
+}
+
+GLTRACE_EXT(void, CompileShader, PFNGLCOMPILESHADERPROC, (GLuint shader)) {
+  REAL_EXT(CompileShader)(shader);
+  if (enabled) Rec(kOpCompileShader).u(shader);
+}
+
+GLTRACE_EXT(void, DeleteShader, PFNGLDELETESHADERPROC, (GLuint shader)) {
+  if (enabled) Rec(kOpDeleteShader).u(shader);
+  REAL_EXT(DeleteShader)(shader);
+}
+
+GLTRACE_EXT(GLuint, CreateProgram, PFNGLCREATEPROGRAMPROC, ()) {
+  GLuint ret = REAL_EXT(CreateProgram)();
+  if (enabled) Rec(kOpCreateProgram).u(ret);
+  return ret;
+}
+
+GLTRACE_EXT(void, AttachShader, PFNGLATTACHSHADERPROC,
+            (GLuint program, GLuint shader)) {
+  REAL_EXT(AttachShader)(program, shader);
+  if (enabled) Rec(kOpAttachShader).u(program).u(shader);
+}
+
+GLTRACE_EXT(void, DetachShader, PFNGLDETACHSHADERPROC,
+            (GLuint program, GLuint shader)) {
+  REAL_EXT(DetachShader)(program, shader);
+  if (enabled) Rec(kOpDetachShader).u(program).u(shader);
+}
+
+GLTRACE_EXT(void, LinkProgram, PFNGLLINKPROGRAMPROC, (GLuint program)) {
+  REAL_EXT(LinkProgram)(program);
+  if (enabled) Rec(kOpLinkProgram).u(program);
+}
+
+GLTRACE_EXT(void, UseProgram, PFNGLUSEPROGRAMPROC, (GLuint program)) {
+  REAL_EXT(UseProgram)(program);
+  if (enabled) Rec(kOpUseProgram).u(program);
+}
+
+GLTRACE_EXT(void, DeleteProgram, PFNGLDELETEPROGRAMPROC, (GLuint program)) {
+  if (enabled) Rec(kOpDeleteProgram).u(program);
+  REAL_EXT(DeleteProgram)(program);
+}
+
+GLTRACE_EXT(void, BindAttribLocation, PFNGLBINDATTRIBLOCATIONPROC,
+            (GLuint program, GLuint index, GLchar const *name)) {
+  REAL_EXT(BindAttribLocation)(program, index, name);
+  if (enabled) Rec(kOpBindAttribLocation).u(program).u(index).str(name);
+}
+
+GLTRACE_EXT(GLint, GetUniformLocation, PFNGLGETUNIFORMLOCATIONPROC,
+            (GLuint program, GLchar const *name)) {
+  GLint ret = REAL_EXT(GetUniformLocation)(program, name);
+  if (enabled) Rec(kOpGetUniformLocation).u(program).str(name).i(ret);
+  return ret;
+}
+
+GLTRACE_EXT(void, Uniform1i, PFNGLUNIFORM1IPROC, (GLint location, GLint v0)) {
+  REAL_EXT(Uniform1i)(location, v0);
+  if (enabled) Rec(kOpUniform1i).i(location).i(v0);
+}
+
+GLTRACE_EXT(void, Uniform1f, PFNGLUNIFORM1FPROC,
+            (GLint location, GLfloat v0)) {
+  REAL_EXT(Uniform1f)(location, v0);
+  if (enabled) Rec(kOpUniform1f).i(location).d(v0);
+}
+
+GLTRACE_EXT(void, Uniform2f, PFNGLUNIFORM2FPROC,
+            (GLint location, GLfloat v0, GLfloat v1)) {
+  REAL_EXT(Uniform2f)(location, v0, v1);
+  if (enabled) Rec(kOpUniform2f).i(location).d(v0).d(v1);
+}
+
+GLTRACE_EXT(void, Uniform3f, PFNGLUNIFORM3FPROC,
+            (GLint location, GLfloat v0, GLfloat v1, GLfloat v2)) {
+  REAL_EXT(Uniform3f)(location, v0, v1, v2);
+  if (enabled) Rec(kOpUniform3f).i(location).d(v0).d(v1).d(v2);
+}
+
+GLTRACE_EXT(void, Uniform4f, PFNGLUNIFORM4FPROC,
+            (GLint location, GLfloat v0, GLfloat v1, GLfloat v2,
+             GLfloat v3)) {
+  REAL_EXT(Uniform4f)(location, v0, v1, v2, v3);
+  if (enabled) Rec(kOpUniform4f).i(location).d(v0).d(v1).d(v2).d(v3);
+}
+
+#define GLTRACE_UNIFORM_V(n, PFN, T, width)                             \
+  GLTRACE_EXT(void, n, PFN,                                             \
+              (GLint location, GLsizei count, T const *value)) {        \
+    REAL_EXT(n)(location, count, value);                                \
+    if (enabled)                                                        \
+      Rec(kOp##n).i(location).i(count).blob(value,                      \
+                                            count * (width) * sizeof(T)); \
+  }
+
+GLTRACE_UNIFORM_V(Uniform1iv, PFNGLUNIFORM1IVPROC, GLint, 1)
+GLTRACE_UNIFORM_V(Uniform1fv, PFNGLUNIFORM1FVPROC, GLfloat, 1)
+GLTRACE_UNIFORM_V(Uniform2fv, PFNGLUNIFORM2FVPROC, GLfloat, 2)
+GLTRACE_UNIFORM_V(Uniform3fv, PFNGLUNIFORM3FVPROC, GLfloat, 3)
+GLTRACE_UNIFORM_V(Uniform4fv, PFNGLUNIFORM4FVPROC, GLfloat, 4)
+
+#define GLTRACE_UNIFORM_MATRIX(n, PFN, width)                               \
+  GLTRACE_EXT(void, n, PFN,                                                 \
+              (GLint location, GLsizei count, GLboolean transpose,          \
+               GLfloat const *value)) {                                     \
+    REAL_EXT(n)(location, count, transpose, value);                         \
+    if (enabled)                                                            \
+      Rec(kOp##n).i(location).i(count).u(transpose).blob(                   \
+          value, count * (width) * sizeof(GLfloat));                        \
+  }
+
+GLTRACE_UNIFORM_MATRIX(UniformMatrix3fv, PFNGLUNIFORMMATRIX3FVPROC, 9)
+GLTRACE_UNIFORM_MATRIX(UniformMatrix4fv, PFNGLUNIFORMMATRIX4FVPROC, 16)
+
+GLTRACE_EXT(void, VertexAttribPointer, PFNGLVERTEXATTRIBPOINTERPROC,
+            (GLuint index, GLint size, GLenum type, GLboolean normalized,
+             GLsizei stride, void const *pointer)) {
+  REAL_EXT(VertexAttribPointer)(index, size, type, normalized, stride, pointer);
+  if (!enabled) return;
+  if (isClientMemory(GL_ARRAY_BUFFER_BINDING, pointer)) {
+    untraceable("glVertexAttribPointer with client-side memory");
+    return;
+  }
+  Rec(kOpVertexAttribPointer)
+      .u(index)
+      .i(size)
+      .u(type)
+      .u(normalized)
+      .i(stride)
+      .o(pointer);
+}
+
+GLTRACE_EXT(void, EnableVertexAttribArray, PFNGLENABLEVERTEXATTRIBARRAYPROC,
+            (GLuint index)) {
+  REAL_EXT(EnableVertexAttribArray)(index);
+  if (enabled) Rec(kOpEnableVertexAttribArray).u(index);
+}
+
+GLTRACE_EXT(void, DisableVertexAttribArray, PFNGLDISABLEVERTEXATTRIBARRAYPROC,
+            (GLuint index)) {
+  REAL_EXT(DisableVertexAttribArray)(index);
+  if (enabled) Rec(kOpDisableVertexAttribArray).u(index);
+}
+
+GLTRACE_EXT(void, GenVertexArrays, PFNGLGENVERTEXARRAYSPROC,
+            (GLsizei n, GLuint *arrays)) {
+  REAL_EXT(GenVertexArrays)(n, arrays);
+  if (enabled) Rec(kOpGenVertexArrays).names(n, arrays);
+}
+
+GLTRACE_EXT(void, DeleteVertexArrays, PFNGLDELETEVERTEXARRAYSPROC,
+            (GLsizei n, GLuint const *arrays)) {
+  if (enabled) Rec(kOpDeleteVertexArrays).names(n, arrays);
+  REAL_EXT(DeleteVertexArrays)(n, arrays);
+}
+
+GLTRACE_EXT(void, BindVertexArray, PFNGLBINDVERTEXARRAYPROC, (GLuint array)) {
+  REAL_EXT(BindVertexArray)(array);
+  if (enabled) Rec(kOpBindVertexArray).u(array);
+}
+
+GLTRACE_EXT(void, GenFramebuffers, PFNGLGENFRAMEBUFFERSPROC,
+            (GLsizei n, GLuint *framebuffers)) {
+  REAL_EXT(GenFramebuffers)(n, framebuffers);
+  if (enabled) Rec(kOpGenFramebuffers).names(n, framebuffers);
+}
+
+GLTRACE_EXT(void, DeleteFramebuffers, PFNGLDELETEFRAMEBUFFERSPROC,
+            (GLsizei n, GLuint const *framebuffers)) {
+  if (enabled) Rec(kOpDeleteFramebuffers).names(n, framebuffers);
+  REAL_EXT(DeleteFramebuffers)(n, framebuffers);
+}
+
+GLTRACE_EXT(void, BindFramebuffer, PFNGLBINDFRAMEBUFFERPROC,
+            (GLenum target, GLuint framebuffer)) {
+  REAL_EXT(BindFramebuffer)(target, framebuffer);
+  if (enabled) Rec(kOpBindFramebuffer).u(target).u(framebuffer);
+}
+
+GLTRACE_EXT(void, FramebufferTexture2D, PFNGLFRAMEBUFFERTEXTURE2DPROC,
+            (GLenum target, GLenum attachment, GLenum textarget,
+             GLuint texture, GLint level)) {
+  REAL_EXT(FramebufferTexture2D)(target, attachment, textarget, texture, level);
+  if (enabled)
+    Rec(kOpFramebufferTexture2D)
+        .u(target)
+        .u(attachment)
+        .u(textarget)
+        .u(texture)
+        .i(level);
+}
+
+GLTRACE_EXT(void, GenerateMipmap, PFNGLGENERATEMIPMAPPROC, (GLenum target)) {
+  REAL_EXT(GenerateMipmap)(target);
+  if (enabled) Rec(kOpGenerateMipmap).u(target);
+}
+
+GLTRACE_EXT(void, BlitFramebuffer, PFNGLBLITFRAMEBUFFERPROC,
+            (GLint srcX0, GLint srcY0, GLint srcX1, GLint srcY1, GLint dstX0,
+             GLint dstY0, GLint dstX1, GLint dstY1, GLbitfield mask,
+             GLenum filter)) {
+  REAL_EXT(BlitFramebuffer)
+  (srcX0, srcY0, srcX1, srcY1, dstX0, dstY0, dstX1, dstY1, mask, filter);
+  if (enabled)
+    Rec(kOpBlitFramebuffer)
+        .i(srcX0)
+        .i(srcY0)
+        .i(srcX1)
+        .i(srcY1)
+        .i(dstX0)
+        .i(dstY0)
+        .i(dstX1)
+        .i(dstY1)
+        .u(mask)
+        .u(filter);
+}
+
+// Every GLTRACE_EXT entry point.
+#define GLTRACE_EXT_NAMES(X)                                                \
+  X(ActiveTexture)                                                          \
+  X(BlendFuncSeparate)                                                      \
+  X(BlendEquation)                                                          \
+  X(BindBuffer)                                                             \
+  X(GenBuffers)                                                             \
+  X(DeleteBuffers)                                                          \
+  X(BufferData)                                                             \
+  X(BufferSubData)                                                          \
+  X(MapBuffer)                                                              \
+  X(UnmapBuffer)                                                            \
+  X(CreateShader)                                                           \
+  X(ShaderSource)                                                           \
+  X(CompileShader)                                                          \
+  X(DeleteShader)                                                           \
+  X(CreateProgram)                                                          \
+  X(AttachShader)                                                           \
+  X(DetachShader)                                                           \
+  X(LinkProgram)                                                            \
+  X(UseProgram)                                                             \
+  X(DeleteProgram)                                                          \
+  X(BindAttribLocation)                                                     \
+  X(GetUniformLocation)                                                     \
+  X(Uniform1i)                                                              \
+  X(Uniform1f)                                                              \
+  X(Uniform2f)                                                              \
+  X(Uniform3f)                                                              \
+  X(Uniform4f)                                                              \
+  X(Uniform1iv)                                                             \
+  X(Uniform1fv)                                                             \
+  X(Uniform2fv)                                                             \
+  X(Uniform3fv)                                                             \
+  X(Uniform4fv)                                                             \
+  X(UniformMatrix3fv)                                                       \
+  X(UniformMatrix4fv)                                                       \
+  X(VertexAttribPointer)                                                    \
+  X(EnableVertexAttribArray)                                                \
+  X(DisableVertexAttribArray)                                               \
+  X(GenVertexArrays)                                                        \
+  X(DeleteVertexArrays)                                                     \
+  X(BindVertexArray)                                                        \
+  X(GenFramebuffers)                                                        \
+  X(DeleteFramebuffers)                                                     \
+  X(BindFramebuffer)                                                        \
+  X(FramebufferTexture2D)                                                   \
+  X(GenerateMipmap)                                                         \
+  X(BlitFramebuffer)
+
+extern "C" {
+
+void GlopTraceInstall() {
+#if GLTRACE_GLEW_POINTERS
+  // glewInit might have been called again since we last installed so check
+  // each pointer rather than remembering that we've been here before.
+#define GLTRACE_INSTALL(n)                                       \
+  if (__glew##n != nullptr && __glew##n != traced_gl##n) {       \
+    real_gl##n = __glew##n;                                      \
+    __glew##n = traced_gl##n;                                    \
+  }
+  GLTRACE_EXT_NAMES(GLTRACE_INSTALL)
+#undef GLTRACE_INSTALL
+#endif
+}
+
+void GlopTraceSetEnabled(int enable) {
+  enabled = enable != 0;
+  if (!enabled) {
+    mappings.clear();
+  }
+}
+
+void GlopTraceTakeCalls(uint8_t **data, size_t *len) {
+  *len = recorded.size();
+  *data = static_cast<uint8_t *>(malloc(recorded.size()));
+  if (!recorded.empty()) {
+    std::memcpy(*data, recorded.data(), recorded.size());
+  }
+  recorded.clear();
+}
+
+}  // extern "C"
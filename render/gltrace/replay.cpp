@@ -0,0 +1,605 @@
+#include <GL/glew.h>
+
+#include <cinttypes>
+#include <cstddef>
+#include <cstdint>
+#include <cstdio>
+#include <cstdlib>
+#include <cstring>
+#include <map>
+#include <sstream>
+#include <string>
+#include <utility>
+#include <vector>
+
+#include "include/gltrace.h"
+#include "ops.hpp"
+
+GlTraceOpInfo const kGlTraceOps[kNumOps] = {
+#define GLTRACE_INFO(name, sig) {"gl" #name, sig},
+    GLTRACE_OPS(GLTRACE_INFO)
+#undef GLTRACE_INFO
+};
+
+static bool isBlob(char c) {
+  switch (c) {
+    case 'B':
+    case 'T':
+    case 'R':
+    case 'V':
+    case 'N':
+      return true;
+  }
+  return false;
+}
+
+bool decodeCall(uint8_t const *data, size_t len, size_t *offset,
+                GlTraceCall *out) {
+  auto take = [&](void *dst, size_t n) {
+    if (*offset + n > len) {
+      return false;
+    }
+    std::memcpy(dst, data + *offset, n);
+    *offset += n;
+    return true;
+  };
+
+  uint16_t op;
+  if (!take(&op, sizeof(op)) || op >= kNumOps) {
+    return false;
+  }
+  out->op = static_cast<GlTraceOp>(op);
+  out->args.clear();
+
+  for (char const *sig = kGlTraceOps[op].signature; *sig != '\0'; sig++) {
+    GlTraceArg arg;
+    if (isBlob(*sig)) {
+      uint32_t n;
+      if (!take(&n, sizeof(n)) || *offset + n > len) {
+        return false;
+      }
+      arg.blob.assign(reinterpret_cast<char const *>(data + *offset), n);
+      *offset += n;
+      if (*sig != 'B') {
+        arg.names.resize(n / sizeof(uint32_t));
+        std::memcpy(arg.names.data(), arg.blob.data(),
+                    arg.names.size() * sizeof(uint32_t));
+      }
+    } else if (!take(&arg.bits, sizeof(arg.bits))) {
+      return false;
+    }
+    out->args.push_back(std::move(arg));
+  }
+
+  return true;
+}
+
+// Ops whose blob argument is a name rather than data.
+static bool blobIsText(GlTraceOp op) {
+  return op == kOpGetUniformLocation || op == kOpBindAttribLocation;
+}
+
+std::string describeCall(GlTraceCall const &call) {
+  if (call.op == kOpUntraceable) {
+    return "untraceable: " + call.args[0].blob;
+  }
+
+  std::stringstream result;
+  result << kGlTraceOps[call.op].name << "(";
+  char const *sig = kGlTraceOps[call.op].signature;
+  for (size_t i = 0; i < call.args.size(); i++) {
+    if (i > 0) {
+      result << ", ";
+    }
+
+    GlTraceArg const &arg = call.args[i];
+    char buf[32];
+    switch (sig[i]) {
+      case 'e':
+        snprintf(buf, sizeof(buf), "0x%04" PRIX32, arg.asUint());
+        result << buf;
+        break;
+      case 'i':
+      case 'l':
+        result << arg.asInt();
+        break;
+      case 'f':
+      case 'd':
+        result << arg.asDouble();
+        break;
+      case 'B':
+        if (blobIsText(call.op)) {
+          result << '"' << arg.blob << '"';
+        } else {
+          result << "<" << arg.blob.size() << " bytes>";
+        }
+        break;
+      case 'T':
+      case 'R':
+      case 'V':
+      case 'N':
+        result << "[";
+        for (size_t j = 0; j < arg.names.size(); j++) {
+          result << (j > 0 ? ", " : "") << arg.names[j];
+        }
+        result << "]";
+        break;
+      default:
+        result << arg.bits;
+        break;
+    }
+  }
+  result << ")";
+  return result.str();
+}
+
+// Object names in a trace are whatever the traced context handed out; the
+// replaying context might hand out different ones.
+struct GlopTraceReplayer {
+  std::map<std::pair<char, uint32_t>, uint32_t> names;
+  std::map<std::pair<GLuint, GLint>, GLint> locations;
+
+  GLuint lookup(char kind, uint32_t recorded) const {
+    auto itr = names.find({kind, recorded});
+    return itr == names.end() ? recorded : itr->second;
+  }
+
+  GLint lookupLocation(GLint recorded) const {
+    GLint program = 0;
+    glGetIntegerv(GL_CURRENT_PROGRAM, &program);
+    auto itr = locations.find({program, recorded});
+    return itr == locations.end() ? recorded : itr->second;
+  }
+
+  void remember(char kind, std::vector<uint32_t> const &recorded,
+                std::vector<GLuint> const &actual) {
+    for (size_t i = 0; i < recorded.size(); i++) {
+      names[{kind, recorded[i]}] = actual[i];
+    }
+  }
+
+  std::vector<GLuint> lookupAll(char kind,
+                                std::vector<uint32_t> const &recorded) {
+    std::vector<GLuint> ret;
+    for (uint32_t name : recorded) {
+      ret.push_back(lookup(kind, name));
+      names.erase({kind, name});
+    }
+    return ret;
+  }
+
+  // Returns false if the call can't be replayed.
+  bool replay(GlTraceCall const &call, std::string *err);
+};
+
+bool GlopTraceReplayer::replay(GlTraceCall const &call, std::string *err) {
+  auto const &a = call.args;
+  auto e = [&](int i) { return static_cast<GLenum>(a[i].asUint()); };
+  auto i = [&](int n) { return static_cast<GLint>(a[n].asInt()); };
+  auto u = [&](int n) { return a[n].asUint(); };
+  auto f = [&](int n) { return static_cast<GLfloat>(a[n].asDouble()); };
+  auto d = [&](int n) { return a[n].asDouble(); };
+  auto ptr = [&](int n) {
+    return reinterpret_cast<void const *>(static_cast<uintptr_t>(a[n].bits));
+  };
+  auto data = [&](int n) -> void const * {
+    return a[n].blob.empty() ? nullptr : a[n].blob.data();
+  };
+  auto floats = [&](int n) {
+    return reinterpret_cast<GLfloat const *>(a[n].blob.data());
+  };
+  auto tex = [&](int n) { return lookup('t', u(n)); };
+  auto buf = [&](int n) { return lookup('r', u(n)); };
+  auto shader = [&](int n) { return lookup('s', u(n)); };
+  auto prog = [&](int n) { return lookup('p', u(n)); };
+  auto loc = [&](int n) { return lookupLocation(i(n)); };
+  auto gen = [&](char kind, int n, void (*genFn)(GLsizei, GLuint *)) {
+    std::vector<GLuint> actual(a[n].names.size());
+    genFn(actual.size(), actual.data());
+    remember(kind, a[n].names, actual);
+  };
+  auto del = [&](char kind, int n, void (*delFn)(GLsizei, GLuint const *)) {
+    std::vector<GLuint> actual = lookupAll(kind, a[n].names);
+    delFn(actual.size(), actual.data());
+  };
+
+  switch (call.op) {
+    case kOpUntraceable:
+      *err = "the trace is incomplete here: " + a[0].blob;
+      return false;
+    case kOpEnable:
+      glEnable(e(0));
+      break;
+    case kOpDisable:
+      glDisable(e(0));
+      break;
+    case kOpBlendFunc:
+      glBlendFunc(e(0), e(1));
+      break;
+    case kOpMatrixMode:
+      glMatrixMode(e(0));
+      break;
+    case kOpLoadIdentity:
+      glLoadIdentity();
+      break;
+    case kOpPushMatrix:
+      glPushMatrix();
+      break;
+    case kOpPopMatrix:
+      glPopMatrix();
+      break;
+    case kOpOrtho:
+      glOrtho(d(0), d(1), d(2), d(3), d(4), d(5));
+      break;
+    case kOpLoadMatrixf:
+      glLoadMatrixf(floats(0));
+      break;
+    case kOpMultMatrixf:
+      glMultMatrixf(floats(0));
+      break;
+    case kOpTranslated:
+      glTranslated(d(0), d(1), d(2));
+      break;
+    case kOpScaled:
+      glScaled(d(0), d(1), d(2));
+      break;
+    case kOpRotated:
+      glRotated(d(0), d(1), d(2), d(3));
+      break;
+    case kOpBegin:
+      glBegin(e(0));
+      break;
+    case kOpEnd:
+      glEnd();
+      break;
+    case kOpVertex2i:
+      glVertex2i(i(0), i(1));
+      break;
+    case kOpVertex2f:
+      glVertex2f(f(0), f(1));
+      break;
+    case kOpVertex2d:
+      glVertex2d(d(0), d(1));
+      break;
+    case kOpVertex3f:
+      glVertex3f(f(0), f(1), f(2));
+      break;
+    case kOpVertex3d:
+      glVertex3d(d(0), d(1), d(2));
+      break;
+    case kOpColor3f:
+      glColor3f(f(0), f(1), f(2));
+      break;
+    case kOpColor3d:
+      glColor3d(d(0), d(1), d(2));
+      break;
+    case kOpColor4f:
+      glColor4f(f(0), f(1), f(2), f(3));
+      break;
+    case kOpColor4d:
+      glColor4d(d(0), d(1), d(2), d(3));
+      break;
+    case kOpColor4ub:
+      glColor4ub(u(0), u(1), u(2), u(3));
+      break;
+    case kOpTexCoord2f:
+      glTexCoord2f(f(0), f(1));
+      break;
+    case kOpTexCoord2d:
+      glTexCoord2d(d(0), d(1));
+      break;
+    case kOpClear:
+      glClear(u(0));
+      break;
+    case kOpClearColor:
+      glClearColor(f(0), f(1), f(2), f(3));
+      break;
+    case kOpClearDepth:
+      glClearDepth(d(0));
+      break;
+    case kOpClearStencil:
+      glClearStencil(i(0));
+      break;
+    case kOpViewport:
+      glViewport(i(0), i(1), i(2), i(3));
+      break;
+    case kOpScissor:
+      glScissor(i(0), i(1), i(2), i(3));
+      break;
+    case kOpPushAttrib:
+      glPushAttrib(u(0));
+      break;
+    case kOpPopAttrib:
+      glPopAttrib();
+      break;
+    case kOpTexParameterf:
+      glTexParameterf(e(0), e(1), f(2));
+      break;
+    case kOpTexParameteri:
+      glTexParameteri(e(0), e(1), i(2));
+      break;
+    case kOpTexEnvf:
+      glTexEnvf(e(0), e(1), f(2));
+      break;
+    case kOpTexEnvi:
+      glTexEnvi(e(0), e(1), i(2));
+      break;
+    case kOpBindTexture:
+      glBindTexture(e(0), tex(1));
+      break;
+    case kOpGenTextures:
+      gen('t', 0, [](GLsizei n, GLuint *out) { glGenTextures(n, out); });
+      break;
+    case kOpDeleteTextures:
+      del('t', 0,
+          [](GLsizei n, GLuint const *in) { glDeleteTextures(n, in); });
+      break;
+    case kOpTexImage2D:
+      glTexImage2D(e(0), i(1), i(2), i(3), i(4), i(5), e(6), e(7), data(8));
+      break;
+    case kOpTexSubImage2D:
+      glTexSubImage2D(e(0), i(1), i(2), i(3), i(4), i(5), e(6), e(7),
+                      data(8));
+      break;
+    case kOpPixelStorei:
+      glPixelStorei(e(0), i(1));
+      break;
+    case kOpEnableClientState:
+      glEnableClientState(e(0));
+      break;
+    case kOpDisableClientState:
+      glDisableClientState(e(0));
+      break;
+    case kOpVertexPointer:
+      glVertexPointer(i(0), e(1), i(2), ptr(3));
+      break;
+    case kOpTexCoordPointer:
+      glTexCoordPointer(i(0), e(1), i(2), ptr(3));
+      break;
+    case kOpColorPointer:
+      glColorPointer(i(0), e(1), i(2), ptr(3));
+      break;
+    case kOpDrawArrays:
+      glDrawArrays(e(0), i(1), i(2));
+      break;
+    case kOpDrawElements:
+      glDrawElements(e(0), i(1), e(2),
+                     a[4].blob.empty() ? ptr(3) : a[4].blob.data());
+      break;
+    case kOpPolygonMode:
+      glPolygonMode(e(0), e(1));
+      break;
+    case kOpDepthFunc:
+      glDepthFunc(e(0));
+      break;
+    case kOpDepthMask:
+      glDepthMask(u(0));
+      break;
+    case kOpColorMask:
+      glColorMask(u(0), u(1), u(2), u(3));
+      break;
+    case kOpClipPlane:
+      glClipPlane(e(0), reinterpret_cast<GLdouble const *>(a[1].blob.data()));
+      break;
+    case kOpLineWidth:
+      glLineWidth(f(0));
+      break;
+    case kOpPointSize:
+      glPointSize(f(0));
+      break;
+    case kOpShadeModel:
+      glShadeModel(e(0));
+      break;
+    case kOpAlphaFunc:
+      glAlphaFunc(e(0), f(1));
+      break;
+    case kOpFinish:
+      glFinish();
+      break;
+    case kOpFlush:
+      glFlush();
+      break;
+    case kOpActiveTexture:
+      glActiveTexture(e(0));
+      break;
+    case kOpBlendFuncSeparate:
+      glBlendFuncSeparate(e(0), e(1), e(2), e(3));
+      break;
+    case kOpBlendEquation:
+      glBlendEquation(e(0));
+      break;
+    case kOpBindBuffer:
+      glBindBuffer(e(0), buf(1));
+      break;
+    case kOpGenBuffers:
+      gen('r', 0, [](GLsizei n, GLuint *out) { glGenBuffers(n, out); });
+      break;
+    case kOpDeleteBuffers:
+      del('r', 0, [](GLsizei n, GLuint const *in) { glDeleteBuffers(n, in); });
+      break;
+    case kOpBufferData:
+      glBufferData(e(0), a[1].asInt(), data(2), e(3));
+      break;
+    case kOpBufferSubData:
+      glBufferSubData(e(0), a[1].asInt(), a[2].blob.size(), data(2));
+      break;
+    case kOpCreateShader:
+      names[{'s', u(1)}] = glCreateShader(e(0));
+      break;
+    case kOpShaderSource: {
+      GLchar const *source = a[1].blob.data();
+      GLint length = a[1].blob.size();
+      glShaderSource(shader(0), 1, &source, &length);
+      break;
+    }
+    case kOpCompileShader:
+      glCompileShader(shader(0));
+      break;
+    case kOpDeleteShader:
+      glDeleteShader(shader(0));
+      names.erase({'s', u(0)});
+      break;
+    case kOpCreateProgram:
+      names[{'p', u(0)}] = glCreateProgram();
+      break;
+    case kOpAttachShader:
+      glAttachShader(prog(0), shader(1));
+      break;
+    case kOpDetachShader:
+      glDetachShader(prog(0), shader(1));
+      break;
+    case kOpLinkProgram:
+      glLinkProgram(prog(0));
+      break;
+    case kOpUseProgram:
+      glUseProgram(prog(0));
+      break;
+    case kOpDeleteProgram:
+      glDeleteProgram(prog(0));
+      names.erase({'p', u(0)});
+      break;
+    case kOpBindAttribLocation:
+      glBindAttribLocation(prog(0), u(1), a[2].blob.c_str());
+      break;
+    case kOpGetUniformLocation:
+      locations[{prog(0), i(2)}] =
+          glGetUniformLocation(prog(0), a[1].blob.c_str());
+      break;
+    case kOpUniform1i:
+      glUniform1i(loc(0), i(1));
+      break;
+    case kOpUniform1f:
+      glUniform1f(loc(0), f(1));
+      break;
+    case kOpUniform2f:
+      glUniform2f(loc(0), f(1), f(2));
+      break;
+    case kOpUniform3f:
+      glUniform3f(loc(0), f(1), f(2), f(3));
+      break;
+    case kOpUniform4f:
+      glUniform4f(loc(0), f(1), f(2), f(3), f(4));
+      break;
+    case kOpUniform1iv:
+      glUniform1iv(loc(0), i(1),
+                   reinterpret_cast<GLint const *>(a[2].blob.data()));
+      break;
+    case kOpUniform1fv:
+      glUniform1fv(loc(0), i(1), floats(2));
+      break;
+    case kOpUniform2fv:
+      glUniform2fv(loc(0), i(1), floats(2));
+      break;
+    case kOpUniform3fv:
+      glUniform3fv(loc(0), i(1), floats(2));
+      break;
+    case kOpUniform4fv:
+      glUniform4fv(loc(0), i(1), floats(2));
+      break;
+    case kOpUniformMatrix3fv:
+      glUniformMatrix3fv(loc(0), i(1), u(2), floats(3));
+      break;
+    case kOpUniformMatrix4fv:
+      glUniformMatrix4fv(loc(0), i(1), u(2), floats(3));
+      break;
+    case kOpVertexAttribPointer:
+      glVertexAttribPointer(u(0), i(1), e(2), u(3), i(4), ptr(5));
+      break;
+    case kOpEnableVertexAttribArray:
+      glEnableVertexAttribArray(u(0));
+      break;
+    case kOpDisableVertexAttribArray:
+      glDisableVertexAttribArray(u(0));
+      break;
+    case kOpGenVertexArrays:
+      gen('v', 0, [](GLsizei n, GLuint *out) { glGenVertexArrays(n, out); });
+      break;
+    case kOpDeleteVertexArrays:
+      del('v', 0,
+          [](GLsizei n, GLuint const *in) { glDeleteVertexArrays(n, in); });
+      break;
+    case kOpBindVertexArray:
+      glBindVertexArray(lookup('v', u(0)));
+      break;
+    case kOpGenFramebuffers:
+      gen('F', 0, [](GLsizei n, GLuint *out) { glGenFramebuffers(n, out); });
+      break;
+    case kOpDeleteFramebuffers:
+      del('F', 0,
+          [](GLsizei n, GLuint const *in) { glDeleteFramebuffers(n, in); });
+      break;
+    case kOpBindFramebuffer:
+      glBindFramebuffer(e(0), lookup('F', u(1)));
+      break;
+    case kOpFramebufferTexture2D:
+      glFramebufferTexture2D(e(0), e(1), e(2), tex(3), i(4));
+      break;
+    case kOpGenerateMipmap:
+      glGenerateMipmap(e(0));
+      break;
+    case kOpBlitFramebuffer:
+      glBlitFramebuffer(i(0), i(1), i(2), i(3), i(4), i(5), i(6), i(7), u(8),
+                        e(9));
+      break;
+    case kNumOps:
+      *err = "bad op";
+      return false;
+  }
+
+  return true;
+}
+
+static char *copyString(std::string const &s) {
+  char *ret = static_cast<char *>(malloc(s.size() + 1));
+  std::memcpy(ret, s.c_str(), s.size() + 1);
+  return ret;
+}
+
+extern "C" {
+
+char *GlopTraceDescribe(uint8_t const *data, size_t len) {
+  std::string result;
+  size_t offset = 0;
+  GlTraceCall call;
+  while (offset < len) {
+    if (!decodeCall(data, len, &offset, &call)) {
+      result += "<corrupt call data>\n";
+      break;
+    }
+    result += describeCall(call);
+    result += "\n";
+  }
+  return copyString(result);
+}
+
+struct GlopTraceReplayer *GlopTraceNewReplayer() {
+  return new GlopTraceReplayer();
+}
+
+void GlopTraceFreeReplayer(struct GlopTraceReplayer *replayer) {
+  delete replayer;
+}
+
+size_t GlopTraceReplay(struct GlopTraceReplayer *replayer, uint8_t const *data,
+                       size_t len, char **err) {
+  *err = nullptr;
+
+  size_t count = 0;
+  size_t offset = 0;
+  GlTraceCall call;
+  std::string problem;
+  while (offset < len) {
+    if (!decodeCall(data, len, &offset, &call)) {
+      *err = copyString("corrupt call data");
+      return count;
+    }
+    if (!replayer->replay(call, &problem)) {
+      *err = copyString(problem);
+      return count;
+    }
+    count++;
+  }
+  return count;
+}
+
+}  // extern "C"
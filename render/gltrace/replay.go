@@ -0,0 +1,51 @@
+package gltrace
+
+// #include <stdlib.h>
+// #include "include/gltrace.h"
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/caffeine-storm/glop/render"
+)
+
+// Replays JobRecords into the current GL context. Object names (textures,
+// buffers, shaders, etc.) in a trace are translated to whatever names the
+// current context hands out so a Replayer must see every job of a trace, in
+// order.
+type Replayer struct {
+	replayer *C.struct_GlopTraceReplayer
+}
+
+func NewReplayer() *Replayer {
+	return &Replayer{
+		replayer: C.GlopTraceNewReplayer(),
+	}
+}
+
+// Issues the GL calls of the given record. Must be called on the render
+// thread. Returns an error if some of the calls couldn't be replayed; the
+// calls before the problem will have been made.
+func (r *Replayer) Replay(record *JobRecord) error {
+	render.MustBeOnRenderThread()
+
+	if len(record.Calls) == 0 {
+		return nil
+	}
+
+	var problem *C.char
+	count := C.GlopTraceReplay(r.replayer, (*C.uint8_t)(unsafe.SliceData(record.Calls)), C.size_t(len(record.Calls)), &problem)
+	if problem != nil {
+		defer C.free(unsafe.Pointer(problem))
+		return fmt.Errorf("job %d: stopped after %d calls: %s", record.Seq, count, C.GoString(problem))
+	}
+
+	return nil
+}
+
+func (r *Replayer) Close() {
+	C.GlopTraceFreeReplayer(r.replayer)
+	r.replayer = nil
+}
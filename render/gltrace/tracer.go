@@ -0,0 +1,118 @@
+package gltrace
+
+// #include "include/gltrace.h"
+import "C"
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/caffeine-storm/glop/render"
+)
+
+// There's only one GL call log so only one Tracer can be attached at a time.
+var attached atomic.Bool
+
+// Records the GL calls made by each job on a render queue.
+type Tracer struct {
+	queue render.RenderQueueInterface
+
+	mut     sync.Mutex
+	out     *Writer
+	seq     uint64
+	stopped bool
+	err     error
+}
+
+// Starts writing a trace of the jobs run on the given queue to 'out'. Tracing
+// begins with the jobs queued after Attach returns and continues until Stop
+// is called. Jobs that made no traced GL calls are left out of the trace so
+// JobRecord.Seq can skip numbers.
+func Attach(queue render.RenderQueueInterface, out io.Writer) *Tracer {
+	if !attached.CompareAndSwap(false, true) {
+		panic(fmt.Errorf("gltrace.Attach: a Tracer is already attached"))
+	}
+
+	writer, err := NewWriter(out)
+	if err != nil {
+		attached.Store(false)
+		panic(fmt.Errorf("gltrace.Attach: couldn't write trace header: %w", err))
+	}
+
+	t := &Tracer{
+		queue: queue,
+		out:   writer,
+	}
+
+	// Listeners can't be removed from a queue; once stopped, these become
+	// no-ops.
+	queue.AddTimingListener(&render.JobTimingListener{
+		OnNotify: func(info *render.JobTimingInfo, attribution string) {
+			t.record(info.Lane, attribution, "")
+		},
+		OnPanic: func(info *render.JobTimingInfo, attribution string, err error) {
+			t.record(info.Lane, attribution, err.Error())
+		},
+		Threshold: 0,
+	})
+
+	queue.Queue(func(render.RenderQueueState) {
+		C.GlopTraceInstall()
+		C.GlopTraceSetEnabled(1)
+	})
+
+	return t
+}
+
+// Runs on the render thread after each job.
+func (t *Tracer) record(lane render.Lane, attribution, jobErr string) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if t.stopped {
+		return
+	}
+
+	t.seq++
+	calls := takeCalls()
+	if len(calls) == 0 && jobErr == "" {
+		return
+	}
+
+	err := t.out.Write(&JobRecord{
+		Seq:         t.seq,
+		Lane:        lane,
+		Attribution: attribution,
+		Err:         jobErr,
+		Calls:       calls,
+	})
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+}
+
+// Stops tracing and flushes the trace. Blocks until the queue has run
+// everything queued before the call. Returns the first error encountered
+// while writing the trace, if any.
+func (t *Tracer) Stop() error {
+	t.queue.Queue(func(render.RenderQueueState) {
+		C.GlopTraceSetEnabled(0)
+	})
+	t.queue.Purge()
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if t.stopped {
+		return t.err
+	}
+	t.stopped = true
+	attached.Store(false)
+
+	if err := t.out.Flush(); err != nil && t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/caffeine-storm/glop/render"
 	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/exp/slices"
 )
 
 func pickADifferentMode(someMatrixMode render.MatrixMode) render.MatrixMode {
@@ -41,28 +40,24 @@ func pickADifferentMatrix(someMatrix render.Matrix) render.Matrix {
 }
 
 func assertFreshState(t *testing.T, st *debug.GlState) {
-	ident64 := make([]float64, 16)
+	var ident64 [16]float64
 	for i := range 4 {
 		ident64[4*i+i] = 1.0
 	}
 
-	for i, stateComponent := range [][]float64{
+	for i, stateComponent := range [][16]float64{
 		st.ModelViewMatrix,
 		st.ProjectionMatrix,
 		st.TextureMatrix,
 	} {
-		if !slices.Equal(ident64, stateComponent) {
+		if ident64 != stateComponent {
 			t.Logf("mismatch: component#%d, %v vs %v", i, ident64, stateComponent)
 			t.Fail()
 		}
 	}
 
-	expectedColorMat := ident64
-	if !glew.GL_ARB_imaging {
-		expectedColorMat = nil
-	}
-
-	if !slices.Equal(expectedColorMat, st.ColorMatrix) {
+	assert.Equal(t, glew.GL_ARB_imaging, st.HasColorMatrix)
+	if st.HasColorMatrix && ident64 != st.ColorMatrix {
 		t.Logf("mismatch: colormatrix, %v vs %v", ident64, st.ColorMatrix)
 		t.Fail()
 	}
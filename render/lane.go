@@ -0,0 +1,81 @@
+package render
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Each RenderJob is queued into a Lane. Jobs in a higher priority lane always
+// run before jobs in a lower priority lane; jobs within the same lane run in
+// the order they were queued.
+type Lane int
+
+const (
+	// For work that the current frame can't be drawn without.
+	LaneFrameCritical Lane = iota
+	// The lane used by RenderQueueInterface.Queue.
+	LaneNormal
+	// For work that can happen 'eventually' like streaming texture uploads.
+	// Note that jobs in this lane can be starved by a steady stream of higher
+	// priority jobs.
+	LaneBackground
+
+	numLanes
+)
+
+func (l Lane) String() string {
+	switch l {
+	case LaneFrameCritical:
+		return "frame-critical"
+	case LaneNormal:
+		return "normal"
+	case LaneBackground:
+		return "background"
+	}
+
+	return fmt.Sprintf("Lane(%d)", int(l))
+}
+
+func (l Lane) mustValidate() {
+	if l < 0 || l >= numLanes {
+		panic(fmt.Errorf("invalid lane: %d", int(l)))
+	}
+}
+
+const (
+	jobPending int32 = iota
+	jobStarted
+	jobCancelled
+)
+
+// A JobHandle refers to a single job that was queued on a
+// RenderQueueInterface. It can be used to cancel the job as long as the job
+// hasn't started yet. The zero-value refers to a pending job.
+type JobHandle struct {
+	state atomic.Int32
+}
+
+// Prevents the job from running. Returns true iff the job had not yet started
+// and will now never run. Cancelling a job that is already cancelled returns
+// true.
+func (h *JobHandle) Cancel() bool {
+	if h.state.CompareAndSwap(jobPending, jobCancelled) {
+		return true
+	}
+	return h.state.Load() == jobCancelled
+}
+
+func (h *JobHandle) IsCancelled() bool {
+	return h.state.Load() == jobCancelled
+}
+
+// Returns true iff the job has started running (or has finished running).
+func (h *JobHandle) IsStarted() bool {
+	return h.state.Load() == jobStarted
+}
+
+// Marks the job as started. Returns false if the job was cancelled and must
+// not run.
+func (h *JobHandle) start() bool {
+	return h.state.CompareAndSwap(jobPending, jobStarted)
+}
@@ -0,0 +1,267 @@
+package render
+
+import (
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/glog"
+	"github.com/caffeine-storm/mathgl"
+)
+
+// The shader that QuadBatch draws with. It's GLSL 3.30 so it works in both
+// core and compatibility contexts.
+const CoreShaderName = "glop.core"
+
+// Attribute locations in CoreShaderName's vertex shader. Shaders passed to
+// QuadBatch.DrawWithShader must use the same ones.
+const (
+	corePositionAttrib gl.AttribLocation = 0
+	coreTexCoordAttrib gl.AttribLocation = 1
+	coreColourAttrib   gl.AttribLocation = 2
+)
+
+const coreVertexShader = `
+#version 330 core
+
+uniform mat4 glop_ModelViewProjection;
+
+layout(location = 0) in vec2 position;
+layout(location = 1) in vec2 texCoord;
+layout(location = 2) in vec4 colour;
+
+out vec2 fragTexCoord;
+out vec4 fragColour;
+
+void main() {
+  gl_Position = glop_ModelViewProjection * vec4(position, 0.0, 1.0);
+  fragTexCoord = texCoord;
+  fragColour = colour;
+}
+`
+
+const coreFragmentShader = `
+#version 330 core
+
+uniform vec4 glop_Colour;
+uniform sampler2D glop_Texture;
+uniform bool glop_Textured;
+
+in vec2 fragTexCoord;
+in vec4 fragColour;
+
+out vec4 outColour;
+
+void main() {
+  vec4 ret = fragColour * glop_Colour;
+  if (glop_Textured) {
+    ret *= texture(glop_Texture, fragTexCoord);
+  }
+  outColour = ret;
+}
+`
+
+// Registers CoreShaderName unless it's already registered. Must be called on
+// the render thread.
+func RegisterCoreShader(bank *ShaderBank) error {
+	if bank.HasShader(CoreShaderName) {
+		return nil
+	}
+	return bank.RegisterShader(CoreShaderName, coreVertexShader, coreFragmentShader)
+}
+
+// An axis-aligned rectangle to be drawn by a QuadBatch.
+type Quad struct {
+	// Opposite corners in the space that the model-view matrix expects.
+	X0, Y0, X1, Y1 float32
+
+	// Texture co-ordinates for (X0, Y0) and (X1, Y1). Ignored when the batch is
+	// drawn without a texture.
+	U0, V0, U1, V1 float32
+
+	// Multiplied with the Transforms' colour. Use {1, 1, 1, 1} to leave it be.
+	Colour mathgl.Vec4
+}
+
+// Collects quads and draws them with one glDrawElements call. This replaces
+// gl.Begin(gl.QUADS), which core profiles don't have. Each attribute lives in
+// its own buffer; the vertex array object remembers how they're bound.
+//
+// A QuadBatch must only be used on the render thread.
+type QuadBatch struct {
+	vao                          gl.VertexArray
+	positions, texCoords, colour gl.Buffer
+	indices                      gl.Buffer
+
+	positionData []float32
+	texCoordData []float32
+	colourData   []float32
+
+	// The number of quads that the index buffer has indices for.
+	indexedQuads int
+}
+
+// Must be called on the render thread.
+func MakeQuadBatch() *QuadBatch {
+	batch := &QuadBatch{
+		vao:       gl.GenVertexArray(),
+		positions: gl.GenBuffer(),
+		texCoords: gl.GenBuffer(),
+		colour:    gl.GenBuffer(),
+		indices:   gl.GenBuffer(),
+	}
+
+	batch.vao.Bind()
+	defer gl.VertexArray(0).Bind()
+
+	for _, attrib := range []struct {
+		location gl.AttribLocation
+		buffer   gl.Buffer
+		size     uint
+	}{
+		{corePositionAttrib, batch.positions, 2},
+		{coreTexCoordAttrib, batch.texCoords, 2},
+		{coreColourAttrib, batch.colour, 4},
+	} {
+		attrib.buffer.Bind(gl.ARRAY_BUFFER)
+		attrib.location.AttribPointer(attrib.size, gl.FLOAT, false, 0, nil)
+		attrib.location.EnableArray()
+	}
+	gl.Buffer(0).Bind(gl.ARRAY_BUFFER)
+
+	// The element array binding is part of the vertex array object's state so
+	// it stays bound until the vertex array object is deleted.
+	batch.indices.Bind(gl.ELEMENT_ARRAY_BUFFER)
+
+	return batch
+}
+
+// Returns the number of quads waiting to be drawn.
+func (batch *QuadBatch) Len() int {
+	return len(batch.positionData) / 8
+}
+
+func (batch *QuadBatch) Add(quad Quad) {
+	batch.positionData = append(batch.positionData,
+		quad.X0, quad.Y0,
+		quad.X1, quad.Y0,
+		quad.X1, quad.Y1,
+		quad.X0, quad.Y1,
+	)
+	batch.texCoordData = append(batch.texCoordData,
+		quad.U0, quad.V0,
+		quad.U1, quad.V0,
+		quad.U1, quad.V1,
+		quad.U0, quad.V1,
+	)
+	c := quad.Colour
+	for range 4 {
+		batch.colourData = append(batch.colourData, c.X, c.Y, c.Z, c.W)
+	}
+}
+
+// Forgets any quads that haven't been drawn.
+func (batch *QuadBatch) Reset() {
+	batch.positionData = batch.positionData[:0]
+	batch.texCoordData = batch.texCoordData[:0]
+	batch.colourData = batch.colourData[:0]
+}
+
+func (batch *QuadBatch) ensureIndices(quads int) {
+	if quads <= batch.indexedQuads {
+		return
+	}
+
+	data := make([]uint32, 0, 6*quads)
+	for i := range quads {
+		first := uint32(4 * i)
+		data = append(data, first, first+1, first+2, first, first+2, first+3)
+	}
+	// The vertex array object must be bound so that it keeps the element array
+	// binding.
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, 4*len(data), data, gl.STATIC_DRAW)
+	batch.indexedQuads = quads
+}
+
+func uploadFloats(buffer gl.Buffer, data []float32) {
+	buffer.Bind(gl.ARRAY_BUFFER)
+	gl.BufferData(gl.ARRAY_BUFFER, 4*len(data), data, gl.STREAM_DRAW)
+}
+
+// Draws every quad added since the last Draw or Reset with CoreShaderName,
+// registering it if need be, then empties the batch. The transforms supply the
+// matrices and colour. If texture is non-zero, it's bound to texture unit 0
+// and sampled; otherwise the quads are drawn in flat colours.
+func (batch *QuadBatch) Draw(bank *ShaderBank, transforms *Transforms, texture gl.Texture) error {
+	if batch.Len() > 0 {
+		err := RegisterCoreShader(bank)
+		if err != nil {
+			batch.Reset()
+			return err
+		}
+	}
+	return batch.DrawWithShader(bank, transforms, CoreShaderName, texture)
+}
+
+// Like Draw but with the named shader, which must already be registered. The
+// shader must read its attributes from the locations that CoreShaderName
+// does. Any of CoreShaderName's uniforms that it lacks are left alone.
+func (batch *QuadBatch) DrawWithShader(bank *ShaderBank, transforms *Transforms, shader string, texture gl.Texture) error {
+	defer batch.Reset()
+
+	quads := batch.Len()
+	if quads == 0 {
+		return nil
+	}
+
+	oldProgram := gl.Program(gl.GetInteger(gl.CURRENT_PROGRAM))
+	err := bank.EnableShader(shader)
+	if err != nil {
+		return err
+	}
+	defer oldProgram.Use()
+
+	err = transforms.SetUniforms(bank, shader)
+	if err != nil {
+		return err
+	}
+	textured := 0
+	if texture != 0 {
+		textured = 1
+		oldUnit := gl.GLenum(gl.GetInteger(gl.ACTIVE_TEXTURE))
+		gl.ActiveTexture(gl.TEXTURE0)
+		oldTexture := gl.Texture(gl.GetInteger(gl.TEXTURE_BINDING_2D))
+		texture.Bind(gl.TEXTURE_2D)
+		defer func() {
+			oldTexture.Bind(gl.TEXTURE_2D)
+			gl.ActiveTexture(oldUnit)
+		}()
+	}
+	err = bank.SetUniforms(shader, struct {
+		Texture  int `uniform:"glop_Texture,optional"`
+		Textured int `uniform:"glop_Textured,optional"`
+	}{
+		Texture:  0,
+		Textured: textured,
+	})
+	if err != nil {
+		return err
+	}
+
+	batch.vao.Bind()
+	defer gl.VertexArray(0).Bind()
+
+	uploadFloats(batch.positions, batch.positionData)
+	uploadFloats(batch.texCoords, batch.texCoordData)
+	uploadFloats(batch.colour, batch.colourData)
+	gl.Buffer(0).Bind(gl.ARRAY_BUFFER)
+	batch.ensureIndices(quads)
+
+	gl.DrawElements(gl.TRIANGLES, 6*quads, gl.UNSIGNED_INT, nil)
+
+	LogAndClearGlErrors(glog.DebugLogger())
+	return nil
+}
+
+// Releases the batch's GL objects. Must be called on the render thread.
+func (batch *QuadBatch) Delete() {
+	batch.vao.Delete()
+	gl.DeleteBuffers([]gl.Buffer{batch.positions, batch.texCoords, batch.colour, batch.indices})
+}
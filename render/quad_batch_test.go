@@ -0,0 +1,166 @@
+package render_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/debug"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
+	"github.com/caffeine-storm/mathgl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns the pixel at (x, y) where (0, 0) is the bottom-left corner.
+func pixelAt(img *image.NRGBA, x, y int) color.NRGBA {
+	return img.NRGBAAt(x, img.Bounds().Dy()-1-y)
+}
+
+func TestQuadBatch(t *testing.T) {
+	const size = 64
+	white := mathgl.Vec4{X: 1, Y: 1, Z: 1, W: 1}
+
+	t.Run("draws flat quads in one batch", func(t *testing.T) {
+		var err error
+		var quadsAfterDraw int
+		var screen *image.NRGBA
+		testbuilder.New().WithSize(size, size).RunForQueueState(func(st render.RenderQueueState) {
+			gl.ClearColor(0, 0, 0, 1)
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+
+			transforms := render.MakeTransforms()
+			transforms.Ortho(render.MatrixModeProjection, 0, size, 0, size, 1, -1)
+
+			batch := render.MakeQuadBatch()
+			defer batch.Delete()
+
+			batch.Add(render.Quad{X0: 0, Y0: 0, X1: 32, Y1: 32, Colour: mathgl.Vec4{X: 1, W: 1}})
+			batch.Add(render.Quad{X0: 32, Y0: 32, X1: 64, Y1: 64, Colour: mathgl.Vec4{Y: 1, W: 1}})
+			err = batch.Draw(st.Shaders(), transforms, 0)
+			quadsAfterDraw = batch.Len()
+
+			screen = debug.ScreenShotNrgba(size, size)
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 0, quadsAfterDraw)
+		assert.Equal(t, color.NRGBA{R: 255, A: 255}, pixelAt(screen, 8, 8))
+		assert.Equal(t, color.NRGBA{G: 255, A: 255}, pixelAt(screen, 56, 56))
+		assert.Equal(t, color.NRGBA{A: 255}, pixelAt(screen, 8, 56))
+	})
+
+	t.Run("applies the transforms' colour and matrices", func(t *testing.T) {
+		var err error
+		var screen *image.NRGBA
+		testbuilder.New().WithSize(size, size).RunForQueueState(func(st render.RenderQueueState) {
+			gl.ClearColor(0, 0, 0, 1)
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+
+			transforms := render.MakeTransforms()
+			transforms.Ortho(render.MatrixModeProjection, 0, size, 0, size, 1, -1)
+
+			batch := render.MakeQuadBatch()
+			defer batch.Delete()
+
+			transforms.WithMatrixMode(render.MatrixModeModelView, func() {
+				transforms.Translate(render.MatrixModeModelView, 32, 0, 0)
+				transforms.WithColour(0, 0, 1, 1, func() {
+					batch.Add(render.Quad{X0: 0, Y0: 0, X1: 32, Y1: 32, Colour: white})
+					err = batch.Draw(st.Shaders(), transforms, 0)
+				})
+			})
+
+			screen = debug.ScreenShotNrgba(size, size)
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, color.NRGBA{A: 255}, pixelAt(screen, 8, 8))
+		assert.Equal(t, color.NRGBA{B: 255, A: 255}, pixelAt(screen, 40, 8))
+	})
+
+	t.Run("samples the texture", func(t *testing.T) {
+		var err error
+		var screen *image.NRGBA
+		testbuilder.New().WithSize(size, size).RunForQueueState(func(st render.RenderQueueState) {
+			gl.ClearColor(0, 0, 0, 1)
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+
+			// Left half yellow, right half cyan.
+			texture := gl.GenTexture()
+			defer texture.Delete()
+			texture.Bind(gl.TEXTURE_2D)
+			gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+			gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+			gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, 2, 1, 0, gl.RGBA, gl.UNSIGNED_BYTE, []byte{
+				255, 255, 0, 255,
+				0, 255, 255, 255,
+			})
+			gl.Texture(0).Bind(gl.TEXTURE_2D)
+
+			transforms := render.MakeTransforms()
+			batch := render.MakeQuadBatch()
+			defer batch.Delete()
+
+			batch.Add(render.Quad{X0: -1, Y0: -1, X1: 1, Y1: 1, U0: 0, V0: 0, U1: 1, V1: 1, Colour: white})
+			err = batch.Draw(st.Shaders(), transforms, texture)
+
+			screen = debug.ScreenShotNrgba(size, size)
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, color.NRGBA{R: 255, G: 255, A: 255}, pixelAt(screen, 8, 32))
+		assert.Equal(t, color.NRGBA{G: 255, B: 255, A: 255}, pixelAt(screen, 56, 32))
+	})
+	t.Run("puts back the program and texture state", func(t *testing.T) {
+		const shaderName = "quad-batch-previous"
+		var err error
+		var program gl.Program
+		var programAfter, unitAfter, bindingAfter, unit0BindingAfter int
+		var previous gl.Texture
+		testbuilder.New().WithSize(size, size).RunForQueueState(func(st render.RenderQueueState) {
+			bank := st.Shaders()
+			if _, ok := bank.ShaderProgs[shaderName]; !ok {
+				err = bank.RegisterShader(shaderName, testVertexShader, testFragmentShader)
+				if err != nil {
+					return
+				}
+			}
+			program = bank.ShaderProgs[shaderName]
+			program.Use()
+			defer gl.Program(0).Use()
+
+			previous = gl.GenTexture()
+			defer previous.Delete()
+			gl.ActiveTexture(gl.TEXTURE1)
+			previous.Bind(gl.TEXTURE_2D)
+			defer func() {
+				gl.ActiveTexture(gl.TEXTURE1)
+				gl.Texture(0).Bind(gl.TEXTURE_2D)
+				gl.ActiveTexture(gl.TEXTURE0)
+			}()
+
+			texture := gl.GenTexture()
+			defer texture.Delete()
+
+			batch := render.MakeQuadBatch()
+			defer batch.Delete()
+			batch.Add(render.Quad{X0: -1, Y0: -1, X1: 1, Y1: 1, Colour: white})
+			err = batch.Draw(bank, render.MakeTransforms(), texture)
+
+			programAfter = gl.GetInteger(gl.CURRENT_PROGRAM)
+			unitAfter = gl.GetInteger(gl.ACTIVE_TEXTURE)
+			bindingAfter = gl.GetInteger(gl.TEXTURE_BINDING_2D)
+			gl.ActiveTexture(gl.TEXTURE0)
+			unit0BindingAfter = gl.GetInteger(gl.TEXTURE_BINDING_2D)
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, int(program), programAfter)
+		assert.Equal(t, int(gl.TEXTURE1), unitAfter)
+		assert.Equal(t, int(previous), bindingAfter)
+		assert.Equal(t, 0, unit0BindingAfter)
+	})
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"reflect"
 	"runtime"
 	"runtime/pprof"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -40,7 +42,12 @@ type RenderQueueState interface {
 type RenderJob func(RenderQueueState)
 
 func (j *RenderJob) GetSourceAttribution() string {
-	pc := uintptr(reflect.ValueOf(*j).UnsafePointer())
+	return sourceAttribution(*j)
+}
+
+// Names the file and line that the given func was defined at.
+func sourceAttribution(f any) string {
+	pc := uintptr(reflect.ValueOf(f).UnsafePointer())
 	fn := runtime.FuncForPC(pc)
 	if fn == nil {
 		panic("couldn't runtime.FuncForPC T_T")
@@ -64,9 +71,16 @@ type RenderQueueInterface interface {
 	// RenderQueueInterface's render thread. Callers may assume that the
 	// RenderQueueState instance passed to each RenderJob is the same object
 	// per-queue. Caveat: if the queue is in a 'defunct' state, calls to Queue()
-	// will succeed but the jobs may not run.
+	// will succeed but the jobs may not run. Equivalent to calling
+	// QueueInLane(LaneNormal, f) and ignoring the returned JobHandle.
 	Queue(f RenderJob)
 
+	// Like Queue but the job runs in the given Lane. Jobs are run sequentially
+	// in the order queued with respect to other jobs in the same lane but any
+	// job in a higher priority lane will run first. The returned JobHandle can
+	// be used to cancel the job before it starts.
+	QueueInLane(lane Lane, f RenderJob) *JobHandle
+
 	// Blocks until all Queue'd jobs have completed. Note that, if other
 	// goroutines are queueing jobs, this will block waiting for them as well!
 	// If the queue enters a 'defunct' state (by calling StopProcessing or if the
@@ -94,6 +108,22 @@ type RenderQueueInterface interface {
 
 	// For debugability, polls the queue's current Purging/NotPurging status.
 	IsPurging() bool
+
+	// Registers a listener for job and frame timing. Can be called at any time.
+	AddTimingListener(*JobTimingListener)
+
+	// Jobs queued between a call to BeginFrame() and the matching EndFrame()
+	// call are grouped into a frame. Once each of those jobs has run (or been
+	// cancelled), the frame's FrameStats are recorded and sent to any
+	// JobTimingListener's OnFrame callback. Frames must not overlap; calling
+	// BeginFrame() twice without an intervening EndFrame() panics, as does
+	// calling EndFrame() without a matching BeginFrame().
+	BeginFrame()
+	EndFrame()
+
+	// Returns stats for up to the last FrameHistorySize completed frames,
+	// oldest first.
+	RecentFrames() []FrameStats
 }
 
 type RenderQueueWithLoggerInterface interface {
@@ -101,6 +131,28 @@ type RenderQueueWithLoggerInterface interface {
 	SetLogger(glog.Logger)
 }
 
+type RenderQueueWithAttributionInterface interface {
+	RenderQueueInterface
+
+	// Like QueueInLane but the job is attributed to source, which must be a
+	// func, in timing notifications, GL error logs and invariant errors. For
+	// helpers, like Submit, that wrap the caller's func in a job of their own.
+	QueueInLaneAttributed(lane Lane, source any, f RenderJob) *JobHandle
+}
+
+type RenderQueueWithInvariantChecksInterface interface {
+	RenderQueueInterface
+
+	// When enabled, every job is followed by a cheap subset of
+	// CheckGlInvariants: matrix stack depths, buffer and texture bindings and
+	// the foreground colour. A violation is reported to the error callbacks as
+	// a *GlInvariantError naming the job's source and then undone so that
+	// later jobs aren't blamed for it. Jobs that run without a current GL
+	// context aren't checked. Defaults to on in builds with the 'glopdebug' tag
+	// and off otherwise.
+	SetGlInvariantChecks(enabled bool)
+}
+
 type renderQueueState struct {
 	ctx     context.Context
 	shaders *ShaderBank
@@ -119,24 +171,145 @@ func (state *renderQueueState) Shaders() *ShaderBank {
 type jobWithTiming struct {
 	Job      RenderJob
 	QueuedAt time.Time
+	Lane     Lane
+	Handle   *JobHandle
+	// The frame that was open when the job was queued, if any.
+	Frame *frameRecord
+	// The func that the job is attributed to; Job itself if nil.
+	Source any
+}
+
+func (request *jobWithTiming) source() any {
+	if request.Source != nil {
+		return request.Source
+	}
+	return request.Job
+}
+
+func (request *jobWithTiming) attribution() string {
+	return sourceAttribution(request.source())
 }
 
 type renderQueue struct {
-	queueState     *renderQueueState
-	workQueue      chan *jobWithTiming
-	purge          chan chan bool
-	isRunning      atomic.Bool
-	isPurging      atomic.Bool
-	isDefunct      atomic.Bool
-	listener       *JobTimingListener
+	queueState *renderQueueState
+	// One channel of work per Lane; indexed by Lane.
+	workQueues [numLanes]chan *jobWithTiming
+	purge      chan chan bool
+	isRunning  atomic.Bool
+	isPurging  atomic.Bool
+	isDefunct  atomic.Bool
+	listeners  struct {
+		all []*JobTimingListener
+		mut sync.Mutex
+	}
 	errorCallbacks struct {
 		fns []func(RenderQueueInterface, error)
 		mut sync.Mutex
 	}
-	logger glog.Logger
+	frames struct {
+		current *frameRecord
+		// Every frame that has begun but not completed, including current.
+		unfinished map[*frameRecord]struct{}
+		lastNumber uint64
+		history    frameHistory
+		mut        sync.Mutex
+	}
+	// Things waiting on jobs that haven't started yet; see track.
+	abandonables struct {
+		set map[abandonable]struct{}
+		mut sync.Mutex
+	}
+	logger struct {
+		current glog.Logger
+		mut     sync.Mutex
+	}
+	invariantChecks atomic.Bool
+}
+
+// Something waiting on a job that has to be told if the job will never run.
+type abandonable interface {
+	abandon()
+}
+
+// Implemented by queues that can tell Submit when they go defunct.
+type abandonTracker interface {
+	// Arranges for a.abandon() to be called if the queue goes defunct before
+	// forget(a) is called. Returns false if the queue is already defunct.
+	track(a abandonable) bool
+	forget(a abandonable)
+}
+
+var (
+	_ abandonTracker                      = (*renderQueue)(nil)
+	_ RenderQueueWithAttributionInterface = (*renderQueue)(nil)
+)
+
+func (q *renderQueue) track(a abandonable) bool {
+	q.abandonables.mut.Lock()
+	defer q.abandonables.mut.Unlock()
+	if q.isDefunct.Load() {
+		return false
+	}
+	if q.abandonables.set == nil {
+		q.abandonables.set = map[abandonable]struct{}{}
+	}
+	q.abandonables.set[a] = struct{}{}
+	return true
+}
+
+func (q *renderQueue) forget(a abandonable) {
+	q.abandonables.mut.Lock()
+	defer q.abandonables.mut.Unlock()
+	delete(q.abandonables.set, a)
+}
+
+// Puts the queue in a 'defunct' state and abandons everything that was still
+// being tracked.
+func (q *renderQueue) markDefunct() {
+	q.abandonables.mut.Lock()
+	q.isDefunct.Store(true)
+	set := q.abandonables.set
+	q.abandonables.set = nil
+	q.abandonables.mut.Unlock()
+
+	for a := range set {
+		a.abandon()
+	}
+
+	// Frames still waiting on jobs would otherwise never complete.
+	q.frames.mut.Lock()
+	frames := slices.Collect(maps.Keys(q.frames.unfinished))
+	q.frames.mut.Unlock()
+
+	for _, frame := range frames {
+		if frame.abandon() {
+			q.completeFrame(frame)
+		}
+	}
 }
 
-func (q *renderQueue) runAndNotify(request *jobWithTiming, ack chan bool) time.Duration {
+// Runs the given job unless it was cancelled.
+func (q *renderQueue) runIfPending(request *jobWithTiming, ack chan bool) {
+	if !request.Handle.start() {
+		q.onFrameJobDone(request.Frame, nil, true)
+		return
+	}
+
+	var info *JobTimingInfo
+	defer func() {
+		// Even if the job panics, its frame shouldn't wait on it forever.
+		q.onFrameJobDone(request.Frame, info, false)
+	}()
+	info = q.runAndNotify(request, ack)
+}
+
+func (q *renderQueue) getListeners() []*JobTimingListener {
+	q.listeners.mut.Lock()
+	defer q.listeners.mut.Unlock()
+	return slices.Clone(q.listeners.all)
+}
+
+func (q *renderQueue) runAndNotify(request *jobWithTiming, ack chan bool) *JobTimingInfo {
 	before := time.Now()
 	after := time.Time{}
 	defer func() {
@@ -148,6 +321,7 @@ func (q *renderQueue) runAndNotify(request *jobWithTiming, ack chan bool) time.D
 		// Let panics panic but, if e is nil, we know we're running
 		// runtime.Goeexit.
 		if e := recover(); e != nil {
+			q.notifyPanic(request, before, e)
 			panic(e)
 		}
 
@@ -162,26 +336,97 @@ func (q *renderQueue) runAndNotify(request *jobWithTiming, ack chan bool) time.D
 
 		// Set this flag last so that clients either see 'already defunct' or they
 		// try to read/write closed channels.
-		q.isDefunct.Store(true)
+		q.markDefunct()
 	}()
 
-	LogAndClearGlErrors(q.logger)
+	LogAndClearGlErrors(q.getLogger())
 	request.Job(q.queueState)
-	LogAndClearGlErrorsWithAttribution(q.logger, request.Job)
+	glErrorCount := logAndCountGlErrorsWithAttribution(q.getLogger(), request.source())
 
 	after = time.Now()
 	delta := after.Sub(before)
 
 	info := &JobTimingInfo{
-		RunTime:   delta,
-		QueueTime: before.Sub(request.QueuedAt),
+		RunTime:      delta,
+		QueueTime:    before.Sub(request.QueuedAt),
+		Lane:         request.Lane,
+		GlErrorCount: glErrorCount,
 	}
 	totalTime := info.RunTime + info.QueueTime
-	if q.listener != nil && totalTime >= q.listener.Threshold {
-		q.listener.OnNotify(info, request.Job.GetSourceAttribution())
+	for _, listener := range q.getListeners() {
+		if listener.OnNotify != nil && totalTime >= listener.Threshold {
+			listener.OnNotify(info, request.attribution())
+		}
+	}
+
+	// Checking costs a handful of glGets so it's left out of the job's timing
+	// and happens after listeners, like gltrace's Tracer, are done with the
+	// job. Any state that has to be put back is traced with the next job, which
+	// then replays from the same clean state that it ran from.
+	if q.invariantChecks.Load() {
+		q.checkInvariantsAfter(request)
+	}
+
+	return info
+}
+
+func (q *renderQueue) notifyPanic(request *jobWithTiming, before time.Time, e any) {
+	err, ok := e.(error)
+	if !ok {
+		err = fmt.Errorf("non-error error: %v", e)
+	}
+	info := &JobTimingInfo{
+		RunTime:   time.Since(before),
+		QueueTime: before.Sub(request.QueuedAt),
+		Lane:      request.Lane,
+	}
+	for _, listener := range q.getListeners() {
+		if listener.OnPanic != nil {
+			listener.OnPanic(info, request.attribution(), err)
+		}
+	}
+}
+
+func (q *renderQueue) onFrameJobDone(frame *frameRecord, info *JobTimingInfo, cancelled bool) {
+	if frame == nil {
+		return
+	}
+	if frame.jobDone(info, cancelled) {
+		q.completeFrame(frame)
+	}
+}
+
+func (q *renderQueue) completeFrame(frame *frameRecord) {
+	stats := frame.finalize()
+	if stats == nil {
+		return
 	}
 
-	return delta
+	q.frames.mut.Lock()
+	delete(q.frames.unfinished, frame)
+	q.frames.history.push(stats)
+	q.frames.mut.Unlock()
+
+	for _, listener := range q.getListeners() {
+		if listener.OnFrame != nil {
+			listener.OnFrame(stats)
+		}
+	}
+}
+
+func (q *renderQueue) checkInvariantsAfter(request *jobWithTiming) {
+	if !hasCurrentContext() {
+		return
+	}
+	err := checkCheapGlInvariants()
+	if err == nil {
+		return
+	}
+	enforceCheapGlInvariants()
+	q.onError(&GlInvariantError{
+		Attribution: request.attribution(),
+		Err:         err,
+	})
 }
 
 func (q *renderQueue) onError(e error) {
@@ -226,46 +471,80 @@ func (q *renderQueue) loopWithErrorTracking() {
 	}
 }
 
+// Returns the next queued job from the highest priority, non-empty lane.
+// Returns nil if every lane is empty.
+func (q *renderQueue) pollJob() *jobWithTiming {
+	for _, lane := range q.workQueues {
+		select {
+		case job := <-lane:
+			return job
+		default:
+		}
+	}
+	return nil
+}
+
 func (q *renderQueue) loop() {
 	for {
 		select {
-		case job := <-q.workQueue:
-			q.runAndNotify(job, nil)
 		case ack := <-q.purge:
-			func() {
-				q.isPurging.Store(true)
-				defer q.isPurging.Store(false)
-
-				defer func() {
-					if e := recover(); e != nil {
-						// put the 'ack' channel back into the sequence-of-purge-requests
-						// so that we can continue draining the queue once loop() gets
-						// called again. We can't just acknowledge the purge request yet
-						// because there could be more work in the workQueue.
-						q.purge <- ack
-
-						// Re-raise the error so that RenderQueueInterface error reporting
-						// can happen.
-						panic(e)
-					}
-				}()
-
-				for {
-					select {
-					case job := <-q.workQueue:
-						q.runAndNotify(job, ack)
-					default:
-						// We've just exhausted the workQueue; we can break out of this
-						// inner func().
-						return
-					}
-				}
-			}()
-			ack <- true
+			q.drain(ack)
+			continue
+		default:
+		}
+
+		if job := q.pollJob(); job != nil {
+			q.runIfPending(job, nil)
+			continue
+		}
+
+		// Everything is empty; wait for something to do.
+		select {
+		case job := <-q.workQueues[LaneFrameCritical]:
+			q.runIfPending(job, nil)
+		case job := <-q.workQueues[LaneNormal]:
+			q.runIfPending(job, nil)
+		case job := <-q.workQueues[LaneBackground]:
+			q.runIfPending(job, nil)
+		case ack := <-q.purge:
+			q.drain(ack)
 		}
 	}
 }
 
+// Runs jobs until every lane is empty then acknowledges the purge request.
+func (q *renderQueue) drain(ack chan bool) {
+	func() {
+		q.isPurging.Store(true)
+		defer q.isPurging.Store(false)
+
+		defer func() {
+			if e := recover(); e != nil {
+				// put the 'ack' channel back into the sequence-of-purge-requests
+				// so that we can continue draining the queue once loop() gets
+				// called again. We can't just acknowledge the purge request yet
+				// because there could be more work in the workQueues.
+				q.purge <- ack
+
+				// Re-raise the error so that RenderQueueInterface error reporting
+				// can happen.
+				panic(e)
+			}
+		}()
+
+		for {
+			job := q.pollJob()
+			if job == nil {
+				// We've just exhausted the workQueues; we can break out of this
+				// inner func().
+				return
+			}
+			q.runIfPending(job, ack)
+		}
+	}()
+	ack <- true
+}
+
 func MakeQueue(initialization RenderJob) RenderQueueInterface {
 	return MakeQueueWithTimingAndLogger(initialization, nil, glog.WarningLogger())
 }
@@ -283,23 +562,31 @@ func MakeQueueWithTimingAndLogger(initialization RenderJob, listener *JobTimingL
 		logger = glog.VoidLogger()
 	}
 
+	shaders := MakeShaderBank()
+	shaders.Logger = logger
 	result := renderQueue{
 		queueState: &renderQueueState{
 			ctx:     context.Background(),
-			shaders: MakeShaderBank(),
+			shaders: shaders,
 		},
-		workQueue: make(chan *jobWithTiming, 1000),
 		purge:     make(chan chan bool, 16),
 		isRunning: atomic.Bool{}, // zero-value is false
 		isPurging: atomic.Bool{}, // zero-value is false
 		isDefunct: atomic.Bool{}, // zero-value is false
-		listener:  listener,
-		logger:    logger,
+	}
+	result.logger.current = logger
+	result.invariantChecks.Store(glInvariantChecksByDefault)
+	if listener != nil {
+		result.listeners.all = append(result.listeners.all, listener)
+	}
+	for lane := range result.workQueues {
+		result.workQueues[lane] = make(chan *jobWithTiming, 1000)
 	}
 
-	// We're guaranteed that this render job will run first. We can include our
-	// own initialization that should happen on the loop's thread.
-	result.Queue(func(st RenderQueueState) {
+	// We're guaranteed that this render job will run first because it's the
+	// first job in the highest priority lane. We can include our own
+	// initialization that should happen on the loop's thread.
+	result.QueueInLane(LaneFrameCritical, func(st RenderQueueState) {
 		runtime.LockOSThread()
 		tls.SetSentinel()
 		initialization(st)
@@ -327,12 +614,40 @@ func (q *renderQueue) AddErrorCallback(fn func(RenderQueueInterface, error)) {
 // TODO(tmckee): inject a GL dependency to given func for testability and to
 // keep arbitrary code from calling GL off of the render thread.
 func (q *renderQueue) Queue(f RenderJob) {
+	q.QueueInLane(LaneNormal, f)
+}
+
+func (q *renderQueue) QueueInLane(lane Lane, f RenderJob) *JobHandle {
+	return q.QueueInLaneAttributed(lane, nil, f)
+}
+
+func (q *renderQueue) QueueInLaneAttributed(lane Lane, source any, f RenderJob) *JobHandle {
+	lane.mustValidate()
+
+	handle := &JobHandle{}
 	if q.isDefunct.Load() {
-		return
+		return handle
+	}
+
+	q.frames.mut.Lock()
+	frame := q.frames.current
+	if frame != nil {
+		frame.addPending()
 	}
-	q.workQueue <- &jobWithTiming{
+	q.frames.mut.Unlock()
+
+	q.enqueue(lane, source, f, handle, frame)
+	return handle
+}
+
+func (q *renderQueue) enqueue(lane Lane, source any, f RenderJob, handle *JobHandle, frame *frameRecord) {
+	q.workQueues[lane] <- &jobWithTiming{
 		Job:      f,
 		QueuedAt: time.Now(),
+		Lane:     lane,
+		Handle:   handle,
+		Frame:    frame,
+		Source:   source,
 	}
 }
 
@@ -365,7 +680,7 @@ func (q *renderQueue) StartProcessing() {
 }
 
 func (q *renderQueue) StopProcessing() {
-	q.isDefunct.Store(true)
+	q.markDefunct()
 }
 
 func (q *renderQueue) IsDefunct() bool {
@@ -376,6 +691,83 @@ func (q *renderQueue) IsPurging() bool {
 	return q.isPurging.Load()
 }
 
+func (q *renderQueue) AddTimingListener(listener *JobTimingListener) {
+	q.listeners.mut.Lock()
+	defer q.listeners.mut.Unlock()
+	q.listeners.all = append(q.listeners.all, listener)
+}
+
+func (q *renderQueue) BeginFrame() {
+	q.frames.mut.Lock()
+	defer q.frames.mut.Unlock()
+
+	if q.frames.current != nil {
+		panic(fmt.Errorf("BeginFrame called during frame %d", q.frames.current.stats.Number))
+	}
+
+	q.frames.lastNumber++
+	q.frames.current = &frameRecord{
+		stats: FrameStats{
+			Number:  q.frames.lastNumber,
+			BeganAt: time.Now(),
+		},
+	}
+	if q.frames.unfinished == nil {
+		q.frames.unfinished = map[*frameRecord]struct{}{}
+	}
+	q.frames.unfinished[q.frames.current] = struct{}{}
+
+	// Frames begun after the queue went defunct have nothing to wait for.
+	if q.isDefunct.Load() {
+		q.frames.current.abandoned = true
+	}
+}
+
+func (q *renderQueue) EndFrame() {
+	q.frames.mut.Lock()
+	frame := q.frames.current
+	q.frames.current = nil
+	q.frames.mut.Unlock()
+
+	if frame == nil {
+		panic(fmt.Errorf("EndFrame called without a matching BeginFrame"))
+	}
+
+	if frame.end() {
+		// Every job in the frame has already finished.
+		q.completeFrame(frame)
+	}
+}
+
+func (q *renderQueue) RecentFrames() []FrameStats {
+	q.frames.mut.Lock()
+	defer q.frames.mut.Unlock()
+	return q.frames.history.list()
+}
+
+func (q *renderQueue) SetGlInvariantChecks(enabled bool) {
+	q.invariantChecks.Store(enabled)
+}
+
+// The queue reports GL errors to the new logger as soon as SetLogger returns.
+// The ShaderBank is only touched on the render thread so its logger is
+// updated by a LaneFrameCritical job; that job isn't counted in any open
+// frame.
 func (q *renderQueue) SetLogger(logger glog.Logger) {
-	q.logger = logger
+	q.logger.mut.Lock()
+	q.logger.current = logger
+	q.logger.mut.Unlock()
+
+	if q.isDefunct.Load() {
+		return
+	}
+	q.enqueue(LaneFrameCritical, nil, func(st RenderQueueState) {
+		st.Shaders().Logger = logger
+	}, &JobHandle{}, nil)
+}
+
+func (q *renderQueue) getLogger() glog.Logger {
+	q.logger.mut.Lock()
+	defer q.logger.mut.Unlock()
+	return q.logger.current
 }
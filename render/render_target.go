@@ -0,0 +1,143 @@
+package render
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/imgmanip"
+)
+
+// An offscreen framebuffer with an RGBA colour texture and, optionally, a
+// depth buffer. Draw into one with WithRenderTarget; the colour texture can
+// then be sampled like any other texture or read back with ReadPixels.
+//
+// RenderTargets must only be used on the render thread.
+type RenderTarget struct {
+	Width, Height int
+
+	framebuffer gl.Framebuffer
+	colour      gl.Texture
+
+	// Zero if there's no depth attachment.
+	depth gl.Renderbuffer
+}
+
+// Returned when a framebuffer can't be drawn into with the attachments we
+// gave it.
+type IncompleteRenderTargetError struct {
+	Status gl.GLenum
+}
+
+func (err *IncompleteRenderTargetError) Error() string {
+	return fmt.Sprintf("framebuffer is incomplete: status 0x%04x", int(err.Status))
+}
+
+// Must be called on the render thread. The colour texture's contents are
+// undefined until something is drawn.
+func MakeRenderTarget(width, height int, withDepth bool) (*RenderTarget, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("MakeRenderTarget: bad dims (%d, %d)", width, height)
+	}
+
+	rt := &RenderTarget{
+		Width:       width,
+		Height:      height,
+		framebuffer: gl.GenFramebuffer(),
+		colour:      gl.GenTexture(),
+	}
+
+	rt.colour.Bind(gl.TEXTURE_2D)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	gl.Texture(0).Bind(gl.TEXTURE_2D)
+
+	if withDepth {
+		rt.depth = gl.GenRenderbuffer()
+		rt.depth.Bind()
+		gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT24, width, height)
+		rt.depth.Unbind()
+	}
+
+	var status gl.GLenum
+	withFramebuffer(rt.framebuffer, func() {
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, rt.colour, 0)
+		if rt.depth != 0 {
+			rt.depth.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.RENDERBUFFER)
+		}
+		status = gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	})
+
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		rt.Delete()
+		return nil, &IncompleteRenderTargetError{Status: status}
+	}
+
+	return rt, nil
+}
+
+// The texture that WithRenderTarget draws into. It's owned by the
+// RenderTarget; don't delete it.
+func (rt *RenderTarget) Texture() gl.Texture {
+	return rt.colour
+}
+
+func (rt *RenderTarget) HasDepth() bool {
+	return rt.depth != 0
+}
+
+// Releases the framebuffer and its attachments. Must be called on the render
+// thread.
+func (rt *RenderTarget) Delete() {
+	rt.framebuffer.Delete()
+	rt.colour.Delete()
+	if rt.depth != 0 {
+		rt.depth.Delete()
+	}
+}
+
+// Reads back the colour texture. Like debug.ScreenShotNrgba, the top row of
+// pixels comes first.
+func (rt *RenderTarget) ReadPixels() *image.NRGBA {
+	ret := image.NewNRGBA(image.Rect(0, 0, rt.Width, rt.Height))
+
+	oldRead := gl.Framebuffer(gl.GetInteger(gl.READ_FRAMEBUFFER_BINDING))
+	rt.framebuffer.BindTarget(gl.READ_FRAMEBUFFER)
+	defer oldRead.BindTarget(gl.READ_FRAMEBUFFER)
+
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	gl.ReadPixels(0, 0, rt.Width, rt.Height, gl.RGBA, gl.UNSIGNED_BYTE, ret.Pix)
+
+	imgmanip.FlipVertically[*image.NRGBA](ret, ret.Pix)
+	return ret
+}
+
+// Binds the given framebuffer for drawing and reading while fn runs.
+func withFramebuffer(fb gl.Framebuffer, fn func()) {
+	oldDraw := gl.Framebuffer(gl.GetInteger(gl.DRAW_FRAMEBUFFER_BINDING))
+	oldRead := gl.Framebuffer(gl.GetInteger(gl.READ_FRAMEBUFFER_BINDING))
+	defer func() {
+		oldDraw.BindTarget(gl.DRAW_FRAMEBUFFER)
+		oldRead.BindTarget(gl.READ_FRAMEBUFFER)
+	}()
+
+	fb.Bind()
+	fn()
+}
+
+// Has everything that fn draws go to the given RenderTarget. The viewport is
+// set to cover the whole target while fn runs; the previous viewport and
+// framebuffer bindings are restored afterwards.
+func WithRenderTarget(rt *RenderTarget, fn func()) {
+	var oldViewport [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, oldViewport[:])
+	defer gl.Viewport(int(oldViewport[0]), int(oldViewport[1]), int(oldViewport[2]), int(oldViewport[3]))
+
+	withFramebuffer(rt.framebuffer, func() {
+		gl.Viewport(0, 0, rt.Width, rt.Height)
+		fn()
+	})
+}
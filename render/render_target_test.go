@@ -0,0 +1,83 @@
+package render_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/debug"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
+	"github.com/caffeine-storm/mathgl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTarget(t *testing.T) {
+	t.Run("rejects bad dims", func(t *testing.T) {
+		var err error
+		testbuilder.Run(func() {
+			_, err = render.MakeRenderTarget(0, 4, false)
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("draws offscreen at its own size", func(t *testing.T) {
+		var makeErr, drawErr error
+		var hasDepth bool
+		var offscreen, screen *image.NRGBA
+		var viewportDuring, viewportAfter [4]int32
+		var bindingAfter int
+		testbuilder.New().WithSize(32, 32).RunForQueueState(func(st render.RenderQueueState) {
+			gl.ClearColor(0, 0, 1, 1)
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+			gl.ClearColor(0, 0, 0, 0)
+
+			rt, err := render.MakeRenderTarget(16, 8, true)
+			makeErr = err
+			if err != nil {
+				return
+			}
+			defer rt.Delete()
+			hasDepth = rt.HasDepth()
+
+			batch := render.MakeQuadBatch()
+			defer batch.Delete()
+			transforms := render.MakeTransforms()
+			transforms.Ortho(render.MatrixModeProjection, 0, 16, 0, 8, 1, -1)
+
+			render.WithRenderTarget(rt, func() {
+				gl.GetIntegerv(gl.VIEWPORT, viewportDuring[:])
+
+				gl.ClearColor(0, 0, 0, 1)
+				gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+				gl.ClearColor(0, 0, 0, 0)
+
+				// Fill the left half.
+				batch.Add(render.Quad{X0: 0, Y0: 0, X1: 8, Y1: 8, Colour: mathgl.Vec4{X: 1, W: 1}})
+				drawErr = batch.Draw(st.Shaders(), transforms, 0)
+			})
+			gl.GetIntegerv(gl.VIEWPORT, viewportAfter[:])
+			bindingAfter = gl.GetInteger(gl.DRAW_FRAMEBUFFER_BINDING)
+
+			offscreen = rt.ReadPixels()
+			screen = debug.ScreenShotNrgba(32, 32)
+		})
+
+		require.NoError(t, makeErr)
+		require.NoError(t, drawErr)
+		assert.True(t, hasDepth)
+
+		assert.Equal(t, [4]int32{0, 0, 16, 8}, viewportDuring)
+		assert.Equal(t, [4]int32{0, 0, 32, 32}, viewportAfter)
+		assert.Equal(t, 0, bindingAfter)
+
+		assert.Equal(t, image.Rect(0, 0, 16, 8), offscreen.Bounds())
+		assert.Equal(t, color.NRGBA{R: 255, A: 255}, offscreen.NRGBAAt(2, 2))
+		assert.Equal(t, color.NRGBA{A: 255}, offscreen.NRGBAAt(14, 2))
+
+		// The window's framebuffer doesn't see any of it.
+		assert.Equal(t, color.NRGBA{B: 255, A: 255}, screen.NRGBAAt(2, 30))
+	})
+}
@@ -279,6 +279,169 @@ func TestJobTiming(t *testing.T) {
 
 		assert.Contains(attribution, "render/render_test.go")
 	})
+
+	t.Run("Lane is reported", func(t *testing.T) {
+		lanes := []render.Lane{}
+		listener := &render.JobTimingListener{
+			OnNotify: func(info *render.JobTimingInfo, attrib string) {
+				lanes = append(lanes, info.Lane)
+			},
+			Threshold: 0,
+		}
+		queue := GivenATimedQueue(listener)
+		queue.QueueInLane(render.LaneBackground, nop)
+		queue.StartProcessing()
+		queue.Purge()
+
+		// The initialization job runs in the frame-critical lane.
+		assert.Equal(t, []render.Lane{render.LaneFrameCritical, render.LaneBackground}, lanes)
+	})
+
+	t.Run("Panics are reported with the job's lane and attribution", func(t *testing.T) {
+		thisIsFine := &everythingIsFine{}
+		var lanes []render.Lane
+		var attributions []string
+		var errs []error
+		listener := &render.JobTimingListener{
+			OnPanic: func(info *render.JobTimingInfo, attrib string, err error) {
+				lanes = append(lanes, info.Lane)
+				attributions = append(attributions, attrib)
+				errs = append(errs, err)
+			},
+			Threshold: time.Hour,
+		}
+		queue := GivenATimedQueue(listener)
+		queue.Queue(nop)
+		queue.QueueInLane(render.LaneBackground, func(render.RenderQueueState) {
+			panic(thisIsFine)
+		})
+		queue.StartProcessing()
+		queue.Purge()
+
+		assert.Equal(t, []render.Lane{render.LaneBackground}, lanes)
+		require.Len(t, attributions, 1)
+		assert.Contains(t, attributions[0], "render/render_test.go")
+		assert.ErrorIs(t, errs[0], thisIsFine)
+	})
+}
+
+func TestRenderQueueLanes(t *testing.T) {
+	t.Run("higher priority lanes run first", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		ranLanes := []render.Lane{}
+		for _, lane := range []render.Lane{render.LaneBackground, render.LaneNormal, render.LaneFrameCritical} {
+			queue.QueueInLane(lane, func(render.RenderQueueState) {
+				ranLanes = append(ranLanes, lane)
+			})
+		}
+
+		queue.StartProcessing()
+		queue.Purge()
+
+		assert.Equal(t, []render.Lane{render.LaneFrameCritical, render.LaneNormal, render.LaneBackground}, ranLanes)
+	})
+
+	t.Run("jobs in the same lane run in the order queued", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		ranJobs := []int{}
+		for i := range 5 {
+			queue.QueueInLane(render.LaneBackground, func(render.RenderQueueState) {
+				ranJobs = append(ranJobs, i)
+			})
+		}
+
+		queue.StartProcessing()
+		queue.Purge()
+
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, ranJobs)
+	})
+
+	t.Run("Queue uses the normal lane", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		ranJobs := []string{}
+		queue.Queue(func(render.RenderQueueState) {
+			ranJobs = append(ranJobs, "queue")
+		})
+		queue.QueueInLane(render.LaneBackground, func(render.RenderQueueState) {
+			ranJobs = append(ranJobs, "background")
+		})
+		queue.QueueInLane(render.LaneNormal, func(render.RenderQueueState) {
+			ranJobs = append(ranJobs, "normal")
+		})
+
+		queue.StartProcessing()
+		queue.Purge()
+
+		assert.Equal(t, []string{"queue", "normal", "background"}, ranJobs)
+	})
+
+	t.Run("invalid lanes are rejected", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		assert.Panics(t, func() {
+			queue.QueueInLane(render.Lane(-1), nop)
+		})
+	})
+
+	t.Run("lanes have names", func(t *testing.T) {
+		assert.Equal(t, "frame-critical", render.LaneFrameCritical.String())
+		assert.Equal(t, "background", render.LaneBackground.String())
+		assert.Equal(t, "Lane(7)", render.Lane(7).String())
+	})
+}
+
+func TestJobHandle(t *testing.T) {
+	t.Run("cancelled jobs do not run", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		didRun := false
+		handle := queue.QueueInLane(render.LaneNormal, func(render.RenderQueueState) {
+			didRun = true
+		})
+
+		require.True(t, handle.Cancel(), "a job that hasn't started must be cancellable")
+
+		queue.StartProcessing()
+		queue.Purge()
+
+		assert.False(t, didRun)
+		assert.True(t, handle.IsCancelled())
+		assert.False(t, handle.IsStarted())
+	})
+
+	t.Run("cancelling one job does not affect others", func(t *testing.T) {
+		queue := GivenAQueue()
+
+		ranJobs := []string{}
+		queue.Queue(func(render.RenderQueueState) {
+			ranJobs = append(ranJobs, "first")
+		})
+		queue.QueueInLane(render.LaneNormal, func(render.RenderQueueState) {
+			ranJobs = append(ranJobs, "cancelled")
+		}).Cancel()
+		queue.Queue(func(render.RenderQueueState) {
+			ranJobs = append(ranJobs, "last")
+		})
+
+		queue.StartProcessing()
+		queue.Purge()
+
+		assert.Equal(t, []string{"first", "last"}, ranJobs)
+	})
+
+	t.Run("started jobs can not be cancelled", func(t *testing.T) {
+		queue := GivenARunningQueue()
+
+		handle := queue.QueueInLane(render.LaneNormal, nop)
+		queue.Purge()
+
+		assert.True(t, handle.IsStarted())
+		assert.False(t, handle.Cancel())
+		assert.False(t, handle.IsCancelled())
+	})
 }
 
 func TestRenderJob(t *testing.T) {
@@ -443,3 +606,20 @@ func TestRenderQueueLogsGlErrors(t *testing.T) {
 		}
 	})
 }
+
+func TestSetLoggerBeforeStartProcessing(t *testing.T) {
+	logger := glog.New(&glog.Opts{
+		Output: &bytes.Buffer{},
+	})
+	queue := GivenAQueue()
+	queue.(render.RenderQueueWithLoggerInterface).SetLogger(logger)
+
+	var shaderLogger glog.Logger
+	queue.Queue(func(st render.RenderQueueState) {
+		shaderLogger = st.Shaders().Logger
+	})
+	queue.StartProcessing()
+	queue.Purge()
+
+	assert.Same(t, logger, shaderLogger)
+}
@@ -30,23 +30,6 @@ func ImagesAreWithinThreshold(actual, expected image.Image, thresh Threshold, ba
 	return CompareWithThreshold(lhsNrgba.Pix, rhsNrgba.Pix, thresh) == 0
 }
 
-func ComputeImageDifference(lhs, rhs []byte) []byte {
-	if len(lhs) != len(rhs) {
-		panic(fmt.Errorf("need same-sized slices but got %d and %d", len(lhs), len(rhs)))
-	}
-
-	ret := make([]byte, len(lhs))
-	for i := range lhs {
-		diff := int(lhs[i]) - int(rhs[i])
-		absdiff := diff
-		if absdiff < 0 {
-			absdiff = -absdiff
-		}
-		ret[i] = byte(absdiff)
-	}
-	return ret
-}
-
 func CompareWithThreshold(lhs, rhs []byte, threshold Threshold) int {
 	llen := len(lhs)
 	rlen := len(rhs)
@@ -118,8 +101,9 @@ func expectPixelReadersMatch(actual, expected io.Reader, thresh Threshold, bg co
 // threshold. We need the fuzzy matching because OpenGL doesn't guarantee exact
 // pixel-to-pixel matches across different hardware/driver combinations. It
 // should be close, though! To support transparency in our testdata files, we
-// also take a background to use as needed.
-func expectPixelsMatchFile(actualImage image.Image, pngFileExpected string, thresh Threshold, bg color.Color) bool {
+// also take a background to use as needed. See CompareImages for the other
+// options in 'args'.
+func expectPixelsMatchFile(actualImage image.Image, pngFileExpected string, args []any) ComparisonResult {
 	pngFile, err := os.Open(pngFileExpected)
 	if err != nil {
 		panic(fmt.Errorf("couldn't os.Open %q: %w", pngFileExpected, err))
@@ -127,7 +111,7 @@ func expectPixelsMatchFile(actualImage image.Image, pngFileExpected string, thre
 	defer pngFile.Close()
 
 	expectedImage := MustLoadImageFromReader(pngFile)
-	return ImagesAreWithinThreshold(actualImage, expectedImage, thresh, bg)
+	return compareImages(actualImage, expectedImage, args)
 }
 
 func readerShouldLookLike(actual, expected io.Reader, args ...interface{}) string {
@@ -166,14 +150,12 @@ func ShouldLookLike(actual interface{}, expected ...interface{}) string {
 }
 
 func imageShouldLookLike(actualImage, expectedImage image.Image, expected ...interface{}) string {
-	bg, _ := getBackgroundFromArgs(expected)
-
-	thresh := getThresholdFromArgs(expected)
-	if ImagesAreWithinThreshold(actualImage, expectedImage, thresh, bg) {
+	result := compareImages(actualImage, expectedImage, expected)
+	if result.Match {
 		return ""
 	}
 
-	return "image mismatch; rejection file creation elided"
+	return fmt.Sprintf("image mismatch (%v); rejection file creation elided", result)
 }
 
 func imageShouldLookLikeFile(actualImage image.Image, expected ...interface{}) string {
@@ -186,10 +168,22 @@ func imageShouldLookLikeFile(actualImage image.Image, expected ...interface{}) s
 
 	expectedFileName := ExpectationFile(testDataKey, "png", testnumber)
 
-	bg, _ := getBackgroundFromArgs(expected)
+	if shouldUpdateGoldens(expected) && !fileExists(expectedFileName) {
+		updateGolden(actualImage, expectedFileName)
+		return ""
+	}
+
+	result := expectPixelsMatchFile(actualImage, expectedFileName, expected)
+	if result.Match {
+		return ""
+	}
 
-	thresh := getThresholdFromArgs(expected)
-	if expectPixelsMatchFile(actualImage, expectedFileName, thresh, bg) {
+	if shouldUpdateGoldens(expected) {
+		updateFileName := goldenToUpdate(testDataKey, testnumber, expectedFileName)
+		updateGolden(actualImage, updateFileName)
+		if updateFileName != expectedFileName {
+			os.Remove(MakeRejectName(expectedFileName, ".png"))
+		}
 		return ""
 	}
 
@@ -197,10 +191,20 @@ func imageShouldLookLikeFile(actualImage image.Image, expected ...interface{}) s
 	if doMakeRejectFiles == true {
 		rejectFileName := MakeRejectName(expectedFileName, ".png")
 		imgmanip.MustDumpImage(actualImage, rejectFileName)
-		return fmt.Sprintf("image mismatch; see %s", rejectFileName)
+		return fmt.Sprintf("image mismatch (%v) against %s; see %s", result, describeExpectation(expectedFileName), rejectFileName)
 	} else {
-		return "image mismatch; rejection file creation elided"
+		return fmt.Sprintf("image mismatch (%v) against %s; rejection file creation elided", result, describeExpectation(expectedFileName))
+	}
+}
+
+// Names the expectation file along with the renderer variant it was chosen for
+// so that failures on one renderer aren't mistaken for failures on another.
+func describeExpectation(expectedFileName string) string {
+	variant := RendererVariant()
+	if variant == "" {
+		variant = "unknown"
 	}
+	return fmt.Sprintf("%s (renderer variant %q)", expectedFileName, variant)
 }
 
 func makeFallbackImage() *image.NRGBA {
@@ -301,7 +305,7 @@ func MustLookLikeFile(t *testing.T, actual any, expected ...any) {
 }
 
 func ShouldNotLookLikeFile(actual interface{}, expected ...interface{}) string {
-	expected = append(expected, MakeRejectFiles(false))
+	expected = append(expected, MakeRejectFiles(false), updateGoldens(false))
 	doesLook := ShouldLookLikeFile(actual, expected...)
 	if doesLook == "" {
 		return "arguments matched but should have been different"
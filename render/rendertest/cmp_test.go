@@ -138,6 +138,15 @@ func TestComparingPngsAgainstPngs(t *testing.T) {
 	})
 }
 
+func TestShouldLookLikeOptions(t *testing.T) {
+	expected := solidImage(color.NRGBA{R: 200, A: 255})
+	actual := solidImage(color.NRGBA{R: 200, A: 255})
+	actual.SetNRGBA(3, 3, color.NRGBA{A: 255})
+
+	assert.NotEmpty(t, rendertest.ShouldLookLike(actual, expected))
+	assert.Empty(t, rendertest.ShouldLookLike(actual, expected, rendertest.IgnoreRegions(image.Pt(4, 4), image.Rect(3, 3, 4, 4))))
+}
+
 func TestCompareWithThreshold(t *testing.T) {
 	t.Run("same slices are equal", func(t *testing.T) {
 		lhs := []byte("lol")
@@ -46,7 +46,29 @@ func (ref *TestDataReference) PathExtension(ext string) string {
 	return fmt.Sprintf("testdata/%s/0.%s", *ref, ext)
 }
 
+// Like Path but for the given renderer variant; e.g. 'testdata/foo/0.llvmpipe.png'
+// instead of 'testdata/foo/0.png'.
+func (ref *TestDataReference) VariantPath(variant string, args ...interface{}) string {
+	if variant == "" {
+		return ref.Path(args...)
+	}
+	ref.MustValidate()
+
+	args = append([]interface{}{nil}, args...)
+	testnumber := getTestNumberFromArgs(args)
+	fileExtension := getFileExtensionFromArgs(args)
+	return fmt.Sprintf("testdata/%s/%d.%s.%s", *ref, testnumber, variant, fileExtension)
+}
+
+// Returns the expectation file for the current RendererVariant if there is one
+// and the plain expectation file otherwise.
 func ExpectationFile(testDataKey TestDataReference, fileExt FileExtension, testnumber TestNumber) string {
+	if variant := RendererVariant(); variant != "" {
+		variantFile := testDataKey.VariantPath(variant, fileExt, testnumber)
+		if fileExists(variantFile) {
+			return variantFile
+		}
+	}
 	return testDataKey.Path(fileExt, testnumber)
 }
 
@@ -1,10 +1,14 @@
 package rendertest_test
 
 import (
+	"image/color"
+	"os"
 	"testing"
 
+	"github.com/caffeine-storm/glop/imgmanip"
 	"github.com/caffeine-storm/glop/render/rendertest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExpectationFilePaths(t *testing.T) {
@@ -70,3 +74,51 @@ func TestTestdataReference(t *testing.T) {
 		})
 	})
 }
+
+func TestRendererVariants(t *testing.T) {
+	t.Run("named from GL_RENDERER", func(t *testing.T) {
+		assert.Equal(t, "llvmpipe", rendertest.RendererVariantFor("llvmpipe (LLVM 15.0.7, 256 bits)"))
+		assert.Equal(t, "d3d12", rendertest.RendererVariantFor("D3D12 (NVIDIA GeForce RTX 3070)"))
+		// Hardware renderers share the plain expectation files.
+		assert.Equal(t, "", rendertest.RendererVariantFor("NVIDIA GeForce RTX 3070/PCIe/SSE2"))
+		assert.Equal(t, "", rendertest.RendererVariantFor(""))
+	})
+
+	t.Run("variant paths", func(t *testing.T) {
+		checker := rendertest.NewTestdataReference("checker")
+		assert.Equal(t, "testdata/checker/3.llvmpipe.png", checker.VariantPath("llvmpipe", rendertest.TestNumber(3)))
+		assert.Equal(t, "testdata/checker/0.png", checker.VariantPath(""))
+	})
+
+	t.Run("expectations fall back to the plain file", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		require.NoError(t, os.MkdirAll("testdata/golden", 0755))
+		require.NoError(t, os.WriteFile("testdata/golden/0.png", nil, 0644))
+		require.NoError(t, os.WriteFile("testdata/golden/0.llvmpipe.png", nil, 0644))
+
+		t.Setenv(rendertest.RendererVariantEnvVar, "llvmpipe")
+		assert.Equal(t, "testdata/golden/0.llvmpipe.png", rendertest.ExpectationFile("golden", "png", 0))
+
+		t.Setenv(rendertest.RendererVariantEnvVar, "d3d12")
+		assert.Equal(t, "testdata/golden/0.png", rendertest.ExpectationFile("golden", "png", 0))
+
+		t.Setenv(rendertest.RendererVariantEnvVar, "none")
+		assert.Equal(t, "testdata/golden/0.png", rendertest.ExpectationFile("golden", "png", 0))
+	})
+
+	t.Run("failures name the variant", func(t *testing.T) {
+		t.Setenv(rendertest.RendererVariantEnvVar, "llvmpipe")
+		t.Setenv(rendertest.UpdateGoldensEnvVar, "")
+		withGoldenDir(t, func() {
+			imgmanip.MustDumpImage(solidImage(color.NRGBA{G: 255, A: 255}), "testdata/golden/0.llvmpipe.png")
+
+			green := solidImage(color.NRGBA{G: 255, A: 255})
+			assert.Empty(t, rendertest.ShouldLookLikeFile(green, "golden"))
+
+			result := rendertest.ShouldLookLikeFile(solidImage(color.NRGBA{R: 255, A: 255}), "golden")
+			assert.Contains(t, result, "testdata/golden/0.llvmpipe.png")
+			assert.Contains(t, result, `renderer variant "llvmpipe"`)
+			assert.FileExists(t, "testdata/golden/0.llvmpipe.rej.png")
+		})
+	})
+}
@@ -28,6 +28,11 @@ func (ff *failfast) Queue(job render.RenderJob) {
 	ff.RenderQueueInterface.Queue(job)
 }
 
+func (ff *failfast) QueueInLane(lane render.Lane, job render.RenderJob) *render.JobHandle {
+	ff.checkErrors()
+	return ff.RenderQueueInterface.QueueInLane(lane, job)
+}
+
 func (ff *failfast) Purge() {
 	ff.RenderQueueInterface.Purge()
 	ff.checkErrors()
@@ -40,6 +45,18 @@ func Failfastqueue(ctx *glContext) render.RenderQueueInterface {
 	}
 }
 
+func (ff *failfast) QueueInLaneAttributed(lane render.Lane, source any, job render.RenderJob) *render.JobHandle {
+	ff.checkErrors()
+	if attributed, ok := ff.RenderQueueInterface.(render.RenderQueueWithAttributionInterface); ok {
+		return attributed.QueueInLaneAttributed(lane, source, job)
+	}
+	return ff.RenderQueueInterface.QueueInLane(lane, job)
+}
+
 func (ff *failfast) SetLogger(logger glog.Logger) {
 	ff.RenderQueueInterface.(render.RenderQueueWithLoggerInterface).SetLogger(logger)
 }
+
+func (ff *failfast) SetGlInvariantChecks(enabled bool) {
+	ff.RenderQueueInterface.(render.RenderQueueWithInvariantChecksInterface).SetGlInvariantChecks(enabled)
+}
@@ -0,0 +1,46 @@
+package rendertest
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/caffeine-storm/glop/debug"
+)
+
+// Compares frames from a debug.FrameRecorder against a sequence that
+// FrameRecorder.WritePngSequence wrote to expectedDir. The same frames must be
+// present, by index, and each must match its PNG; the options are the same as
+// for CompareImages and apply to every frame. Timestamps depend on how fast
+// the machine is so they aren't compared.
+func CompareFrames(actual []debug.RecordedFrame, expectedDir string, options ...any) error {
+	manifest, err := debug.ReadFrameManifest(expectedDir)
+	if err != nil {
+		return err
+	}
+
+	if len(actual) != len(manifest.Frames) {
+		return fmt.Errorf("frame count mismatch: got %d, expected %d", len(actual), len(manifest.Frames))
+	}
+
+	errs := []error{}
+	for i, expected := range manifest.Frames {
+		frame := actual[i]
+		if frame.Index != expected.Index {
+			errs = append(errs, fmt.Errorf("frame #%d: got index %d, expected %d", i, frame.Index, expected.Index))
+			continue
+		}
+
+		expectedImage, err := LoadImage(filepath.Join(expectedDir, expected.File))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("frame %d: %w", frame.Index, err))
+			continue
+		}
+		result := CompareImages(frame.Image, expectedImage, options...)
+		if !result.Match {
+			errs = append(errs, fmt.Errorf("frame %d doesn't look like %q: %v", frame.Index, expected.File, result))
+		}
+	}
+
+	return errors.Join(errs...)
+}
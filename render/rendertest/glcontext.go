@@ -8,6 +8,7 @@ import (
 	"github.com/caffeine-storm/gl"
 	"github.com/caffeine-storm/glop/glog"
 	"github.com/caffeine-storm/glop/gos"
+	"github.com/caffeine-storm/glop/gos/headless"
 	"github.com/caffeine-storm/glop/render"
 	"github.com/caffeine-storm/glop/system"
 )
@@ -21,8 +22,8 @@ func (c *conveyIsHalting) Error() string {
 }
 
 func checkAndEnforceInvariants(errorContext string) {
-	err := checkInvariants()
-	enforceInvariants()
+	err := render.CheckGlInvariants()
+	render.EnforceGlInvariants()
 	if err != nil {
 		panic(fmt.Errorf("%s: invariants violated: %w", errorContext, err))
 	}
@@ -275,15 +276,19 @@ func newGlWindowForTest(width, height int) (system.System, system.NativeWindowHa
 		hdl <- sys.CreateWindow(0, 0, width, height)
 
 		sys.EnableVSync(true)
-		err := gl.Init()
-		if err != 0 {
-			panic(fmt.Errorf("couldn't gl.Init: %d", err))
+		err := headless.CheckGlInit(gl.Init())
+		if err != nil {
+			panic(err)
 		}
+		recordRendererVariant()
 		gl.Enable(gl.BLEND)
 		gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
 
 		sys.SwapBuffers()
 	})
+	// Tests leave state behind on purpose; checkAndEnforceInvariants looks
+	// after it between tests instead.
+	renderQueue.(render.RenderQueueWithInvariantChecksInterface).SetGlInvariantChecks(false)
 	renderQueue.AddErrorCallback(func(q render.RenderQueueInterface, e error) {
 		glog.ErrorLogger().Error("test-render-queue.OnError", "err", e)
 	})
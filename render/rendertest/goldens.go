@@ -0,0 +1,70 @@
+package rendertest
+
+import (
+	"errors"
+	"image"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/caffeine-storm/glop/glog"
+	"github.com/caffeine-storm/glop/imgmanip"
+)
+
+// Set this environment variable to "1" to have ShouldLookLikeFile and friends
+// rewrite the expectation files that don't match instead of failing. Review
+// the changed files before committing them!
+const UpdateGoldensEnvVar = "GLOP_UPDATE_GOLDENS"
+
+// Returns true iff the environment asks for expectation files to be updated.
+func UpdatingGoldens() bool {
+	val, found := os.LookupEnv(UpdateGoldensEnvVar)
+	return found && val != "" && val != "0"
+}
+
+// Checks that need an expectation to be different from what was drawn, like
+// ShouldNotLookLikeFile, pass updateGoldens(false) so that they never clobber
+// expectation files.
+type updateGoldens bool
+
+var defaultUpdateGoldens = updateGoldens(true)
+
+func getUpdateGoldensFromArgs(args []interface{}) updateGoldens {
+	var result updateGoldens
+	getFromArgs(args, defaultUpdateGoldens, &result)
+	return result
+}
+
+func shouldUpdateGoldens(args []interface{}) bool {
+	return UpdatingGoldens() && bool(getUpdateGoldensFromArgs(args))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// Picks the file to rewrite when an image doesn't match expectedFileName. An
+// existing variant file is already expectedFileName so it's rewritten in
+// place. A new variant file is only started when RendererVariantEnvVar asks
+// for one; otherwise the plain expectation file is rewritten.
+func goldenToUpdate(testDataKey TestDataReference, testnumber TestNumber, expectedFileName string) string {
+	variant := explicitRendererVariant()
+	if variant == "" || expectedFileName != testDataKey.Path(FileExtension("png"), testnumber) {
+		return expectedFileName
+	}
+	return testDataKey.VariantPath(variant, FileExtension("png"), testnumber)
+}
+
+// Writes the given image as the new expectation and cleans up any rejection
+// file left over from before.
+func updateGolden(actualImage image.Image, expectedFileName string) {
+	err := os.MkdirAll(filepath.Dir(expectedFileName), 0755)
+	if err != nil {
+		panic(err)
+	}
+	imgmanip.MustDumpImage(actualImage, expectedFileName)
+	os.Remove(MakeRejectName(expectedFileName, ".png"))
+
+	glog.WarningLogger().Warn("updated expectation file", "path", expectedFileName)
+}
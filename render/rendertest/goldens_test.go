@@ -0,0 +1,109 @@
+package rendertest_test
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caffeine-storm/glop/imgmanip"
+	"github.com/caffeine-storm/glop/render/rendertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func solidImage(c color.Color) *image.NRGBA {
+	ret := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := range 4 {
+		for x := range 4 {
+			ret.Set(x, y, c)
+		}
+	}
+	return ret
+}
+
+// Runs fn from a fresh directory with testdata/golden/0.png holding a red
+// image.
+func withGoldenDir(t *testing.T, fn func()) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("testdata/golden", 0755))
+	imgmanip.MustDumpImage(solidImage(color.NRGBA{R: 255, A: 255}), "testdata/golden/0.png")
+	fn()
+}
+
+func TestUpdatingGoldens(t *testing.T) {
+	blue := solidImage(color.NRGBA{B: 255, A: 255})
+
+	t.Run("mismatches fail without the env var", func(t *testing.T) {
+		t.Setenv(rendertest.UpdateGoldensEnvVar, "")
+		withGoldenDir(t, func() {
+			assert.NotEmpty(t, rendertest.ShouldLookLikeFile(blue, "golden"))
+			assert.FileExists(t, "testdata/golden/0.rej.png")
+		})
+	})
+
+	t.Run("mismatches rewrite the expectation", func(t *testing.T) {
+		t.Setenv(rendertest.UpdateGoldensEnvVar, "1")
+		t.Setenv(rendertest.RendererVariantEnvVar, "none")
+		withGoldenDir(t, func() {
+			imgmanip.MustDumpImage(blue, "testdata/golden/0.rej.png")
+
+			assert.Empty(t, rendertest.ShouldLookLikeFile(blue, "golden"))
+			assert.NoFileExists(t, "testdata/golden/0.rej.png")
+
+			t.Setenv(rendertest.UpdateGoldensEnvVar, "0")
+			assert.Empty(t, rendertest.ShouldLookLikeFile(blue, "golden"))
+		})
+	})
+
+	t.Run("detected renderers don't start new variant files", func(t *testing.T) {
+		t.Setenv(rendertest.UpdateGoldensEnvVar, "1")
+		t.Setenv(rendertest.RendererVariantEnvVar, "")
+		withGoldenDir(t, func() {
+			assert.Empty(t, rendertest.ShouldLookLikeFile(blue, "golden"))
+
+			matches, err := filepath.Glob("testdata/golden/*")
+			require.NoError(t, err)
+			assert.Equal(t, []string{filepath.Join("testdata", "golden", "0.png")}, matches)
+		})
+	})
+
+	t.Run("mismatches go to the variant file named by the env var", func(t *testing.T) {
+		t.Setenv(rendertest.UpdateGoldensEnvVar, "1")
+		t.Setenv(rendertest.RendererVariantEnvVar, "llvmpipe")
+		withGoldenDir(t, func() {
+			imgmanip.MustDumpImage(blue, "testdata/golden/0.rej.png")
+
+			assert.Empty(t, rendertest.ShouldLookLikeFile(blue, "golden"))
+			assert.FileExists(t, "testdata/golden/0.llvmpipe.png")
+			assert.NoFileExists(t, "testdata/golden/0.rej.png")
+
+			// Other renderers still see the shared expectation.
+			t.Setenv(rendertest.UpdateGoldensEnvVar, "0")
+			red := solidImage(color.NRGBA{R: 255, A: 255})
+			t.Setenv(rendertest.RendererVariantEnvVar, "d3d12")
+			assert.Empty(t, rendertest.ShouldLookLikeFile(red, "golden"))
+			t.Setenv(rendertest.RendererVariantEnvVar, "llvmpipe")
+			assert.Empty(t, rendertest.ShouldLookLikeFile(blue, "golden"))
+		})
+	})
+
+	t.Run("missing expectations are created", func(t *testing.T) {
+		t.Setenv(rendertest.UpdateGoldensEnvVar, "1")
+		withGoldenDir(t, func() {
+			assert.Empty(t, rendertest.ShouldLookLikeFile(blue, "brand-new", rendertest.TestNumber(2)))
+			assert.FileExists(t, filepath.Join("testdata", "brand-new", "2.png"))
+		})
+	})
+
+	t.Run("negative checks never update", func(t *testing.T) {
+		t.Setenv(rendertest.UpdateGoldensEnvVar, "1")
+		withGoldenDir(t, func() {
+			assert.Empty(t, rendertest.ShouldNotLookLikeFile(blue, "golden"))
+
+			t.Setenv(rendertest.UpdateGoldensEnvVar, "0")
+			assert.NotEmpty(t, rendertest.ShouldLookLikeFile(blue, "golden", rendertest.MakeRejectFiles(false)))
+		})
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"image"
 	"io"
-	"os"
 
 	"github.com/caffeine-storm/glop/imgmanip"
 )
@@ -13,14 +12,16 @@ func MustLoadTestImage(testdataref TestDataReference) image.Image {
 	return MustLoadImage(testdataref.Path())
 }
 
+func LoadImage(imageFilePath string) (image.Image, error) {
+	return imgmanip.LoadImage(imageFilePath)
+}
+
 func MustLoadImage(imageFilePath string) image.Image {
-	file, err := os.Open(imageFilePath)
+	img, err := LoadImage(imageFilePath)
 	if err != nil {
-		panic(fmt.Errorf("couldn't open file %q: %w", imageFilePath, err))
+		panic(err)
 	}
-	defer file.Close()
-
-	return MustLoadImageFromReader(file)
+	return img
 }
 
 func MustLoadImageNRGBA(imageFilePath string) *image.NRGBA {
@@ -0,0 +1,52 @@
+package rendertest
+
+import (
+	"image"
+
+	"github.com/caffeine-storm/glop/render/rendertest/imgcmp"
+)
+
+// The comparison options and reports live in imgcmp so that tools can use them
+// without a GL context; they're re-exported here for use with
+// ShouldLookLikeFile and friends.
+type (
+	Threshold              = imgcmp.Threshold
+	BackgroundColour       = imgcmp.BackgroundColour
+	ChannelTolerance       = imgcmp.ChannelTolerance
+	MaxDifferingPixelRatio = imgcmp.MaxDifferingPixelRatio
+	MinSSIM                = imgcmp.MinSSIM
+	IgnoreMask             = imgcmp.IgnoreMask
+	ComparisonResult       = imgcmp.ComparisonResult
+	Rejection              = imgcmp.Rejection
+)
+
+const RejectionReportFile = imgcmp.RejectionReportFile
+
+// DefaultBackground is an opaque black
+var DefaultBackground = imgcmp.DefaultBackground
+
+func CompareImages(actual, expected image.Image, options ...any) ComparisonResult {
+	return imgcmp.CompareImages(actual, expected, options...)
+}
+
+func IgnoreRegions(size image.Point, regions ...image.Rectangle) IgnoreMask {
+	return imgcmp.IgnoreRegions(size, regions...)
+}
+
+func ComputeImageDifference(lhs, rhs []byte) []byte {
+	return imgcmp.ComputeImageDifference(lhs, rhs)
+}
+
+func FindRejections(root string) ([]Rejection, error) {
+	return imgcmp.FindRejections(root)
+}
+
+func WriteRejectionReport(rejections []Rejection, outDir string) error {
+	return imgcmp.WriteRejectionReport(rejections, outDir)
+}
+
+// Like CompareImages but 'args' are the trailing arguments to a 'convey.So'
+// call so the first one is skipped.
+func compareImages(actual, expected image.Image, args []any) ComparisonResult {
+	return imgcmp.CompareImages(actual, expected, args[1:]...)
+}
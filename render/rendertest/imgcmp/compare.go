@@ -0,0 +1,160 @@
+package imgcmp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/caffeine-storm/glop/glog"
+	"github.com/caffeine-storm/glop/imgmanip"
+)
+
+// The outcome of comparing two images with CompareImages.
+type ComparisonResult struct {
+	Match bool
+
+	// The number of pixels that weren't masked off by an IgnoreMask.
+	Compared int
+
+	// The number of compared pixels with a channel outside of the tolerance.
+	Differing int
+
+	// Only set when a MinSSIM was given.
+	SSIM float64
+
+	// Set when the images weren't the same size; nothing else was compared.
+	SizeMismatch bool
+}
+
+func (r ComparisonResult) String() string {
+	if r.SizeMismatch {
+		return "size mismatch"
+	}
+	ret := fmt.Sprintf("%d of %d pixels differ", r.Differing, r.Compared)
+	if r.SSIM != 0 {
+		ret += fmt.Sprintf(", SSIM %.4f", r.SSIM)
+	}
+	return ret
+}
+
+// Compares the actual image against the expected one. The options are any of
+// Threshold, ChannelTolerance, MaxDifferingPixelRatio, MinSSIM, IgnoreMask and
+// BackgroundColour; anything else is ignored. Both
+// images are composed over the background before comparing; it defaults to
+// DefaultBackground.
+//
+// By default, every pixel must be within the tolerance. Given a
+// MaxDifferingPixelRatio, that fraction of pixels may be off by any amount.
+// Given a MinSSIM, only the structural similarity is considered.
+func CompareImages(actual, expected image.Image, options ...any) ComparisonResult {
+	// Do a size check first so that we don't read out of bounds.
+	if actual.Bounds().Size() != expected.Bounds().Size() {
+		glog.ErrorLogger().Error("size mismatch", "actual", actual.Bounds(), "expected", expected.Bounds())
+		return ComparisonResult{SizeMismatch: true}
+	}
+
+	bg := getBackground(options)
+	expectedNrgba := imgmanip.DrawAsNrgbaWithBackground(expected, bg)
+	actualNrgba := imgmanip.DrawAsNrgbaWithBackground(actual, bg)
+
+	ignored := ignoredPixels(expectedNrgba.Bounds(), options)
+	if ignored != nil {
+		// Masked pixels take the expected value so that they can't contribute to
+		// any of the metrics below.
+		for i, skip := range ignored {
+			if skip {
+				copy(actualNrgba.Pix[4*i:4*i+4], expectedNrgba.Pix[4*i:4*i+4])
+			}
+		}
+	}
+
+	tolerance := getChannelTolerance(options)
+	ret := ComparisonResult{}
+	for i := 0; i < len(expectedNrgba.Pix); i += 4 {
+		if ignored != nil && ignored[i/4] {
+			continue
+		}
+		ret.Compared++
+		if !withinTolerance(actualNrgba.Pix[i:i+4], expectedNrgba.Pix[i:i+4], tolerance) {
+			ret.Differing++
+		}
+	}
+
+	if minSSIM, found := getMinSSIM(options); found {
+		ret.SSIM = imgmanip.SSIM(actualNrgba, expectedNrgba)
+		ret.Match = ret.SSIM >= float64(minSSIM)
+		return ret
+	}
+
+	maxRatio := getMaxDifferingPixelRatio(options)
+	ret.Match = float64(ret.Differing) <= float64(maxRatio)*float64(ret.Compared)
+	return ret
+}
+
+func withinTolerance(lhs, rhs []byte, tolerance ChannelTolerance) bool {
+	for c := range 4 {
+		diff := int(lhs[c]) - int(rhs[c])
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > int(tolerance[c]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns, in row-major order, whether each pixel within bounds is masked off
+// by an IgnoreMask. Returns nil if there's no mask.
+func ignoredPixels(bounds image.Rectangle, options []any) []bool {
+	mask, found := getIgnoreMask(options)
+	if !found {
+		return nil
+	}
+
+	maskBounds := mask.Image.Bounds()
+	if maskBounds.Size() != bounds.Size() {
+		panic(fmt.Errorf("IgnoreMask is %v but the images are %v", maskBounds.Size(), bounds.Size()))
+	}
+
+	ret := make([]bool, 0, bounds.Dx()*bounds.Dy())
+	for y := range bounds.Dy() {
+		for x := range bounds.Dx() {
+			_, _, _, a := mask.Image.At(maskBounds.Min.X+x, maskBounds.Min.Y+y).RGBA()
+			ret = append(ret, a != 0)
+		}
+	}
+	return ret
+}
+
+// Makes an IgnoreMask out of the given rectangles for images of the given
+// size; handy when the volatile regions are known ahead of time.
+func IgnoreRegions(size image.Point, regions ...image.Rectangle) IgnoreMask {
+	mask := image.NewAlpha(image.Rectangle{Max: size})
+	for _, region := range regions {
+		region = region.Intersect(mask.Rect)
+		for y := region.Min.Y; y < region.Max.Y; y++ {
+			for x := region.Min.X; x < region.Max.X; x++ {
+				mask.SetAlpha(x, y, color.Alpha{A: 255})
+			}
+		}
+	}
+	return IgnoreMask{Image: mask}
+}
+
+func ComputeImageDifference(lhs, rhs []byte) []byte {
+	if len(lhs) != len(rhs) {
+		panic(fmt.Errorf("need same-sized slices but got %d and %d", len(lhs), len(rhs)))
+	}
+
+	ret := make([]byte, len(lhs))
+	for i := range lhs {
+		diff := int(lhs[i]) - int(rhs[i])
+		absdiff := diff
+		if absdiff < 0 {
+			absdiff = -absdiff
+		}
+		ret[i] = byte(absdiff)
+	}
+	return ret
+}
@@ -0,0 +1,106 @@
+package imgcmp_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/caffeine-storm/glop/render/rendertest/imgcmp"
+	"github.com/stretchr/testify/assert"
+)
+
+// A 32x32 image with a dark square in the middle of a light background.
+func squareImage() *image.NRGBA {
+	ret := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := range 32 {
+		for x := range 32 {
+			c := color.NRGBA{R: 200, G: 200, B: 200, A: 255}
+			if x >= 8 && x < 24 && y >= 8 && y < 24 {
+				c = color.NRGBA{R: 20, G: 20, B: 20, A: 255}
+			}
+			ret.SetNRGBA(x, y, c)
+		}
+	}
+	return ret
+}
+
+func TestCompareImages(t *testing.T) {
+	expected := squareImage()
+
+	t.Run("identical images match", func(t *testing.T) {
+		result := imgcmp.CompareImages(squareImage(), expected)
+		assert.True(t, result.Match)
+		assert.Equal(t, 32*32, result.Compared)
+		assert.Equal(t, 0, result.Differing)
+	})
+
+	t.Run("size mismatches don't match", func(t *testing.T) {
+		result := imgcmp.CompareImages(image.NewNRGBA(image.Rect(0, 0, 2, 2)), expected)
+		assert.False(t, result.Match)
+		assert.True(t, result.SizeMismatch)
+	})
+
+	t.Run("per-channel tolerances", func(t *testing.T) {
+		actual := squareImage()
+		actual.SetNRGBA(0, 0, color.NRGBA{R: 200, G: 200, B: 210, A: 255})
+
+		assert.False(t, imgcmp.CompareImages(actual, expected).Match)
+		assert.False(t, imgcmp.CompareImages(actual, expected, imgcmp.ChannelTolerance{10, 10, 9, 0}).Match)
+		assert.True(t, imgcmp.CompareImages(actual, expected, imgcmp.ChannelTolerance{0, 0, 10, 0}).Match)
+	})
+
+	t.Run("differing pixel ratio", func(t *testing.T) {
+		actual := squareImage()
+		for x := range 10 {
+			actual.SetNRGBA(x, 0, color.NRGBA{R: 255, A: 255})
+		}
+
+		result := imgcmp.CompareImages(actual, expected, imgcmp.MaxDifferingPixelRatio(0.01))
+		assert.True(t, result.Match)
+		assert.Equal(t, 10, result.Differing)
+
+		assert.False(t, imgcmp.CompareImages(actual, expected, imgcmp.MaxDifferingPixelRatio(0.005)).Match)
+	})
+
+	t.Run("masked pixels are ignored", func(t *testing.T) {
+		actual := squareImage()
+		for y := range 4 {
+			for x := range 8 {
+				actual.SetNRGBA(x, y, color.NRGBA{G: 255, A: 255})
+			}
+		}
+
+		assert.False(t, imgcmp.CompareImages(actual, expected).Match)
+
+		mask := imgcmp.IgnoreRegions(image.Pt(32, 32), image.Rect(0, 0, 8, 4))
+		result := imgcmp.CompareImages(actual, expected, mask)
+		assert.True(t, result.Match)
+		assert.Equal(t, 32*32-8*4, result.Compared)
+	})
+
+	t.Run("masks must match the image size", func(t *testing.T) {
+		mask := imgcmp.IgnoreRegions(image.Pt(4, 4))
+		assert.Panics(t, func() {
+			imgcmp.CompareImages(squareImage(), expected, mask)
+		})
+	})
+
+	t.Run("SSIM tolerates noise but not moved edges", func(t *testing.T) {
+		noisy := squareImage()
+		for i := 0; i < len(noisy.Pix); i += 4 {
+			if (i/4)%3 == 0 {
+				noisy.Pix[i] += 4
+				noisy.Pix[i+1] -= 4
+			}
+		}
+		result := imgcmp.CompareImages(noisy, expected, imgcmp.MinSSIM(0.95))
+		assert.True(t, result.Match, "SSIM was %v", result.SSIM)
+		assert.NotZero(t, result.Differing)
+
+		moved := image.NewNRGBA(expected.Rect)
+		copy(moved.Pix, expected.Pix[4*4:])
+		result = imgcmp.CompareImages(moved, expected, imgcmp.MinSSIM(0.95))
+		assert.False(t, result.Match, "SSIM was %v", result.SSIM)
+	})
+
+}
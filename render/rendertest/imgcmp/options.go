@@ -0,0 +1,111 @@
+// Package imgcmp compares images the way rendertest's ShouldLookLikeFile does
+// and reports on the rejection files it leaves behind. Unlike rendertest, it
+// doesn't need a GL context so command line tools can use it too.
+package imgcmp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"reflect"
+)
+
+type (
+	Threshold        uint8
+	BackgroundColour color.Color
+
+	// Per-channel (red, green, blue, alpha) versions of Threshold. When given,
+	// it's used instead of any Threshold.
+	ChannelTolerance [4]uint8
+
+	// The fraction of compared pixels, in [0, 1], that may be outside of the
+	// tolerance without failing the comparison. Defaults to 0.
+	MaxDifferingPixelRatio float64
+
+	// Compare images by their mean structural similarity (see imgmanip.SSIM)
+	// instead of pixel by pixel; the comparison passes if the SSIM is at least
+	// this value. Something like 0.98 ignores antialiasing differences but not
+	// moved or missing edges.
+	MinSSIM float64
+
+	// Pixels where this image isn't fully transparent are left out of the
+	// comparison. Use it to mask off regions that change from run to run, like
+	// a FrameRateWidget. The mask must be the same size as the expectation.
+	IgnoreMask struct {
+		Image image.Image
+	}
+)
+
+var DefaultThreshold = Threshold(3)
+
+// DefaultBackground is an opaque black
+var DefaultBackground BackgroundColour = color.RGBA{
+	R: 0,
+	G: 0,
+	B: 0,
+	A: 255,
+}
+
+var (
+	defaultMaxDifferingPixelRatio = MaxDifferingPixelRatio(0)
+	defaultMinSSIM                = MinSSIM(1)
+)
+
+// Looks through 'options' for a value with the same type as 'defaultValue'. If
+// found, assigns it to the pointer wrapped in 'output', otherwise, assigns
+// 'defaultValue' to it. Returns true iff the value was found in 'options'.
+func getFromOptions(options []any, defaultValue any, output any) bool {
+	defaultReflectValue := reflect.ValueOf(defaultValue)
+	targetType := defaultReflectValue.Type()
+	outPtr := reflect.ValueOf(output).Elem()
+
+	for _, option := range options {
+		val := reflect.ValueOf(option)
+		if val.IsValid() && val.Type() == targetType {
+			outPtr.Set(val)
+			return true
+		}
+	}
+
+	outPtr.Set(defaultReflectValue)
+	return false
+}
+
+func getBackground(options []any) BackgroundColour {
+	var result BackgroundColour
+	getFromOptions(options, DefaultBackground, &result)
+	return result
+}
+
+func getChannelTolerance(options []any) ChannelTolerance {
+	var thresh Threshold
+	getFromOptions(options, DefaultThreshold, &thresh)
+	t := uint8(thresh)
+	var result ChannelTolerance
+	getFromOptions(options, ChannelTolerance{t, t, t, t}, &result)
+	return result
+}
+
+func getMaxDifferingPixelRatio(options []any) MaxDifferingPixelRatio {
+	var result MaxDifferingPixelRatio
+	getFromOptions(options, defaultMaxDifferingPixelRatio, &result)
+	if result < 0 || result > 1 {
+		panic(fmt.Errorf("MaxDifferingPixelRatio must be in [0, 1]; got %v", float64(result)))
+	}
+	return result
+}
+
+func getMinSSIM(options []any) (MinSSIM, bool) {
+	var result MinSSIM
+	found := getFromOptions(options, defaultMinSSIM, &result)
+	if result < -1 || result > 1 {
+		panic(fmt.Errorf("MinSSIM must be in [-1, 1]; got %v", float64(result)))
+	}
+	return result, found
+}
+
+func getIgnoreMask(options []any) (IgnoreMask, bool) {
+	var result IgnoreMask
+	found := getFromOptions(options, IgnoreMask{}, &result)
+	return result, found && result.Image != nil
+}
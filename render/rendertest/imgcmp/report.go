@@ -0,0 +1,181 @@
+package imgcmp
+
+import (
+	"fmt"
+	"html/template"
+	"image"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/caffeine-storm/glop/imgmanip"
+)
+
+// A rejection file left by a failed ShouldLookLikeFile and the expectation it
+// was compared against.
+type Rejection struct {
+	Expected string
+	Actual   string
+}
+
+// Walks root looking for rejection files with a matching expectation file.
+// Rejections are returned in lexical order of their paths.
+func FindRejections(root string) ([]Rejection, error) {
+	var ret []Rejection
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		base, ok := strings.CutSuffix(path, ".rej.png")
+		if d.IsDir() || !ok {
+			return nil
+		}
+
+		expected := base + ".png"
+		if _, err := os.Stat(expected); err != nil {
+			return nil
+		}
+		ret = append(ret, Rejection{
+			Expected: expected,
+			Actual:   path,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("FindRejections: %w", err)
+	}
+	return ret, nil
+}
+
+func loadNrgba(path string) (*image.NRGBA, error) {
+	img, err := imgmanip.LoadImage(path)
+	if err != nil {
+		return nil, err
+	}
+	return imgmanip.ToNRGBA(img), nil
+}
+
+// Returns an opaque image of the per-channel absolute differences between the
+// given images and a count of the pixels that differ at all. Returns nil if
+// the images aren't the same size.
+func differenceImage(expected, actual *image.NRGBA) (*image.NRGBA, int) {
+	if expected.Bounds() != actual.Bounds() {
+		return nil, 0
+	}
+
+	ret := image.NewNRGBA(expected.Bounds())
+	ret.Pix = ComputeImageDifference(expected.Pix, actual.Pix)
+
+	differing := 0
+	for i := 0; i < len(ret.Pix); i += 4 {
+		if ret.Pix[i] != 0 || ret.Pix[i+1] != 0 || ret.Pix[i+2] != 0 || ret.Pix[i+3] != 0 {
+			differing++
+		}
+		// Alpha differences are folded into the colour so the diff stays
+		// visible.
+		ret.Pix[i] = max(ret.Pix[i], ret.Pix[i+3])
+		ret.Pix[i+3] = 255
+	}
+	return ret, differing
+}
+
+type reportEntry struct {
+	Name       string
+	Expected   string
+	Actual     string
+	Diff       string
+	Size       image.Point
+	ActualSize image.Point
+	Differing  int
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Rendering rejections</title>
+<style>
+body { font-family: sans-serif; background: #444; color: #eee; }
+img { image-rendering: pixelated; min-width: 128px; background: repeating-conic-gradient(#888 0% 25%, #aaa 0% 50%) 0 0 / 16px 16px; }
+td { vertical-align: top; padding: 4px; }
+</style>
+</head>
+<body>
+<h1>{{len .}} rejection(s)</h1>
+{{range .}}
+<h2>{{.Name}}</h2>
+{{if .Diff}}<p>{{.Differing}} of {{.Size.X}}x{{.Size.Y}} pixels differ.</p>
+{{else}}<p>Size mismatch: expected {{.Size.X}}x{{.Size.Y}} but got {{.ActualSize.X}}x{{.ActualSize.Y}}.</p>
+{{end}}
+<table><tr><th>expected</th><th>actual</th><th>difference</th></tr>
+<tr>
+<td><img src="{{.Expected}}"></td>
+<td><img src="{{.Actual}}"></td>
+<td>{{if .Diff}}<img src="{{.Diff}}">{{end}}</td>
+</tr></table>
+{{end}}
+</body>
+</html>
+`))
+
+// The page that WriteRejectionReport writes.
+const RejectionReportFile = "index.html"
+
+// Writes a single HTML page to outDir that shows each rejection's expected,
+// actual and difference images side by side. The images are copied into
+// outDir so the report can be moved around or attached to a review.
+func WriteRejectionReport(rejections []Rejection, outDir string) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("WriteRejectionReport: %w", err)
+	}
+
+	entries := make([]reportEntry, len(rejections))
+	for i, rej := range rejections {
+		expected, err := loadNrgba(rej.Expected)
+		if err != nil {
+			return fmt.Errorf("WriteRejectionReport: %w", err)
+		}
+		actual, err := loadNrgba(rej.Actual)
+		if err != nil {
+			return fmt.Errorf("WriteRejectionReport: %w", err)
+		}
+
+		entry := reportEntry{
+			Name:       rej.Expected,
+			Expected:   fmt.Sprintf("%03d-expected.png", i),
+			Actual:     fmt.Sprintf("%03d-actual.png", i),
+			Size:       expected.Bounds().Size(),
+			ActualSize: actual.Bounds().Size(),
+		}
+		images := map[string]image.Image{
+			entry.Expected: expected,
+			entry.Actual:   actual,
+		}
+
+		diff, differing := differenceImage(expected, actual)
+		if diff != nil {
+			entry.Diff = fmt.Sprintf("%03d-diff.png", i)
+			entry.Differing = differing
+			images[entry.Diff] = diff
+		}
+
+		for name, img := range images {
+			if err := imgmanip.DumpImage(img, filepath.Join(outDir, name)); err != nil {
+				return fmt.Errorf("WriteRejectionReport: %w", err)
+			}
+		}
+		entries[i] = entry
+	}
+
+	page, err := os.Create(filepath.Join(outDir, RejectionReportFile))
+	if err != nil {
+		return fmt.Errorf("WriteRejectionReport: %w", err)
+	}
+	defer page.Close()
+
+	if err := reportTemplate.Execute(page, entries); err != nil {
+		return fmt.Errorf("WriteRejectionReport: %w", err)
+	}
+	return page.Close()
+}
@@ -0,0 +1,66 @@
+package imgcmp_test
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caffeine-storm/glop/imgmanip"
+	"github.com/caffeine-storm/glop/render/rendertest/imgcmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func solidImage(c color.Color) *image.NRGBA {
+	ret := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := range 4 {
+		for x := range 4 {
+			ret.Set(x, y, c)
+		}
+	}
+	return ret
+}
+
+func loadNrgba(t *testing.T, path string) *image.NRGBA {
+	img, err := imgmanip.LoadImage(path)
+	require.NoError(t, err)
+	return imgmanip.ToNRGBA(img)
+}
+
+func TestRejectionReport(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "testdata", "golden")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	expected := solidImage(color.NRGBA{R: 255, A: 255})
+	actual := solidImage(color.NRGBA{R: 255, A: 255})
+	actual.Set(1, 2, color.NRGBA{R: 200, A: 255})
+	imgmanip.MustDumpImage(expected, filepath.Join(dir, "0.png"))
+	imgmanip.MustDumpImage(actual, filepath.Join(dir, "0.rej.png"))
+	// A rejection without an expectation isn't reported.
+	imgmanip.MustDumpImage(actual, filepath.Join(dir, "1.rej.png"))
+
+	rejections, err := imgcmp.FindRejections(root)
+	require.NoError(t, err)
+	require.Equal(t, []imgcmp.Rejection{{
+		Expected: filepath.Join(dir, "0.png"),
+		Actual:   filepath.Join(dir, "0.rej.png"),
+	}}, rejections)
+
+	outDir := filepath.Join(root, "report")
+	require.NoError(t, imgcmp.WriteRejectionReport(rejections, outDir))
+
+	page, err := os.ReadFile(filepath.Join(outDir, imgcmp.RejectionReportFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "0.png")
+	assert.Contains(t, string(page), "1 of 4x4 pixels differ")
+
+	for _, name := range []string{"000-expected.png", "000-actual.png", "000-diff.png"} {
+		assert.FileExists(t, filepath.Join(outDir, name))
+	}
+	diff := loadNrgba(t, filepath.Join(outDir, "000-diff.png"))
+	assert.Equal(t, color.NRGBA{R: 55, A: 255}, diff.NRGBAAt(1, 2))
+	assert.Equal(t, color.NRGBA{A: 255}, diff.NRGBAAt(0, 0))
+}
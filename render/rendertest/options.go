@@ -2,15 +2,14 @@ package rendertest
 
 import (
 	"fmt"
-	"image/color"
 	"reflect"
+
+	"github.com/caffeine-storm/glop/render/rendertest/imgcmp"
 )
 
 type (
 	TestNumber        uint8
 	FileExtension     string
-	Threshold         uint8
-	BackgroundColour  color.Color
 	MakeRejectFiles   bool
 	DebugDumpFilePath string
 )
@@ -18,18 +17,10 @@ type (
 var (
 	defaultTestNumber        = TestNumber(0)
 	defaultFileExtension     = FileExtension("png")
-	defaultThreshold         = Threshold(3)
+	defaultThreshold         = imgcmp.DefaultThreshold
 	defaultDebugDumpFilePath = DebugDumpFilePath("/dev/null")
 )
 
-// DefaultBackground is an opaque black
-var DefaultBackground BackgroundColour = color.RGBA{
-	R: 0,
-	G: 0,
-	B: 0,
-	A: 255,
-}
-
 var defaultMakeRejectFiles = MakeRejectFiles(true)
 
 // For the given slice of trailing arguments to a 'convey.So' call, look for a
@@ -22,12 +22,23 @@ func (*PanicQueueShouldNotBeCalledError) Error() string {
 func (*panicQueue) Queue(job render.RenderJob) {
 	panic(&PanicQueueShouldNotBeCalledError{})
 }
+
+func (*panicQueue) QueueInLane(render.Lane, render.RenderJob) *render.JobHandle {
+	panic(&PanicQueueShouldNotBeCalledError{})
+}
+
 func (*panicQueue) Purge()           {}
 func (*panicQueue) StartProcessing() {}
 func (*panicQueue) StopProcessing()  {}
 func (*panicQueue) IsDefunct() bool  { return false } // Look like a regular queue even though we'll panic
 func (*panicQueue) IsPurging() bool  { return false }
 
+// Timing and frames are no-ops; no jobs will ever run.
+func (*panicQueue) AddTimingListener(*render.JobTimingListener) {}
+func (*panicQueue) BeginFrame()                                 {}
+func (*panicQueue) EndFrame()                                   {}
+func (*panicQueue) RecentFrames() []render.FrameStats           { return nil }
+
 func MakePanicingRenderQueue() render.RenderQueueInterface {
 	return &panicQueue{}
 }
@@ -0,0 +1,74 @@
+package rendertest
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/caffeine-storm/gl"
+)
+
+// Overrides the renderer variant that expectation files are resolved against;
+// handy for generating a variant's expectations on another machine. Set it to
+// "none" to only use the plain expectation files.
+const RendererVariantEnvVar = "GLOP_RENDERER_VARIANT"
+
+var (
+	rendererVariantMutex sync.Mutex
+	rendererVariant      string
+)
+
+// Renderers whose output differs enough to need their own expectations. The
+// first one found in the GL_RENDERER string wins.
+var knownRenderers = []string{
+	"llvmpipe",
+	"softpipe",
+	"swrast",
+	"d3d12",
+}
+
+// Names the expectation file variant to use for the given GL_RENDERER string;
+// one of knownRenderers. Returns "" for every other renderer so that they
+// share the plain expectation files.
+func RendererVariantFor(renderer string) string {
+	lowered := strings.ToLower(renderer)
+	for _, known := range knownRenderers {
+		if strings.Contains(lowered, known) {
+			return known
+		}
+	}
+	return ""
+}
+
+// Remembers the current context's renderer for resolving expectation files.
+// Must be called on the render thread with a current context.
+func recordRendererVariant() {
+	variant := RendererVariantFor(gl.GetString(gl.RENDERER))
+
+	rendererVariantMutex.Lock()
+	defer rendererVariantMutex.Unlock()
+	rendererVariant = variant
+}
+
+// Returns the variant that expectation files are resolved against: the value
+// of RendererVariantEnvVar if it's set, otherwise the renderer of the most
+// recently created test context. Returns "" if neither is available.
+func RendererVariant() string {
+	if os.Getenv(RendererVariantEnvVar) != "" {
+		return explicitRendererVariant()
+	}
+
+	rendererVariantMutex.Lock()
+	defer rendererVariantMutex.Unlock()
+	return rendererVariant
+}
+
+// Returns the variant named by RendererVariantEnvVar, or "" if it's unset or
+// "none".
+func explicitRendererVariant() string {
+	val := os.Getenv(RendererVariantEnvVar)
+	if val == "none" {
+		return ""
+	}
+	return val
+}
@@ -2,21 +2,26 @@ package rendertest
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+	"testing"
 
+	"github.com/caffeine-storm/gl"
 	"github.com/caffeine-storm/glop/render"
 )
 
-const shaderProgName = "debugshaders"
+// The name that WithShaderProgs registers its shaders under.
+const DebugShaderName = "debugshaders"
 
 func WithShaderProgs(shaders *render.ShaderBank, vertShader string, fragShader string, fn func()) {
-	if !shaders.HasShader(shaderProgName) {
-		err := shaders.RegisterShader(shaderProgName, vertShader, fragShader)
+	if !shaders.HasShader(DebugShaderName) {
+		err := shaders.RegisterShader(DebugShaderName, vertShader, fragShader)
 		if err != nil {
 			panic(fmt.Errorf("couldn't register debug shaders: %w", err))
 		}
 	}
 
-	err := shaders.EnableShader(shaderProgName)
+	err := shaders.EnableShader(DebugShaderName)
 	if err != nil {
 		panic(fmt.Errorf("couldn't enable debug shaders: %w", err))
 	}
@@ -26,3 +31,48 @@ func WithShaderProgs(shaders *render.ShaderBank, vertShader string, fragShader s
 	}()
 	fn()
 }
+
+// Returns an error listing each of the expected uniforms that the named shader
+// doesn't expose with the expected GL type. Must be called on the render
+// thread.
+func CheckShaderUniforms(shaders *render.ShaderBank, shader string, expected map[string]gl.GLenum) error {
+	desc, err := shaders.Describe(shader)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for name, glType := range expected {
+		uniform, found := desc.Uniform(name)
+		if !found {
+			problems = append(problems, fmt.Sprintf("no active uniform %q", name))
+			continue
+		}
+		if uniform.Type != glType {
+			wanted := render.ShaderVariable{Type: glType}
+			problems = append(problems, fmt.Sprintf("uniform %q is a %s, not a %s", name, uniform.TypeName(), wanted.TypeName()))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	slices.Sort(problems)
+	return fmt.Errorf("shader %q: %s; it has %v", shader, strings.Join(problems, ", "), desc.Uniforms)
+}
+
+// Fails the test unless CheckShaderUniforms passes for the given queue's
+// ShaderBank. Must not be called on the render thread.
+func MustExposeUniforms(t *testing.T, queue render.RenderQueueInterface, shader string, expected map[string]gl.GLenum) {
+	t.Helper()
+
+	var err error
+	queue.Queue(func(st render.RenderQueueState) {
+		err = CheckShaderUniforms(st.Shaders(), shader, expected)
+	})
+	queue.Purge()
+
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+}
@@ -36,6 +36,18 @@ func (*stubbedQueue) IsPurging() bool {
 	return false
 }
 
+// Jobs never run on a stubbedQueue so each handle stays 'pending' forever.
+func (*stubbedQueue) QueueInLane(render.Lane, render.RenderJob) *render.JobHandle {
+	return &render.JobHandle{}
+}
+
+func (*stubbedQueue) AddTimingListener(*render.JobTimingListener) {}
+func (*stubbedQueue) BeginFrame()                                 {}
+func (*stubbedQueue) EndFrame()                                   {}
+func (*stubbedQueue) RecentFrames() []render.FrameStats {
+	return nil
+}
+
 func MakeStubbedRenderQueue() render.RenderQueueInterface {
 	return &stubbedQueue{}
 }
@@ -108,7 +108,9 @@ func DrawTexturedQuad(pixelBounds image.Rectangle, tex gl.Texture, shaders *rend
 	// - enable texturing shaders
 	WithShaderProgs(shaders, debug_vertex_shader, debug_fragment_shader, func() {
 		// - set shader variables/inputs; we want to use the 0'th texture unit.
-		shaders.SetUniformI("debugshaders", "tex", 0)
+		if err := shaders.SetUniformI(DebugShaderName, "tex", 0); err != nil {
+			panic(fmt.Errorf("DrawTexturedQuad: %w", err))
+		}
 
 		// - define geometry
 		verts := []int32{
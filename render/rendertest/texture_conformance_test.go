@@ -0,0 +1,167 @@
+package rendertest_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/debug"
+	"github.com/caffeine-storm/glop/imgmanip"
+	"github.com/caffeine-storm/glop/render/rendertest"
+	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a copy of pix with its rows in the reverse order; GL wants the
+// bottom row first.
+func flippedRows(pix []byte, stride int) []byte {
+	ret := make([]byte, 0, len(pix))
+	for start := len(pix) - stride; start >= 0; start -= stride {
+		ret = append(ret, pix[start:start+stride]...)
+	}
+	return ret
+}
+
+func uploadConformanceTexture(internalFormat, format, pixelType gl.GLenum, bounds image.Rectangle, pix []byte) gl.Texture {
+	tex := gl.GenTexture()
+	tex.Bind(gl.TEXTURE_2D)
+	defer gl.Texture(0).Bind(gl.TEXTURE_2D)
+
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, int(internalFormat), bounds.Dx(), bounds.Dy(), 0, format, pixelType, pix)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+
+	return tex
+}
+
+// Odd sizes so that rows aren't a multiple of 4 bytes long.
+var conformanceBounds = image.Rect(0, 0, 7, 5)
+
+func conformanceNrgba() *image.NRGBA {
+	ret := image.NewNRGBA(conformanceBounds)
+	for y := range conformanceBounds.Dy() {
+		for x := range conformanceBounds.Dx() {
+			ret.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 36), G: uint8(y * 60), B: uint8(255 - x*36), A: uint8(15 + 48*y)})
+		}
+	}
+	return ret
+}
+
+type textureConformanceCase struct {
+	name string
+
+	// Internal formats that drivers are allowed to pick for the upload.
+	formats []gl.GLenum
+
+	// Runs on the render thread.
+	upload func() gl.Texture
+
+	expected   *image.NRGBA
+	tolerance  rendertest.ChannelTolerance
+	background color.Color
+}
+
+func textureConformanceCases() []textureConformanceCase {
+	nrgba := conformanceNrgba()
+
+	grayAlpha := imgmanip.NewGrayAlpha(conformanceBounds)
+	for y := range conformanceBounds.Dy() {
+		for x := range conformanceBounds.Dx() {
+			i := grayAlpha.PixOffset(x, y)
+			grayAlpha.Pix[i] = uint8(x * 36)
+			grayAlpha.Pix[i+1] = uint8(255 - y*60)
+		}
+	}
+
+	// Glyph atlases are drawn in white on an NRGBA canvas then uploaded as
+	// alpha-only with each 4-byte pixel read as one big alpha value; see
+	// gui.Dictionary.
+	glyphs := image.NewNRGBA(conformanceBounds)
+	glyphAlpha := image.NewAlpha(conformanceBounds)
+	for y := range conformanceBounds.Dy() {
+		for x := range conformanceBounds.Dx() {
+			a := uint8((x + y) * 25)
+			glyphs.SetNRGBA(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: a})
+			glyphAlpha.SetAlpha(x, y, color.Alpha{A: a})
+		}
+	}
+
+	return []textureConformanceCase{
+		{
+			name:    "NRGBA",
+			formats: []gl.GLenum{gl.RGBA, gl.RGBA8},
+			upload: func() gl.Texture {
+				return uploadConformanceTexture(gl.RGBA, gl.RGBA, gl.UNSIGNED_BYTE, nrgba.Rect, flippedRows(nrgba.Pix, nrgba.Stride))
+			},
+			expected: nrgba,
+		},
+		{
+			name:    "GrayAlpha",
+			formats: []gl.GLenum{gl.LUMINANCE_ALPHA, gl.LUMINANCE8_ALPHA8},
+			upload: func() gl.Texture {
+				return uploadConformanceTexture(gl.LUMINANCE_ALPHA, gl.LUMINANCE_ALPHA, gl.UNSIGNED_BYTE, grayAlpha.Rect, flippedRows(grayAlpha.Pix, grayAlpha.Stride))
+			},
+			expected: imgmanip.ToNRGBA(grayAlpha),
+		},
+		{
+			name:    "glyph atlas",
+			formats: []gl.GLenum{gl.ALPHA, gl.ALPHA8},
+			upload: func() gl.Texture {
+				return uploadConformanceTexture(gl.ALPHA, gl.ALPHA, gl.UNSIGNED_INT, glyphs.Rect, flippedRows(glyphs.Pix, glyphs.Stride))
+			},
+			expected: imgmanip.ToNRGBA(glyphAlpha),
+			// Squeezing 32-bit values into 8 bits rounds some alphas up by one;
+			// compose over black so that barely-there pixels compare as barely
+			// visible instead of by their colour.
+			tolerance:  rendertest.ChannelTolerance{1, 1, 1, 1},
+			background: color.RGBA{A: 255},
+		},
+	}
+}
+
+// Todo #46: catches drivers that store or sample glop's textures differently.
+func TestTextureConformance(t *testing.T) {
+	for _, tc := range textureConformanceCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			var info debug.TextureInfo
+			var dumped *image.NRGBA
+			var dumpErr error
+			testbuilder.Run(func() {
+				tex := tc.upload()
+				defer tex.Delete()
+
+				info = debug.TextureReport(tex)
+				dumped, dumpErr = debug.DumpTexture(tex)
+			})
+
+			t.Logf("%s: %v", tc.name, info)
+
+			assert.Contains(t, tc.formats, info.InternalFormat)
+			assert.Equal(t, conformanceBounds.Dx(), info.Width)
+			assert.Equal(t, conformanceBounds.Dy(), info.Height)
+			assert.Equal(t, 1, info.MipLevels)
+			assert.Equal(t, gl.GLenum(gl.LINEAR), info.MinFilter)
+			assert.Equal(t, gl.GLenum(gl.LINEAR), info.MagFilter)
+			assert.Equal(t, gl.GLenum(gl.REPEAT), info.WrapS)
+			assert.Equal(t, gl.GLenum(gl.REPEAT), info.WrapT)
+			if info.HasSwizzle {
+				assert.Equal(t, [4]gl.GLenum{gl.RED, gl.GREEN, gl.BLUE, gl.ALPHA}, info.Swizzle)
+			}
+
+			if !assert.NoError(t, dumpErr) {
+				return
+			}
+			bg := tc.background
+			if bg == nil {
+				bg = color.RGBA{}
+			}
+			result := rendertest.CompareImages(dumped, tc.expected, tc.tolerance, rendertest.BackgroundColour(bg))
+			assert.True(t, result.Match, "round trip through %s: %v", tc.name, result)
+		})
+	}
+}
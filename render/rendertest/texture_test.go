@@ -54,3 +54,32 @@ func TestDrawTexturedQuad(t *testing.T) {
 		}, ShouldPanic)
 	})
 }
+
+func TestDebugShaderUniforms(t *testing.T) {
+	Convey("DrawTexturedQuad's shader exposes the uniforms it sets", t, func(c C) {
+		testbuilder.New().WithQueue().Run(func(queue render.RenderQueueInterface) {
+			queue.Queue(func(st render.RenderQueueState) {
+				tex, cleanup := rendertest.GivenATexture("red/0.png")
+				defer cleanup()
+				rendertest.DrawTexturedQuad(image.Rect(0, 0, 8, 8), tex, st.Shaders())
+			})
+			queue.Purge()
+
+			rendertest.MustExposeUniforms(t, queue, rendertest.DebugShaderName, map[string]gl.GLenum{
+				"tex": gl.SAMPLER_2D,
+			})
+
+			var err error
+			queue.Queue(func(st render.RenderQueueState) {
+				err = rendertest.CheckShaderUniforms(st.Shaders(), rendertest.DebugShaderName, map[string]gl.GLenum{
+					"tex":     gl.FLOAT,
+					"missing": gl.FLOAT,
+				})
+			})
+			queue.Purge()
+			c.So(err, ShouldNotBeNil)
+			c.So(err.Error(), ShouldContainSubstring, `no active uniform "missing"`)
+			c.So(err.Error(), ShouldContainSubstring, `uniform "tex" is a sampler2D, not a float`)
+		})
+	})
+}
@@ -2,6 +2,7 @@ package render
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/caffeine-storm/gl"
 	"github.com/caffeine-storm/glop/glog"
@@ -9,14 +10,36 @@ import (
 
 type ShaderBank struct {
 	ShaderProgs map[string]gl.Program
+
+	// Problems found while reloading file-backed shaders are reported here. If
+	// nil, glog.WarningLogger() is used.
+	Logger glog.Logger
+
+	// File-backed shaders are checked for changes at most this often.
+	PollInterval time.Duration
+
+	watched  map[string]*watchedShader
+	uniforms map[string]map[string]uniformInfo
+	// Bumped each time a shader's program changes; see Generation.
+	generations map[string]uint64
 }
 
+const DefaultShaderPollInterval = 500 * time.Millisecond
+
 func MakeShaderBank() *ShaderBank {
 	return &ShaderBank{
-		ShaderProgs: make(map[string]gl.Program),
+		ShaderProgs:  make(map[string]gl.Program),
+		PollInterval: DefaultShaderPollInterval,
 	}
 }
 
+func (bank *ShaderBank) logger() glog.Logger {
+	if bank.Logger == nil {
+		return glog.WarningLogger()
+	}
+	return bank.Logger
+}
+
 func (bank *ShaderBank) HasShader(shaderName string) bool {
 	_, found := bank.ShaderProgs[shaderName]
 	return found
@@ -30,12 +53,17 @@ func (err shaderError) Error() string {
 
 var ErrShaderAlreadyRegistered shaderError = "shader name already in use"
 
+// Wrapped by errors about uniforms that don't exist or that the driver
+// optimized away.
+var ErrInactiveUniform shaderError = "no such active uniform"
+
 // TODO(tmckee): refactor: There should be a 'DisableShader' to 'UseProgram(0)'
 func (bank *ShaderBank) EnableShader(name string) error {
 	if name == "" {
 		gl.Program(0).Use()
 		return nil
 	}
+	bank.maybeReload(name)
 	prog, ok := bank.ShaderProgs[name]
 	if !ok {
 		return shaderError(fmt.Sprintf("Tried to use unknown shader '%s'", name))
@@ -45,46 +73,51 @@ func (bank *ShaderBank) EnableShader(name string) error {
 	return nil
 }
 
-func (bank *ShaderBank) SetUniformI(shader, variable string, n int) error {
-	prog, ok := bank.ShaderProgs[shader]
-	if !ok {
-		return shaderError(fmt.Sprintf("Tried to set a uniform in an unknown shader '%s'", shader))
+// Compiles and links the given sources into a program that can be enabled by
+// name. Failures to compile or link are reported as a *ShaderCompileError.
+func (bank *ShaderBank) RegisterShader(name string, vertex, fragment string) error {
+	if _, notOk := bank.ShaderProgs[name]; notOk {
+		return ErrShaderAlreadyRegistered
 	}
-	loc := prog.GetUniformLocation(variable)
-	loc.Uniform1i(n)
-	return nil
-}
 
-func (bank *ShaderBank) SetUniformF(shader, variable string, f float32) error {
-	prog, ok := bank.ShaderProgs[shader]
-	if !ok {
-		return shaderError(fmt.Sprintf("Tried to set a uniform in an unknown shader '%s'", shader))
+	program, err := compileAndLink(name, vertex, fragment)
+	if err != nil {
+		return err
 	}
-	loc := prog.GetUniformLocation(variable)
-	loc.Uniform1f(f)
+
+	bank.ShaderProgs[name] = program
+	bank.bumpGeneration(name)
+
+	LogAndClearGlErrors(glog.InfoLogger())
 	return nil
 }
 
-func (bank *ShaderBank) RegisterShader(name string, vertex, fragment string) error {
-	if _, notOk := bank.ShaderProgs[name]; notOk {
-		return ErrShaderAlreadyRegistered
+func compileShader(name string, stage ShaderStage, shaderType gl.GLenum, source string) (gl.Shader, error) {
+	shader := gl.CreateShader(shaderType)
+	shader.Source(source)
+	shader.Compile()
+	did_compile := shader.Get(gl.COMPILE_STATUS)
+	if did_compile != gl.TRUE {
+		infoLog := shader.GetInfoLog()
+		shader.Delete()
+		return 0, newShaderCompileError(name, stage, source, infoLog)
 	}
+	return shader, nil
+}
 
-	vertex_shader := gl.CreateShader(gl.VERTEX_SHADER)
-	vertex_shader.Source(vertex)
-	vertex_shader.Compile()
-	did_compile := vertex_shader.Get(gl.COMPILE_STATUS)
-	if did_compile != gl.TRUE {
-		return shaderError(fmt.Sprintf("Failed to compile vertex shader '%s': %v", name, did_compile))
+// Returns a linked program or a *ShaderCompileError.
+func compileAndLink(name, vertex, fragment string) (gl.Program, error) {
+	vertex_shader, err := compileShader(name, StageVertex, gl.VERTEX_SHADER, vertex)
+	if err != nil {
+		return 0, err
 	}
+	defer vertex_shader.Delete()
 
-	fragment_shader := gl.CreateShader(gl.FRAGMENT_SHADER)
-	fragment_shader.Source(fragment)
-	fragment_shader.Compile()
-	did_compile = fragment_shader.Get(gl.COMPILE_STATUS)
-	if did_compile != gl.TRUE {
-		return shaderError(fmt.Sprintf("Failed to compile fragment shader '%s': %v", name, did_compile))
+	fragment_shader, err := compileShader(name, StageFragment, gl.FRAGMENT_SHADER, fragment)
+	if err != nil {
+		return 0, err
 	}
+	defer fragment_shader.Delete()
 
 	// shader successfully compiled - now link
 	program := gl.CreateProgram()
@@ -93,11 +126,14 @@ func (bank *ShaderBank) RegisterShader(name string, vertex, fragment string) err
 	program.Link()
 	did_link := program.Get(gl.LINK_STATUS)
 	if did_link != gl.TRUE {
-		return shaderError(fmt.Sprintf("Failed to link shader '%s': %v", name, did_compile))
+		infoLog := program.GetInfoLog()
+		program.Delete()
+		return 0, &ShaderCompileError{
+			Shader:  name,
+			Stage:   StageLink,
+			InfoLog: infoLog,
+		}
 	}
 
-	bank.ShaderProgs[name] = program
-
-	LogAndClearGlErrors(glog.InfoLogger())
-	return nil
+	return program, nil
 }
@@ -0,0 +1,189 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/mathgl"
+)
+
+// An active uniform or attribute of a linked program.
+type ShaderVariable struct {
+	Name string
+	// A GL type like gl.FLOAT_VEC3 or gl.SAMPLER_2D.
+	Type gl.GLenum
+	// The number of elements for arrays; 1 otherwise.
+	Size int
+	// Drivers may list the built-ins that a program uses, like gl_Vertex. Those
+	// have a location of -1.
+	Location int
+}
+
+// The GLSL spelling of the variable's type, like 'vec3'.
+func (v ShaderVariable) TypeName() string {
+	return glslTypeName(v.Type)
+}
+
+func (v ShaderVariable) String() string {
+	if v.Size != 1 {
+		return fmt.Sprintf("%s %s[%d] @%d", v.TypeName(), v.Name, v.Size, v.Location)
+	}
+	return fmt.Sprintf("%s %s @%d", v.TypeName(), v.Name, v.Location)
+}
+
+// What a linked program expects from its callers. Each list is sorted by name.
+type ShaderDescription struct {
+	Uniforms   []ShaderVariable
+	Attributes []ShaderVariable
+}
+
+// Returns the named uniform, if it's active.
+func (desc *ShaderDescription) Uniform(name string) (ShaderVariable, bool) {
+	return findVariable(desc.Uniforms, name)
+}
+
+// Returns the named attribute, if it's active.
+func (desc *ShaderDescription) Attribute(name string) (ShaderVariable, bool) {
+	return findVariable(desc.Attributes, name)
+}
+
+func findVariable(vars []ShaderVariable, name string) (ShaderVariable, bool) {
+	for _, v := range vars {
+		if v.Name == name || v.Name == name+"[0]" {
+			return v, true
+		}
+	}
+	return ShaderVariable{}, false
+}
+
+func sortVariables(vars []ShaderVariable) {
+	slices.SortFunc(vars, func(lhs, rhs ShaderVariable) int {
+		return strings.Compare(lhs.Name, rhs.Name)
+	})
+}
+
+func activeUniforms(prog gl.Program) []ShaderVariable {
+	count := prog.Get(gl.ACTIVE_UNIFORMS)
+	ret := make([]ShaderVariable, 0, count)
+	for i := 0; i < count; i++ {
+		size, glType, name := prog.GetActiveUniform(i)
+		ret = append(ret, ShaderVariable{
+			Name:     name,
+			Type:     glType,
+			Size:     size,
+			Location: int(prog.GetUniformLocation(name)),
+		})
+	}
+	sortVariables(ret)
+	return ret
+}
+
+func activeAttributes(prog gl.Program) []ShaderVariable {
+	count := prog.Get(gl.ACTIVE_ATTRIBUTES)
+	ret := make([]ShaderVariable, 0, count)
+	for i := 0; i < count; i++ {
+		size, glType, name := prog.GetActiveAttrib(i)
+		ret = append(ret, ShaderVariable{
+			Name:     name,
+			Type:     glType,
+			Size:     size,
+			Location: int(prog.GetAttribLocation(name)),
+		})
+	}
+	sortVariables(ret)
+	return ret
+}
+
+// Queries the named program for its active uniforms and attributes. Variables
+// that the driver optimized away aren't listed. Must be called on the render
+// thread.
+func (bank *ShaderBank) Describe(name string) (*ShaderDescription, error) {
+	prog, ok := bank.ShaderProgs[name]
+	if !ok {
+		return nil, shaderError(fmt.Sprintf("Tried to describe unknown shader '%s'", name))
+	}
+
+	return &ShaderDescription{
+		Uniforms:   activeUniforms(prog),
+		Attributes: activeAttributes(prog),
+	}, nil
+}
+
+// Sets uniforms from the fields of a struct (or pointer to struct) that are
+// tagged with `uniform:"name"`. The field's type picks the setter:
+//
+//	float32          SetUniformF
+//	int              SetUniformI (works for samplers too)
+//	mathgl.Vec2/3/4  SetUniformVec2/3/4
+//	mathgl.Mat3      SetUniformMat3
+//	Matrix           SetUniformMat4
+//	[]int32          SetUniformIArray
+//
+// Add ",optional" to the tag to skip uniforms that aren't active. Stops at
+// the first error; an unknown shader is an error even if every field is
+// optional.
+func (bank *ShaderBank) SetUniforms(shader string, values any) error {
+	val := reflect.ValueOf(values)
+	if val.Kind() == reflect.Pointer {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return shaderError(fmt.Sprintf("SetUniforms needs a struct, not a %T", values))
+	}
+
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		tag, ok := field.Tag.Lookup("uniform")
+		if !ok || tag == "-" {
+			continue
+		}
+		if !field.IsExported() {
+			return shaderError(fmt.Sprintf("SetUniforms: field %s is tagged but unexported", field.Name))
+		}
+		name, optional := strings.CutSuffix(tag, ",optional")
+
+		if optional {
+			_, err := bank.uniform(shader, name)
+			if errors.Is(err, ErrInactiveUniform) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("SetUniforms: field %s: %w", field.Name, err)
+			}
+		}
+
+		err := bank.setUniformFromValue(shader, name, val.Field(i))
+		if err != nil {
+			return fmt.Errorf("SetUniforms: field %s: %w", field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (bank *ShaderBank) setUniformFromValue(shader, name string, val reflect.Value) error {
+	switch v := val.Interface().(type) {
+	case float32:
+		return bank.SetUniformF(shader, name, v)
+	case int:
+		return bank.SetUniformI(shader, name, v)
+	case mathgl.Vec2:
+		return bank.SetUniformVec2(shader, name, v)
+	case mathgl.Vec3:
+		return bank.SetUniformVec3(shader, name, v)
+	case mathgl.Vec4:
+		return bank.SetUniformVec4(shader, name, v)
+	case mathgl.Mat3:
+		return bank.SetUniformMat3(shader, name, &v)
+	case Matrix:
+		return bank.SetUniformMat4(shader, name, &v)
+	case []int32:
+		return bank.SetUniformIArray(shader, name, v)
+	}
+
+	return shaderError(fmt.Sprintf("can't set a uniform from a %v", val.Type()))
+}
@@ -0,0 +1,102 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type ShaderStage string
+
+const (
+	StageVertex   ShaderStage = "vertex"
+	StageFragment ShaderStage = "fragment"
+	// Linking isn't a compilation stage but it fails in the same ways.
+	StageLink ShaderStage = "link"
+)
+
+// One complaint from a driver's info log along with the source it refers to.
+type SourceExcerpt struct {
+	// 1-based line number in the stage's source.
+	Line int
+
+	// The line of the info log that mentioned Line.
+	Message string
+
+	// The offending line and its neighbours, ready for printing. The offending
+	// line is marked with a '>'.
+	Source []string
+}
+
+// Returned when a shader fails to compile or a program fails to link.
+type ShaderCompileError struct {
+	Shader   string
+	Stage    ShaderStage
+	InfoLog  string
+	Excerpts []SourceExcerpt
+}
+
+func (err *ShaderCompileError) Error() string {
+	if err.Stage == StageLink {
+		return fmt.Sprintf("Failed to link shader '%s': %s", err.Shader, strings.TrimSpace(err.InfoLog))
+	}
+
+	if len(err.Excerpts) == 0 {
+		return fmt.Sprintf("Failed to compile %s shader '%s': %s", err.Stage, err.Shader, strings.TrimSpace(err.InfoLog))
+	}
+
+	msg := &strings.Builder{}
+	fmt.Fprintf(msg, "Failed to compile %s shader '%s':", err.Stage, err.Shader)
+	for _, excerpt := range err.Excerpts {
+		fmt.Fprintf(msg, "\n%s\n%s", excerpt.Message, strings.Join(excerpt.Source, "\n"))
+	}
+	return msg.String()
+}
+
+// Drivers disagree on how to point at a line. We understand
+//
+//	0:12(5): error: ...         (Mesa)
+//	0(12) : error C0000: ...    (NVIDIA)
+//	ERROR: 0:12: ...            (AMD, Intel on Windows)
+var infoLogLineNumber = regexp.MustCompile(`^\s*(?:(?:ERROR|WARNING):\s*)?\d+(?::(\d+)|\((\d+)\))`)
+
+// How many lines of source to show on either side of an offending line.
+const excerptContext = 1
+
+func newShaderCompileError(shader string, stage ShaderStage, source, infoLog string) *ShaderCompileError {
+	ret := &ShaderCompileError{
+		Shader:  shader,
+		Stage:   stage,
+		InfoLog: infoLog,
+	}
+
+	sourceLines := strings.Split(source, "\n")
+	for _, logLine := range strings.Split(infoLog, "\n") {
+		match := infoLogLineNumber.FindStringSubmatch(logLine)
+		if match == nil {
+			continue
+		}
+		lineNumber, err := strconv.Atoi(match[1] + match[2])
+		if err != nil || lineNumber < 1 || lineNumber > len(sourceLines) {
+			continue
+		}
+
+		excerpt := SourceExcerpt{
+			Line:    lineNumber,
+			Message: strings.TrimSpace(logLine),
+		}
+		first := max(1, lineNumber-excerptContext)
+		last := min(len(sourceLines), lineNumber+excerptContext)
+		for n := first; n <= last; n++ {
+			marker := " "
+			if n == lineNumber {
+				marker = ">"
+			}
+			excerpt.Source = append(excerpt.Source, fmt.Sprintf("%s%4d | %s", marker, n, sourceLines[n-1]))
+		}
+		ret.Excerpts = append(ret.Excerpts, excerpt)
+	}
+
+	return ret
+}
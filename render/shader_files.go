@@ -0,0 +1,166 @@
+package render
+
+import (
+	"os"
+	"time"
+
+	"github.com/caffeine-storm/gl"
+)
+
+// Identifies a version of a file well enough to notice that it was edited.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}, nil
+}
+
+type watchedShader struct {
+	vertPath, fragPath   string
+	vertStamp, fragStamp fileStamp
+	lastChecked          time.Time
+}
+
+// Returns true if either source file looks different from when it was last
+// loaded. Files that can't be stat'd are treated as unchanged; editors often
+// replace a file by removing it first.
+func (w *watchedShader) changed() (bool, fileStamp, fileStamp) {
+	vertStamp, err := stampFile(w.vertPath)
+	if err != nil {
+		return false, w.vertStamp, w.fragStamp
+	}
+	fragStamp, err := stampFile(w.fragPath)
+	if err != nil {
+		return false, w.vertStamp, w.fragStamp
+	}
+
+	return vertStamp != w.vertStamp || fragStamp != w.fragStamp, vertStamp, fragStamp
+}
+
+func readShaderSources(vertPath, fragPath string) (string, string, error) {
+	vertex, err := os.ReadFile(vertPath)
+	if err != nil {
+		return "", "", err
+	}
+	fragment, err := os.ReadFile(fragPath)
+	if err != nil {
+		return "", "", err
+	}
+	return string(vertex), string(fragment), nil
+}
+
+// Like RegisterShader but reads the sources from files. The files are
+// watched; when either changes, the shader is recompiled and relinked the next
+// time it's enabled (or when ReloadChangedShaders is called). If the new
+// sources don't compile or link, the old program stays in use and the driver's
+// info log is reported through the bank's Logger.
+func (bank *ShaderBank) RegisterShaderFromFiles(name, vertPath, fragPath string) error {
+	if _, notOk := bank.ShaderProgs[name]; notOk {
+		return ErrShaderAlreadyRegistered
+	}
+
+	watch := &watchedShader{
+		vertPath:    vertPath,
+		fragPath:    fragPath,
+		lastChecked: time.Now(),
+	}
+	var err error
+	watch.vertStamp, err = stampFile(vertPath)
+	if err != nil {
+		return err
+	}
+	watch.fragStamp, err = stampFile(fragPath)
+	if err != nil {
+		return err
+	}
+
+	vertex, fragment, err := readShaderSources(vertPath, fragPath)
+	if err != nil {
+		return err
+	}
+	err = bank.RegisterShader(name, vertex, fragment)
+	if err != nil {
+		return err
+	}
+
+	if bank.watched == nil {
+		bank.watched = map[string]*watchedShader{}
+	}
+	bank.watched[name] = watch
+	return nil
+}
+
+// Recompiles and relinks every file-backed shader whose files changed since
+// they were last loaded. Must be called on the render thread.
+func (bank *ShaderBank) ReloadChangedShaders() {
+	for name, watch := range bank.watched {
+		bank.reloadIfChanged(name, watch)
+	}
+}
+
+// Checks the named shader's files if it's file-backed and if it hasn't been
+// checked in the last PollInterval.
+func (bank *ShaderBank) maybeReload(name string) {
+	watch, ok := bank.watched[name]
+	if !ok {
+		return
+	}
+	if time.Since(watch.lastChecked) < bank.PollInterval {
+		return
+	}
+	bank.reloadIfChanged(name, watch)
+}
+
+func (bank *ShaderBank) reloadIfChanged(name string, watch *watchedShader) {
+	watch.lastChecked = time.Now()
+
+	changed, vertStamp, fragStamp := watch.changed()
+	if !changed {
+		return
+	}
+
+	// Only try each version of the files once; a broken shader stays broken
+	// until it's edited again.
+	watch.vertStamp, watch.fragStamp = vertStamp, fragStamp
+
+	err := bank.reload(name, watch)
+	if err != nil {
+		bank.logger().Error("couldn't reload shader; keeping the old one", "shader", name, "err", err)
+		return
+	}
+	bank.logger().Info("reloaded shader", "shader", name)
+}
+
+func (bank *ShaderBank) reload(name string, watch *watchedShader) error {
+	vertex, fragment, err := readShaderSources(watch.vertPath, watch.fragPath)
+	if err != nil {
+		return err
+	}
+
+	program, err := compileAndLink(name, vertex, fragment)
+	if err != nil {
+		return err
+	}
+
+	old := bank.ShaderProgs[name]
+	if gl.Program(gl.GetInteger(gl.CURRENT_PROGRAM)) == old {
+		program.Use()
+	}
+	old.Delete()
+
+	bank.ShaderProgs[name] = program
+	delete(bank.uniforms, name)
+	bank.bumpGeneration(name)
+
+	LogAndClearGlErrors(bank.logger())
+	return nil
+}
@@ -0,0 +1,393 @@
+package render_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/glog"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
+	"github.com/caffeine-storm/mathgl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testVertexShader = `
+#version 120
+void main() {
+	gl_Position = ftransform();
+}
+`
+
+const testFragmentShader = `
+#version 120
+uniform float brightness;
+void main() {
+	gl_FragColor = vec4(brightness, 0.0, 0.0, 1.0);
+}
+`
+
+const otherFragmentShader = `
+#version 120
+uniform float unused;
+uniform float brightness;
+void main() {
+	gl_FragColor = vec4(0.0, brightness, unused, 1.0);
+}
+`
+
+const brokenFragmentShader = `
+#version 120
+void main() {
+	gl_FragColor = not_declared_anywhere;
+}
+`
+
+// Writes the given contents and makes sure the file looks modified even if
+// the filesystem's timestamps are coarse.
+func editFile(t *testing.T, path, contents string, age time.Duration) {
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	stamp := time.Now().Add(age)
+	require.NoError(t, os.Chtimes(path, stamp, stamp))
+}
+
+func currentProgram() gl.Program {
+	return gl.Program(gl.GetInteger(gl.CURRENT_PROGRAM))
+}
+
+func TestShaderBankFiles(t *testing.T) {
+	testbuilder.New().Run(func() {
+		dir := t.TempDir()
+		vertPath := filepath.Join(dir, "test.vert")
+		fragPath := filepath.Join(dir, "test.frag")
+		editFile(t, vertPath, testVertexShader, -time.Hour)
+		editFile(t, fragPath, testFragmentShader, -time.Hour)
+
+		logs := &bytes.Buffer{}
+		bank := render.MakeShaderBank()
+		bank.Logger = glog.New(&glog.Opts{Output: logs})
+		bank.PollInterval = 0
+		defer bank.EnableShader("")
+
+		require.NoError(t, bank.RegisterShaderFromFiles("test", vertPath, fragPath))
+		assert.ErrorIs(t, bank.RegisterShaderFromFiles("test", vertPath, fragPath), render.ErrShaderAlreadyRegistered)
+		assert.Error(t, bank.RegisterShaderFromFiles("missing", vertPath, filepath.Join(dir, "nope.frag")))
+
+		require.NoError(t, bank.EnableShader("test"))
+		original := bank.ShaderProgs["test"]
+		assert.Equal(t, original, currentProgram())
+
+		// These checks need the render thread so they can't be t.Run subtests.
+
+		// The old program is kept if the new source is broken.
+		{
+			editFile(t, fragPath, brokenFragmentShader, -time.Minute)
+
+			require.NoError(t, bank.EnableShader("test"))
+			assert.Equal(t, original, bank.ShaderProgs["test"])
+			assert.Equal(t, original, currentProgram())
+			assert.Contains(t, logs.String(), "keeping the old one")
+			assert.Contains(t, logs.String(), "not_declared_anywhere")
+		}
+
+		// The new program is swapped in once the source is fixed.
+		{
+			// Prime the bank's cache of uniform locations.
+			_, err := bank.UniformLocation("test", "brightness")
+			require.NoError(t, err)
+			handle, err := bank.UniformHandle("test", "brightness")
+			require.NoError(t, err)
+			generation := bank.Generation("test")
+
+			editFile(t, fragPath, otherFragmentShader, 0)
+
+			// The bound program should be replaced even without re-enabling it.
+			bank.ReloadChangedShaders()
+			reloaded := bank.ShaderProgs["test"]
+			assert.NotEqual(t, original, reloaded)
+			assert.Equal(t, reloaded, currentProgram())
+
+			after, err := bank.UniformLocation("test", "brightness")
+			require.NoError(t, err)
+			assert.Equal(t, reloaded.GetUniformLocation("brightness"), after)
+			assert.Equal(t, generation+1, bank.Generation("test"))
+
+			// Handles taken before the reload follow it.
+			fromHandle, err := handle.Location()
+			require.NoError(t, err)
+			assert.Equal(t, after, fromHandle)
+			require.NoError(t, bank.SetUniformF("test", "brightness", 0.5))
+			assert.Equal(t, gl.GLenum(gl.NO_ERROR), gl.GetError())
+		}
+
+		// Files aren't polled more often than asked.
+		{
+			bank.PollInterval = time.Hour
+			current := bank.ShaderProgs["test"]
+
+			editFile(t, fragPath, testFragmentShader, time.Minute)
+			require.NoError(t, bank.EnableShader("test"))
+			assert.Equal(t, current, bank.ShaderProgs["test"])
+		}
+	})
+}
+
+const noMainFragmentShader = `
+#version 120
+void notMain() {
+	gl_FragColor = vec4(1.0);
+}
+`
+
+func TestShaderCompileErrors(t *testing.T) {
+	testbuilder.New().Run(func() {
+		bank := render.MakeShaderBank()
+
+		err := bank.RegisterShader("broken", testVertexShader, brokenFragmentShader)
+		var compileErr *render.ShaderCompileError
+		require.ErrorAs(t, err, &compileErr)
+		assert.Equal(t, "broken", compileErr.Shader)
+		assert.Equal(t, render.StageFragment, compileErr.Stage)
+		assert.Contains(t, compileErr.InfoLog, "not_declared_anywhere")
+
+		// The offending line is the 4th; the source starts with a newline.
+		require.NotEmpty(t, compileErr.Excerpts)
+		excerpt := compileErr.Excerpts[0]
+		assert.Equal(t, 4, excerpt.Line)
+		assert.Equal(t, []string{
+			"    3 | void main() {",
+			">   4 | \tgl_FragColor = not_declared_anywhere;",
+			"    5 | }",
+		}, excerpt.Source)
+		assert.Contains(t, err.Error(), ">   4 |")
+
+		err = bank.RegisterShader("unlinkable", testVertexShader, noMainFragmentShader)
+		require.ErrorAs(t, err, &compileErr)
+		assert.Equal(t, render.StageLink, compileErr.Stage)
+		assert.NotEmpty(t, compileErr.InfoLog)
+		assert.Empty(t, compileErr.Excerpts)
+
+		assert.False(t, bank.HasShader("broken"))
+		assert.False(t, bank.HasShader("unlinkable"))
+	})
+}
+
+const uniformsFragmentShader = `
+#version 120
+uniform float f;
+uniform vec2 v2;
+uniform vec3 v3;
+uniform vec4 v4;
+uniform mat3 m3;
+uniform mat4 m4;
+uniform int ints[3];
+uniform sampler2D tex;
+void main() {
+	vec4 sum = vec4(f) + vec4(v2, v3.x, v3.y) + v4 + vec4(m3[0], 0.0) + m4[1];
+	sum += vec4(float(ints[0] + ints[1] + ints[2]));
+	gl_FragColor = sum + texture2D(tex, v2);
+}
+`
+
+func getUniformF(prog gl.Program, loc gl.UniformLocation, n int) []float32 {
+	values := make([]float32, n)
+	prog.GetUniformfv(loc, values)
+	return values
+}
+
+func TestShaderUniformSetters(t *testing.T) {
+	testbuilder.New().Run(func() {
+		bank := render.MakeShaderBank()
+		require.NoError(t, bank.RegisterShader("uniforms", testVertexShader, uniformsFragmentShader))
+		require.NoError(t, bank.EnableShader("uniforms"))
+		defer bank.EnableShader("")
+		prog := bank.ShaderProgs["uniforms"]
+
+		location := func(name string) gl.UniformLocation {
+			loc, err := bank.UniformLocation("uniforms", name)
+			require.NoError(t, err)
+			return loc
+		}
+
+		_, err := bank.UniformHandle("nope", "f")
+		assert.Error(t, err)
+		_, err = bank.UniformHandle("uniforms", "not_a_uniform")
+		assert.Error(t, err)
+
+		require.NoError(t, bank.SetUniformF("uniforms", "f", 0.5))
+		assert.Equal(t, []float32{0.5}, getUniformF(prog, location("f"), 1))
+
+		require.NoError(t, bank.SetUniformVec2("uniforms", "v2", mathgl.Vec2{X: 1, Y: 2}))
+		assert.Equal(t, []float32{1, 2}, getUniformF(prog, location("v2"), 2))
+
+		require.NoError(t, bank.SetUniformVec3("uniforms", "v3", mathgl.Vec3{X: 1, Y: 2, Z: 3}))
+		assert.Equal(t, []float32{1, 2, 3}, getUniformF(prog, location("v3"), 3))
+
+		require.NoError(t, bank.SetUniformVec4("uniforms", "v4", mathgl.Vec4{X: 1, Y: 2, Z: 3, W: 4}))
+		assert.Equal(t, []float32{1, 2, 3, 4}, getUniformF(prog, location("v4"), 4))
+
+		mat3 := mathgl.Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9}
+		require.NoError(t, bank.SetUniformMat3("uniforms", "m3", &mat3))
+		assert.Equal(t, mat3[:], getUniformF(prog, location("m3"), 9))
+
+		mat4 := render.Matrix{}
+		mat4.Identity()
+		mat4[13] = 42
+		require.NoError(t, bank.SetUniformMat4("uniforms", "m4", &mat4))
+		assert.Equal(t, mat4[:], getUniformF(prog, location("m4"), 16))
+
+		require.NoError(t, bank.SetUniformIArray("uniforms", "ints", []int32{7, 8}))
+		ints := make([]int32, 1)
+		intsLoc := prog.GetUniformLocation("ints[1]")
+		prog.GetUniformiv(intsLoc, ints)
+		assert.Equal(t, []int32{8}, ints)
+
+		require.NoError(t, bank.SetUniformSampler("uniforms", "tex", 3))
+		prog.GetUniformiv(location("tex"), ints)
+		assert.Equal(t, []int32{3}, ints)
+		assert.NoError(t, bank.SetUniformI("uniforms", "tex", 0))
+
+		assert.Equal(t, gl.GLenum(gl.NO_ERROR), gl.GetError())
+
+		t.Log("mismatched types are errors")
+		assert.ErrorContains(t, bank.SetUniformF("uniforms", "v2", 1), "it's a vec2")
+		assert.ErrorContains(t, bank.SetUniformVec3("uniforms", "v4", mathgl.Vec3{}), "it's a vec4")
+		assert.ErrorContains(t, bank.SetUniformMat4("uniforms", "m3", &mat4), "it's a mat3")
+		assert.Error(t, bank.SetUniformSampler("uniforms", "f", 0))
+		assert.Error(t, bank.SetUniformIArray("uniforms", "ints", []int32{1, 2, 3, 4}))
+		assert.Error(t, bank.SetUniformIArray("uniforms", "ints", nil))
+		assert.Error(t, bank.SetUniformF("uniforms", "not_a_uniform", 1))
+		assert.Error(t, bank.SetUniformF("not_a_shader", "f", 1))
+
+		// Nothing should have reached GL.
+		assert.Equal(t, []float32{1, 2}, getUniformF(prog, location("v2"), 2))
+		assert.Equal(t, gl.GLenum(gl.NO_ERROR), gl.GetError())
+	})
+}
+
+const attributeVertexShader = `
+#version 120
+attribute vec2 offset;
+void main() {
+	gl_Position = ftransform() + vec4(offset, 0.0, 0.0);
+}
+`
+
+func TestShaderBankDescribe(t *testing.T) {
+	testbuilder.New().Run(func() {
+		bank := render.MakeShaderBank()
+		require.NoError(t, bank.RegisterShader("uniforms", attributeVertexShader, uniformsFragmentShader))
+
+		_, err := bank.Describe("not_a_shader")
+		assert.Error(t, err)
+
+		desc, err := bank.Describe("uniforms")
+		require.NoError(t, err)
+
+		// Drivers can report the built-ins that a shader uses, too.
+		var names []string
+		for _, uniform := range desc.Uniforms {
+			if !strings.HasPrefix(uniform.Name, "gl_") {
+				names = append(names, uniform.Name)
+			}
+		}
+		assert.Equal(t, []string{"f", "ints[0]", "m3", "m4", "tex", "v2", "v3", "v4"}, names)
+
+		ints, found := desc.Uniform("ints")
+		require.True(t, found)
+		assert.Equal(t, gl.GLenum(gl.INT), ints.Type)
+		assert.Equal(t, 3, ints.Size)
+		assert.Equal(t, "int ints[0][3] @"+fmt.Sprint(ints.Location), ints.String())
+
+		tex, found := desc.Uniform("tex")
+		require.True(t, found)
+		assert.Equal(t, "sampler2D", tex.TypeName())
+		loc, err := bank.UniformLocation("uniforms", "tex")
+		require.NoError(t, err)
+		assert.Equal(t, int(loc), tex.Location)
+
+		offset, found := desc.Attribute("offset")
+		require.True(t, found)
+		assert.Equal(t, gl.GLenum(gl.FLOAT_VEC2), offset.Type)
+		assert.Equal(t, 1, offset.Size)
+		assert.GreaterOrEqual(t, offset.Location, 0)
+
+		_, found = desc.Uniform("not_a_uniform")
+		assert.False(t, found)
+	})
+}
+
+type testMaterial struct {
+	Brightness float32       `uniform:"f"`
+	Position   mathgl.Vec2   `uniform:"v2"`
+	Colour     mathgl.Vec4   `uniform:"v4"`
+	Transform  render.Matrix `uniform:"m4"`
+	Counts     []int32       `uniform:"ints"`
+	Texture    int           `uniform:"tex"`
+	Gloss      float32       `uniform:"gloss,optional"`
+	Ignored    string
+	Skipped    float32 `uniform:"-"`
+}
+
+func TestShaderBankSetUniforms(t *testing.T) {
+	testbuilder.New().Run(func() {
+		bank := render.MakeShaderBank()
+		require.NoError(t, bank.RegisterShader("uniforms", testVertexShader, uniformsFragmentShader))
+		require.NoError(t, bank.EnableShader("uniforms"))
+		defer bank.EnableShader("")
+		prog := bank.ShaderProgs["uniforms"]
+
+		location := func(name string) gl.UniformLocation {
+			loc, err := bank.UniformLocation("uniforms", name)
+			require.NoError(t, err)
+			return loc
+		}
+
+		_, err := bank.UniformHandle("nope", "f")
+		assert.Error(t, err)
+		_, err = bank.UniformHandle("uniforms", "not_a_uniform")
+		assert.Error(t, err)
+
+		material := &testMaterial{
+			Brightness: 0.25,
+			Position:   mathgl.Vec2{X: 3, Y: 4},
+			Colour:     mathgl.Vec4{X: 1, Y: 0, Z: 1, W: 0},
+			Counts:     []int32{1, 2, 3},
+			Texture:    2,
+		}
+		material.Transform.Identity()
+
+		require.NoError(t, bank.SetUniforms("uniforms", material))
+		assert.Equal(t, []float32{0.25}, getUniformF(prog, location("f"), 1))
+		assert.Equal(t, []float32{3, 4}, getUniformF(prog, location("v2"), 2))
+		assert.Equal(t, []float32{1, 0, 1, 0}, getUniformF(prog, location("v4"), 4))
+		assert.Equal(t, material.Transform[:], getUniformF(prog, location("m4"), 16))
+		tex := make([]int32, 1)
+		prog.GetUniformiv(location("tex"), tex)
+		assert.Equal(t, []int32{2}, tex)
+
+		// Structs are fine too, not just pointers to them.
+		material.Brightness = 0.75
+		require.NoError(t, bank.SetUniforms("uniforms", *material))
+		assert.Equal(t, []float32{0.75}, getUniformF(prog, location("f"), 1))
+
+		assert.Error(t, bank.SetUniforms("uniforms", 42))
+		assert.Error(t, bank.SetUniforms("uniforms", nil))
+		assert.ErrorContains(t, bank.SetUniforms("uniforms", struct {
+			Wrong mathgl.Vec3 `uniform:"v2"`
+		}{}), "field Wrong")
+		assert.ErrorIs(t, bank.SetUniforms("uniforms", struct {
+			Missing float32 `uniform:"not_a_uniform"`
+		}{}), render.ErrInactiveUniform)
+		assert.ErrorContains(t, bank.SetUniforms("not_a_shader", struct {
+			Gloss float32 `uniform:"gloss,optional"`
+		}{}), "unknown shader")
+		assert.Equal(t, gl.GLenum(gl.NO_ERROR), gl.GetError())
+	})
+}
@@ -0,0 +1,275 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/mathgl"
+)
+
+// What glGetActiveUniform says about a uniform.
+type uniformInfo struct {
+	location gl.UniformLocation
+	glType   gl.GLenum
+	// The number of elements for arrays; 1 otherwise.
+	size int
+}
+
+var samplerTypes = []gl.GLenum{
+	gl.SAMPLER_1D, gl.SAMPLER_2D, gl.SAMPLER_3D, gl.SAMPLER_CUBE,
+	gl.SAMPLER_1D_SHADOW, gl.SAMPLER_2D_SHADOW, gl.SAMPLER_CUBE_SHADOW,
+	gl.SAMPLER_1D_ARRAY, gl.SAMPLER_2D_ARRAY,
+	gl.SAMPLER_1D_ARRAY_SHADOW, gl.SAMPLER_2D_ARRAY_SHADOW,
+	gl.SAMPLER_2D_RECT, gl.SAMPLER_2D_RECT_SHADOW, gl.SAMPLER_BUFFER,
+	gl.SAMPLER_2D_MULTISAMPLE, gl.SAMPLER_2D_MULTISAMPLE_ARRAY,
+	gl.INT_SAMPLER_1D, gl.INT_SAMPLER_2D, gl.INT_SAMPLER_3D, gl.INT_SAMPLER_CUBE,
+	gl.INT_SAMPLER_1D_ARRAY, gl.INT_SAMPLER_2D_ARRAY, gl.INT_SAMPLER_2D_RECT,
+	gl.INT_SAMPLER_BUFFER,
+	gl.UNSIGNED_INT_SAMPLER_1D, gl.UNSIGNED_INT_SAMPLER_2D,
+	gl.UNSIGNED_INT_SAMPLER_3D, gl.UNSIGNED_INT_SAMPLER_CUBE,
+	gl.UNSIGNED_INT_SAMPLER_1D_ARRAY, gl.UNSIGNED_INT_SAMPLER_2D_ARRAY,
+	gl.UNSIGNED_INT_SAMPLER_2D_RECT, gl.UNSIGNED_INT_SAMPLER_BUFFER,
+}
+
+var glslTypeNames = map[gl.GLenum]string{
+	gl.FLOAT:      "float",
+	gl.FLOAT_VEC2: "vec2",
+	gl.FLOAT_VEC3: "vec3",
+	gl.FLOAT_VEC4: "vec4",
+	gl.INT:        "int",
+	gl.INT_VEC2:   "ivec2",
+	gl.INT_VEC3:   "ivec3",
+	gl.INT_VEC4:   "ivec4",
+	gl.BOOL:       "bool",
+	gl.FLOAT_MAT2: "mat2",
+	gl.FLOAT_MAT3: "mat3",
+	gl.FLOAT_MAT4: "mat4",
+	gl.SAMPLER_2D: "sampler2D",
+}
+
+func glslTypeName(glType gl.GLenum) string {
+	if name, ok := glslTypeNames[glType]; ok {
+		return name
+	}
+	if slices.Contains(samplerTypes, glType) {
+		return "sampler"
+	}
+	return fmt.Sprintf("GLenum(0x%04x)", int(glType))
+}
+
+// Asks GL about every active uniform in the given program.
+func queryUniforms(prog gl.Program) map[string]uniformInfo {
+	ret := map[string]uniformInfo{}
+	for _, uniform := range activeUniforms(prog) {
+		info := uniformInfo{
+			location: gl.UniformLocation(uniform.Location),
+			glType:   uniform.Type,
+			size:     uniform.Size,
+		}
+		ret[uniform.Name] = info
+
+		// Arrays are reported as 'name[0]' but are usually referred to as 'name'.
+		if base, found := strings.CutSuffix(uniform.Name, "[0]"); found {
+			ret[base] = info
+		}
+	}
+	return ret
+}
+
+// Returns what's known about the named uniform. Uniforms that the driver
+// optimized away or that don't exist are errors.
+func (bank *ShaderBank) uniform(shader, variable string) (uniformInfo, error) {
+	prog, ok := bank.ShaderProgs[shader]
+	if !ok {
+		return uniformInfo{location: -1}, shaderError(fmt.Sprintf("Tried to find a uniform in an unknown shader '%s'", shader))
+	}
+
+	if bank.uniforms == nil {
+		bank.uniforms = map[string]map[string]uniformInfo{}
+	}
+	uniforms, ok := bank.uniforms[shader]
+	if !ok {
+		uniforms = queryUniforms(prog)
+		bank.uniforms[shader] = uniforms
+	}
+
+	info, ok := uniforms[variable]
+	if !ok {
+		return uniformInfo{location: -1}, fmt.Errorf("Shader '%s' has no active uniform '%s': %w", shader, variable, ErrInactiveUniform)
+	}
+	return info, nil
+}
+
+// Like uniform but also checks that the uniform has one of the given types.
+func (bank *ShaderBank) uniformOfType(setter, shader, variable string, types ...gl.GLenum) (uniformInfo, error) {
+	info, err := bank.uniform(shader, variable)
+	if err != nil {
+		return info, err
+	}
+	if !slices.Contains(types, info.glType) {
+		return info, shaderError(fmt.Sprintf("Can't %s uniform '%s' in shader '%s'; it's a %s", setter, variable, shader, glslTypeName(info.glType)))
+	}
+	return info, nil
+}
+
+// Returns the location of the named uniform in the named shader. Reloading
+// the shader can move its uniforms so use a UniformHandle to hold onto a
+// location.
+func (bank *ShaderBank) UniformLocation(shader, variable string) (gl.UniformLocation, error) {
+	info, err := bank.uniform(shader, variable)
+	return info.location, err
+}
+
+func (bank *ShaderBank) bumpGeneration(shader string) {
+	if bank.generations == nil {
+		bank.generations = map[string]uint64{}
+	}
+	bank.generations[shader]++
+}
+
+// Counts how many times the named shader's program has changed, i.e. it was
+// registered and then reloaded Generation()-1 times. Returns 0 for unknown
+// shaders.
+func (bank *ShaderBank) Generation(shader string) uint64 {
+	return bank.generations[shader]
+}
+
+// A uniform's location that's looked up again whenever its shader is
+// reloaded. Like the rest of the ShaderBank, it's only for the render thread.
+type UniformHandle struct {
+	bank             *ShaderBank
+	shader, variable string
+
+	// The shader generation that location was looked up in.
+	generation uint64
+	location   gl.UniformLocation
+}
+
+// Returns a handle to the named uniform. Fails like UniformLocation does.
+func (bank *ShaderBank) UniformHandle(shader, variable string) (*UniformHandle, error) {
+	h := &UniformHandle{
+		bank:     bank,
+		shader:   shader,
+		variable: variable,
+	}
+	if _, err := h.Location(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Returns the uniform's location in the shader's current program. Fails if a
+// reload left the uniform inactive.
+func (h *UniformHandle) Location() (gl.UniformLocation, error) {
+	// Known shaders are at generation 1 or later.
+	generation := h.bank.Generation(h.shader)
+	if generation != 0 && generation == h.generation {
+		return h.location, nil
+	}
+
+	location, err := h.bank.UniformLocation(h.shader, h.variable)
+	if err != nil {
+		return location, err
+	}
+	h.generation = generation
+	h.location = location
+	return location, nil
+}
+
+// Each of the SetUniform* methods below sets a uniform in the named shader,
+// which must be the enabled shader. They return an error, without setting
+// anything, if the uniform isn't active or if its type doesn't match the
+// method.
+
+// Sets an int, bool or sampler uniform.
+func (bank *ShaderBank) SetUniformI(shader, variable string, n int) error {
+	info, err := bank.uniformOfType("SetUniformI", shader, variable, append([]gl.GLenum{gl.INT, gl.BOOL}, samplerTypes...)...)
+	if err != nil {
+		return err
+	}
+	info.location.Uniform1i(n)
+	return nil
+}
+
+func (bank *ShaderBank) SetUniformF(shader, variable string, f float32) error {
+	info, err := bank.uniformOfType("SetUniformF", shader, variable, gl.FLOAT)
+	if err != nil {
+		return err
+	}
+	info.location.Uniform1f(f)
+	return nil
+}
+
+func (bank *ShaderBank) SetUniformVec2(shader, variable string, v mathgl.Vec2) error {
+	info, err := bank.uniformOfType("SetUniformVec2", shader, variable, gl.FLOAT_VEC2)
+	if err != nil {
+		return err
+	}
+	info.location.Uniform2f(v.X, v.Y)
+	return nil
+}
+
+func (bank *ShaderBank) SetUniformVec3(shader, variable string, v mathgl.Vec3) error {
+	info, err := bank.uniformOfType("SetUniformVec3", shader, variable, gl.FLOAT_VEC3)
+	if err != nil {
+		return err
+	}
+	info.location.Uniform3f(v.X, v.Y, v.Z)
+	return nil
+}
+
+func (bank *ShaderBank) SetUniformVec4(shader, variable string, v mathgl.Vec4) error {
+	info, err := bank.uniformOfType("SetUniformVec4", shader, variable, gl.FLOAT_VEC4)
+	if err != nil {
+		return err
+	}
+	info.location.Uniform4f(v.X, v.Y, v.Z, v.W)
+	return nil
+}
+
+// The matrix is in column-major order, like everything else GL.
+func (bank *ShaderBank) SetUniformMat3(shader, variable string, mat *mathgl.Mat3) error {
+	info, err := bank.uniformOfType("SetUniformMat3", shader, variable, gl.FLOAT_MAT3)
+	if err != nil {
+		return err
+	}
+	info.location.UniformMatrix3f(false, (*[9]float32)(mat))
+	return nil
+}
+
+// The matrix is in column-major order, like everything else GL.
+func (bank *ShaderBank) SetUniformMat4(shader, variable string, mat *Matrix) error {
+	info, err := bank.uniformOfType("SetUniformMat4", shader, variable, gl.FLOAT_MAT4)
+	if err != nil {
+		return err
+	}
+	info.location.UniformMatrix4f(false, (*[16]float32)(mat))
+	return nil
+}
+
+// Sets the leading elements of an int array uniform. It's an error to pass
+// more values than the array holds.
+func (bank *ShaderBank) SetUniformIArray(shader, variable string, values []int32) error {
+	info, err := bank.uniformOfType("SetUniformIArray", shader, variable, gl.INT)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 || len(values) > info.size {
+		return shaderError(fmt.Sprintf("Can't set %d values in uniform '%s' in shader '%s'; it holds %d", len(values), variable, shader, info.size))
+	}
+	info.location.Uniform1iv(len(values), values)
+	return nil
+}
+
+// Points a sampler uniform at a texture unit; pass 0 for gl.TEXTURE0, 1 for
+// gl.TEXTURE1, etc.
+func (bank *ShaderBank) SetUniformSampler(shader, variable string, unit int) error {
+	info, err := bank.uniformOfType("SetUniformSampler", shader, variable, samplerTypes...)
+	if err != nil {
+		return err
+	}
+	info.location.Uniform1i(unit)
+	return nil
+}
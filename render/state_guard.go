@@ -0,0 +1,48 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/caffeine-storm/glop/debug"
+	"github.com/caffeine-storm/glop/glog"
+)
+
+// Returned from WithStateGuard when the guarded function left GL state
+// changed.
+type StateLeakError struct {
+	Differences []debug.GlStateDifference
+}
+
+func (e *StateLeakError) Error() string {
+	return fmt.Sprintf("GL state changed:\n%s", debug.FormatGlStateDiff(e.Differences))
+}
+
+func guardState(fn func()) *StateLeakError {
+	before := debug.GetGlState()
+	fn()
+	after := debug.GetGlState()
+	diffs := debug.DiffGlState(before, after)
+	if len(diffs) == 0 {
+		return nil
+	}
+	return &StateLeakError{
+		Differences: diffs,
+	}
+}
+
+// Runs fn and panics with a *StateLeakError if fn didn't put back the GL state
+// that debug.GetGlState describes. Meant for catching leaks in tests and
+// debug builds; taking the snapshots is slow. Must be called on the render
+// thread of a compatibility-profile context.
+func WithStateGuard(fn func()) {
+	if err := guardState(fn); err != nil {
+		panic(err)
+	}
+}
+
+// Like WithStateGuard but leaked state is logged as a warning instead.
+func WithLoggedStateGuard(logger glog.Logger, fn func()) {
+	if err := guardState(fn); err != nil {
+		logger.Warn("GL state changed", "differences", err.Differences)
+	}
+}
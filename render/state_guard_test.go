@@ -0,0 +1,62 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/glog"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithStateGuard(t *testing.T) {
+	t.Run("is quiet when state is put back", func(t *testing.T) {
+		var recovered any
+		testbuilder.Run(func() {
+			defer func() {
+				recovered = recover()
+			}()
+			render.WithStateGuard(func() {
+				render.WithColour(1, 0, 0, 1, func() {})
+			})
+		})
+
+		assert.Nil(t, recovered)
+	})
+
+	t.Run("panics with the diff when state leaks", func(t *testing.T) {
+		var recovered any
+		testbuilder.Run(func() {
+			defer gl.Disable(gl.DEPTH_TEST)
+			defer func() {
+				recovered = recover()
+			}()
+			render.WithStateGuard(func() {
+				gl.Enable(gl.DEPTH_TEST)
+			})
+		})
+
+		leak, ok := recovered.(*render.StateLeakError)
+		if !assert.True(t, ok, "expected a *render.StateLeakError, got %T", recovered) {
+			return
+		}
+		assert.Len(t, leak.Differences, 1)
+		assert.ErrorContains(t, leak, "DEPTH_TEST: false -> true")
+	})
+
+	t.Run("can log instead", func(t *testing.T) {
+		logs := &bytes.Buffer{}
+		logger := glog.New(&glog.Opts{Output: logs})
+		testbuilder.Run(func() {
+			defer gl.Disable(gl.DEPTH_TEST)
+			render.WithLoggedStateGuard(logger, func() {
+				gl.Enable(gl.DEPTH_TEST)
+			})
+		})
+
+		assert.Contains(t, logs.String(), "GL state changed")
+		assert.Contains(t, logs.String(), "DEPTH_TEST")
+	})
+}
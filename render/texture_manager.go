@@ -0,0 +1,234 @@
+package render
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/debug"
+	"github.com/caffeine-storm/glop/glog"
+)
+
+// Loads the texture for an asset. It's called on the render thread.
+type TextureLoader func() (gl.Texture, error)
+
+// Hands out shared, reference-counted textures keyed by an asset ID. A
+// texture stays resident while any handle to it is unreleased. Once released,
+// it stays cached in case it's wanted again but it's the first to go, least
+// recently used first, when the estimated memory use exceeds Budget.
+//
+// A TextureManager must only be used on the render thread.
+type TextureManager struct {
+	// In bytes. Zero means there's no limit.
+	Budget int
+
+	// Reports textures whose size couldn't be estimated and budgets that
+	// couldn't be met. If nil, glog.WarningLogger() is used.
+	Logger glog.Logger
+
+	entries map[string]*managedTexture
+	bytes   int
+
+	// Incremented on every Acquire and Release to order uses for eviction.
+	clock uint64
+}
+
+type managedTexture struct {
+	id       string
+	texture  gl.Texture
+	bytes    int
+	refs     int
+	lastUsed uint64
+}
+
+// Assumed when a texture's size can't be estimated.
+const fallbackBytesPerTexture = 4
+
+func MakeTextureManager(budget int) *TextureManager {
+	return &TextureManager{
+		Budget:  budget,
+		entries: map[string]*managedTexture{},
+	}
+}
+
+func (m *TextureManager) logger() glog.Logger {
+	if m.Logger == nil {
+		return glog.WarningLogger()
+	}
+	return m.Logger
+}
+
+// A counted reference to a managed texture. Call Release when done with it.
+type TextureHandle struct {
+	manager  *TextureManager
+	entry    *managedTexture
+	released bool
+}
+
+// Returns a handle to the texture for the given asset, calling load if it's
+// not already resident.
+func (m *TextureManager) Acquire(id string, load TextureLoader) (*TextureHandle, error) {
+	m.clock++
+
+	entry, ok := m.entries[id]
+	if !ok {
+		texture, err := load()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load texture %q: %w", id, err)
+		}
+
+		bytes, err := debug.EstimateTextureBytes(texture)
+		if err != nil {
+			m.logger().Warn("couldn't estimate texture size", "id", id, "err", err)
+			bytes = fallbackBytesPerTexture
+		}
+
+		entry = &managedTexture{
+			id:      id,
+			texture: texture,
+			bytes:   bytes,
+		}
+		m.entries[id] = entry
+		m.bytes += bytes
+	}
+
+	entry.refs++
+	entry.lastUsed = m.clock
+	m.enforceBudget()
+
+	return &TextureHandle{
+		manager: m,
+		entry:   entry,
+	}, nil
+}
+
+// Panics if the handle was released.
+func (h *TextureHandle) Texture() gl.Texture {
+	if h.released {
+		panic(fmt.Errorf("TextureHandle.Texture: %q was already released", h.entry.id))
+	}
+	return h.entry.texture
+}
+
+func (h *TextureHandle) ID() string {
+	return h.entry.id
+}
+
+// Gives up this reference. Panics if called twice.
+func (h *TextureHandle) Release() {
+	if h.released {
+		panic(fmt.Errorf("TextureHandle.Release: %q was already released", h.entry.id))
+	}
+	h.released = true
+
+	m := h.manager
+	m.clock++
+	h.entry.refs--
+	h.entry.lastUsed = m.clock
+	m.enforceBudget()
+}
+
+// Estimated bytes used by every resident texture, referenced or not.
+func (m *TextureManager) UsedBytes() int {
+	return m.bytes
+}
+
+func (m *TextureManager) evict(entry *managedTexture) {
+	entry.texture.Delete()
+	m.bytes -= entry.bytes
+	delete(m.entries, entry.id)
+}
+
+func (m *TextureManager) enforceBudget() {
+	if m.Budget <= 0 || m.bytes <= m.Budget {
+		return
+	}
+
+	var unreferenced []*managedTexture
+	for _, entry := range m.entries {
+		if entry.refs == 0 {
+			unreferenced = append(unreferenced, entry)
+		}
+	}
+	slices.SortFunc(unreferenced, func(lhs, rhs *managedTexture) int {
+		return cmp.Compare(lhs.lastUsed, rhs.lastUsed)
+	})
+
+	for _, entry := range unreferenced {
+		if m.bytes <= m.Budget {
+			return
+		}
+		m.evict(entry)
+	}
+
+	if m.bytes > m.Budget {
+		m.logger().Warn("texture budget exceeded by referenced textures", "budget", m.Budget, "used", m.bytes)
+	}
+}
+
+// Deletes every texture that isn't referenced, regardless of the budget.
+func (m *TextureManager) EvictUnreferenced() {
+	for _, entry := range m.entries {
+		if entry.refs == 0 {
+			m.evict(entry)
+		}
+	}
+}
+
+// Describes a resident texture.
+type ManagedTexture struct {
+	ID      string
+	Texture gl.Texture
+	Bytes   int
+	Refs    int
+}
+
+func (t ManagedTexture) String() string {
+	return fmt.Sprintf("%s (texture %d, %d bytes, %d refs)", t.ID, t.Texture, t.Bytes, t.Refs)
+}
+
+func (m *TextureManager) describe(include func(*managedTexture) bool) []ManagedTexture {
+	var ret []ManagedTexture
+	for _, entry := range m.entries {
+		if !include(entry) {
+			continue
+		}
+		ret = append(ret, ManagedTexture{
+			ID:      entry.id,
+			Texture: entry.texture,
+			Bytes:   entry.bytes,
+			Refs:    entry.refs,
+		})
+	}
+	slices.SortFunc(ret, func(lhs, rhs ManagedTexture) int {
+		return strings.Compare(lhs.ID, rhs.ID)
+	})
+	return ret
+}
+
+// Returns every resident texture, sorted by ID.
+func (m *TextureManager) Resident() []ManagedTexture {
+	return m.describe(func(*managedTexture) bool { return true })
+}
+
+// Returns the textures that still have unreleased handles, sorted by ID.
+func (m *TextureManager) Live() []ManagedTexture {
+	return m.describe(func(entry *managedTexture) bool { return entry.refs > 0 })
+}
+
+// Returns an error listing the live textures, if there are any. Meant for
+// tests that want to catch leaked handles.
+func (m *TextureManager) CheckForLeaks() error {
+	live := m.Live()
+	if len(live) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, len(live))
+	for i, t := range live {
+		descriptions[i] = t.String()
+	}
+	return fmt.Errorf("%d leaked texture(s): %s", len(live), strings.Join(descriptions, ", "))
+}
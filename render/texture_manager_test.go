@@ -0,0 +1,138 @@
+package render_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/rendertest/testbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns a loader for a 4x4 RGBA texture (64 bytes) that counts its calls.
+func countingLoader(calls *int) render.TextureLoader {
+	return func() (gl.Texture, error) {
+		*calls++
+		tex := gl.GenTexture()
+		tex.Bind(gl.TEXTURE_2D)
+		defer gl.Texture(0).Bind(gl.TEXTURE_2D)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, 4, 4, 0, gl.RGBA, gl.UNSIGNED_BYTE, make([]byte, 4*4*4))
+		return tex, nil
+	}
+}
+
+func residentIDs(m *render.TextureManager) []string {
+	var ret []string
+	for _, t := range m.Resident() {
+		ret = append(ret, t.ID)
+	}
+	return ret
+}
+
+func TestTextureManager(t *testing.T) {
+	t.Run("shares textures by ID", func(t *testing.T) {
+		var calls, usedBytes int
+		var first, second gl.Texture
+		var errA, errB, leaksWhileHeld, leaksAfter error
+		testbuilder.Run(func() {
+			m := render.MakeTextureManager(0)
+			defer m.EvictUnreferenced()
+
+			var a, b *render.TextureHandle
+			a, errA = m.Acquire("a", countingLoader(&calls))
+			b, errB = m.Acquire("a", countingLoader(&calls))
+			if errA != nil || errB != nil {
+				return
+			}
+			first, second = a.Texture(), b.Texture()
+			usedBytes = m.UsedBytes()
+
+			a.Release()
+			leaksWhileHeld = m.CheckForLeaks()
+			b.Release()
+			leaksAfter = m.CheckForLeaks()
+		})
+
+		require.NoError(t, errA)
+		require.NoError(t, errB)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, first, second)
+		assert.Equal(t, 64, usedBytes)
+		assert.ErrorContains(t, leaksWhileHeld, "a (texture")
+		assert.NoError(t, leaksAfter)
+	})
+
+	t.Run("keeps released textures cached", func(t *testing.T) {
+		var calls int
+		testbuilder.Run(func() {
+			m := render.MakeTextureManager(0)
+			defer m.EvictUnreferenced()
+
+			h, _ := m.Acquire("a", countingLoader(&calls))
+			h.Release()
+			h, _ = m.Acquire("a", countingLoader(&calls))
+			h.Release()
+		})
+
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("evicts the least recently used unreferenced texture", func(t *testing.T) {
+		var calls int
+		var afterC, afterD []string
+		testbuilder.Run(func() {
+			m := render.MakeTextureManager(128)
+			defer m.EvictUnreferenced()
+
+			a, _ := m.Acquire("a", countingLoader(&calls))
+			b, _ := m.Acquire("b", countingLoader(&calls))
+			b.Release()
+			a.Release()
+
+			// 'b' was released first so it goes first.
+			c, _ := m.Acquire("c", countingLoader(&calls))
+			afterC = residentIDs(m)
+
+			// Referenced textures are never evicted, even when over budget.
+			d, _ := m.Acquire("d", countingLoader(&calls))
+			e, _ := m.Acquire("e", countingLoader(&calls))
+			afterD = residentIDs(m)
+
+			c.Release()
+			d.Release()
+			e.Release()
+		})
+
+		assert.Equal(t, []string{"a", "c"}, afterC)
+		assert.Equal(t, []string{"c", "d", "e"}, afterD)
+	})
+
+	t.Run("reports loader failures", func(t *testing.T) {
+		var err error
+		testbuilder.Run(func() {
+			m := render.MakeTextureManager(0)
+			_, err = m.Acquire("broken", func() (gl.Texture, error) {
+				return 0, errors.New("no such file")
+			})
+		})
+
+		assert.ErrorContains(t, err, "broken")
+		assert.ErrorContains(t, err, "no such file")
+	})
+
+	t.Run("handles can't be used after release", func(t *testing.T) {
+		var calls int
+		testbuilder.Run(func() {
+			m := render.MakeTextureManager(0)
+			defer m.EvictUnreferenced()
+
+			h, _ := m.Acquire("a", countingLoader(&calls))
+			h.Release()
+
+			assert.Panics(t, func() { h.Texture() })
+			assert.Panics(t, func() { h.Release() })
+		})
+	})
+}
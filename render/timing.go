@@ -8,9 +8,15 @@ type JobTimingInfo struct {
 	// Time elapsed between when a RenderJob was Queue'd on a
 	// RenderQueueInterface and when the RenderJob func started.
 	QueueTime time.Duration
+	// The Lane that the RenderJob was queued in.
+	Lane Lane
+	// The number of GL errors that were pending after the RenderJob func
+	// returned.
+	GlErrorCount int
 }
 
-// Instances of JobTimingListener can be registered only at Queue construction.
+// Instances of JobTimingListener can be registered at Queue construction or,
+// at any time, through RenderQueueInterface.AddTimingListener.
 type JobTimingListener struct {
 	// NOTE: this notification runs on the render thread that ran the slow job.
 	// Care should be taken not to make a bad situation worse!
@@ -22,4 +28,15 @@ type JobTimingListener struct {
 	// Only jobs that took Threshold or longer will trigger a call to OnNotify.
 	// A job's total time is its RunTime plus its QueueTime.
 	Threshold time.Duration
+
+	// Optional. Called once per frame after all of the jobs queued between
+	// BeginFrame() and EndFrame() have finished. Like OnNotify, this can run on
+	// the render thread.
+	OnFrame func(*FrameStats)
+
+	// Optional. Called on the render thread when a job panics, before the
+	// queue's error callbacks hear about it. Gets the job's timing up to the
+	// panic, its source attribution and what it panicked with. Threshold doesn't
+	// apply.
+	OnPanic func(*JobTimingInfo, string, error)
 }
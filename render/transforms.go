@@ -0,0 +1,186 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/caffeine-storm/mathgl"
+)
+
+// Core-profile contexts don't track matrices or a current colour so
+// Transforms keeps them on the Go side instead. Its With* methods mirror the
+// fixed-function helpers in higher_order.go; shaders read the results through
+// the uniforms that SetUniforms sets.
+//
+// Only MatrixModeModelView and MatrixModeProjection are supported. A
+// Transforms isn't tied to a GL context but it's meant to be used from the
+// render thread like everything else.
+type Transforms struct {
+	// Each stack's top is its last element; neither is ever empty.
+	modelView  []Matrix
+	projection []Matrix
+	colour     []mathgl.Vec4
+}
+
+// The names of the uniforms that Transforms.SetUniforms sets.
+const (
+	ModelViewProjectionUniform = "glop_ModelViewProjection"
+	ColourUniform              = "glop_Colour"
+)
+
+// Returns a Transforms with identity matrices and an opaque white colour,
+// matching a fresh fixed-function context.
+func MakeTransforms() *Transforms {
+	ident := Matrix{}
+	ident.Identity()
+	return &Transforms{
+		modelView:  []Matrix{ident},
+		projection: []Matrix{ident},
+		colour:     []mathgl.Vec4{{X: 1, Y: 1, Z: 1, W: 1}},
+	}
+}
+
+// Returns a Transforms that starts from the fixed-function matrices and
+// colour of the current context. This lets code that draws with Transforms
+// run under callers that still set up gl.Ortho and friends. Only works in a
+// compatibility context and must be called on the render thread.
+func CurrentTransforms() *Transforms {
+	fg := getCurrentForeground()
+	return &Transforms{
+		modelView:  []Matrix{GetCurrentMatrix(MatrixModeModelView)},
+		projection: []Matrix{GetCurrentMatrix(MatrixModeProjection)},
+		colour:     []mathgl.Vec4{{X: fg[0], Y: fg[1], Z: fg[2], W: fg[3]}},
+	}
+}
+
+func (t *Transforms) stack(mode MatrixMode) *[]Matrix {
+	switch mode {
+	case MatrixModeModelView:
+		return &t.modelView
+	case MatrixModeProjection:
+		return &t.projection
+	}
+	panic(fmt.Errorf("Transforms: unsupported matrix mode %d", mode))
+}
+
+func (t *Transforms) top(mode MatrixMode) *Matrix {
+	stack := *t.stack(mode)
+	return &stack[len(stack)-1]
+}
+
+// Returns the current matrix for the given mode.
+func (t *Transforms) Matrix(mode MatrixMode) Matrix {
+	return *t.top(mode)
+}
+
+// Returns projection * model-view; what gl_ModelViewProjectionMatrix would
+// have been.
+func (t *Transforms) ModelViewProjection() Matrix {
+	ret := *t.top(MatrixModeProjection)
+	ret.Multiply(t.top(MatrixModeModelView))
+	return ret
+}
+
+// Returns the current colour as normalized RGBA.
+func (t *Transforms) Colour() mathgl.Vec4 {
+	return t.colour[len(t.colour)-1]
+}
+
+// Replaces the current matrix for the given mode, like gl.LoadMatrixf.
+func (t *Transforms) Load(mode MatrixMode, mat *Matrix) {
+	*t.top(mode) = *mat
+}
+
+// Post-multiplies the current matrix for the given mode, like gl.MultMatrixf.
+func (t *Transforms) Mult(mode MatrixMode, mat *Matrix) {
+	t.top(mode).Multiply(mat)
+}
+
+// Like gl.Ortho but for the given mode's current matrix.
+func (t *Transforms) Ortho(mode MatrixMode, left, right, bottom, top, near, far float32) {
+	mat := OrthoMatrix(left, right, bottom, top, near, far)
+	t.Mult(mode, &mat)
+}
+
+// Like gl.Translatef but for the given mode's current matrix.
+func (t *Transforms) Translate(mode MatrixMode, x, y, z float32) {
+	mat := Matrix{}
+	mat.Translation(x, y, z)
+	t.Mult(mode, &mat)
+}
+
+// Like gl.Scalef but for the given mode's current matrix.
+func (t *Transforms) Scale(mode MatrixMode, x, y, z float32) {
+	mat := Matrix{}
+	mat.Scaling(x, y, z)
+	t.Mult(mode, &mat)
+}
+
+// Like WithMatrixMode; changes that fn makes to the given mode's matrix are
+// undone once fn returns.
+func (t *Transforms) WithMatrixMode(mode MatrixMode, fn func()) {
+	stack := t.stack(mode)
+	*stack = append(*stack, (*stack)[len(*stack)-1])
+	defer func() {
+		*stack = (*stack)[:len(*stack)-1]
+	}()
+
+	fn()
+}
+
+func (t *Transforms) WithMatrixInMode(mat *Matrix, mode MatrixMode, fn func()) {
+	t.WithMatrixMode(mode, func() {
+		t.Load(mode, mat)
+
+		fn()
+	})
+}
+
+func (t *Transforms) WithMultMatrixInMode(mat *Matrix, mode MatrixMode, fn func()) {
+	t.WithMatrixMode(mode, func() {
+		t.Mult(mode, mat)
+
+		fn()
+	})
+}
+
+func (t *Transforms) WithFreshMatrices(fn func()) {
+	ident := &Matrix{}
+	ident.Identity()
+
+	t.WithMatrixInMode(ident, MatrixModeModelView, func() {
+		t.WithMatrixInMode(ident, MatrixModeProjection, fn)
+	})
+}
+
+func (t *Transforms) WithColour(r, g, b, a float32, fn func()) {
+	assertNormalized(r, g, b, a)
+
+	t.colour = append(t.colour, mathgl.Vec4{X: r, Y: g, Z: b, W: a})
+	defer func() {
+		t.colour = t.colour[:len(t.colour)-1]
+	}()
+
+	fn()
+}
+
+// Sets ModelViewProjectionUniform and ColourUniform in the named shader, which
+// must be enabled. Either uniform may be missing from the shader.
+func (t *Transforms) SetUniforms(bank *ShaderBank, shader string) error {
+	return bank.SetUniforms(shader, struct {
+		ModelViewProjection Matrix      `uniform:"glop_ModelViewProjection,optional"`
+		Colour              mathgl.Vec4 `uniform:"glop_Colour,optional"`
+	}{
+		ModelViewProjection: t.ModelViewProjection(),
+		Colour:              t.Colour(),
+	})
+}
+
+// Returns the matrix that gl.Ortho multiplies by.
+func OrthoMatrix(left, right, bottom, top, near, far float32) Matrix {
+	return Matrix{
+		2 / (right - left), 0, 0, 0,
+		0, 2 / (top - bottom), 0, 0,
+		0, 0, -2 / (far - near), 0,
+		-(right + left) / (right - left), -(top + bottom) / (top - bottom), -(far + near) / (far - near), 1,
+	}
+}
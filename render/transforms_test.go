@@ -0,0 +1,94 @@
+package render_test
+
+import (
+	"testing"
+
+	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/mathgl"
+	"github.com/stretchr/testify/assert"
+)
+
+func identity() render.Matrix {
+	ret := render.Matrix{}
+	ret.Identity()
+	return ret
+}
+
+func transformPoint(mat render.Matrix, x, y float32) (float32, float32) {
+	return mat[0]*x + mat[4]*y + mat[12], mat[1]*x + mat[5]*y + mat[13]
+}
+
+func TestTransforms(t *testing.T) {
+	t.Run("starts fresh", func(t *testing.T) {
+		transforms := render.MakeTransforms()
+
+		assert.Equal(t, identity(), transforms.Matrix(render.MatrixModeModelView))
+		assert.Equal(t, identity(), transforms.Matrix(render.MatrixModeProjection))
+		assert.Equal(t, mathgl.Vec4{X: 1, Y: 1, Z: 1, W: 1}, transforms.Colour())
+	})
+
+	t.Run("WithMatrixMode restores the matrix", func(t *testing.T) {
+		transforms := render.MakeTransforms()
+
+		var during render.Matrix
+		transforms.WithMatrixMode(render.MatrixModeModelView, func() {
+			transforms.Translate(render.MatrixModeModelView, 1, 2, 3)
+			during = transforms.Matrix(render.MatrixModeModelView)
+		})
+
+		assert.NotEqual(t, identity(), during)
+		assert.Equal(t, identity(), transforms.Matrix(render.MatrixModeModelView))
+	})
+
+	t.Run("WithColour nests", func(t *testing.T) {
+		transforms := render.MakeTransforms()
+
+		var inner, outer mathgl.Vec4
+		transforms.WithColour(1, 0, 0, 1, func() {
+			transforms.WithColour(0, 1, 0, 0.5, func() {
+				inner = transforms.Colour()
+			})
+			outer = transforms.Colour()
+		})
+
+		assert.Equal(t, mathgl.Vec4{X: 0, Y: 1, Z: 0, W: 0.5}, inner)
+		assert.Equal(t, mathgl.Vec4{X: 1, Y: 0, Z: 0, W: 1}, outer)
+		assert.Equal(t, mathgl.Vec4{X: 1, Y: 1, Z: 1, W: 1}, transforms.Colour())
+	})
+
+	t.Run("WithFreshMatrices resets both matrices", func(t *testing.T) {
+		transforms := render.MakeTransforms()
+		transforms.Scale(render.MatrixModeModelView, 2, 2, 2)
+		transforms.Ortho(render.MatrixModeProjection, 0, 10, 0, 10, 1, -1)
+
+		var mvp render.Matrix
+		transforms.WithFreshMatrices(func() {
+			mvp = transforms.ModelViewProjection()
+		})
+
+		assert.Equal(t, identity(), mvp)
+		assert.NotEqual(t, identity(), transforms.Matrix(render.MatrixModeModelView))
+	})
+
+	t.Run("ModelViewProjection applies model-view first", func(t *testing.T) {
+		transforms := render.MakeTransforms()
+		transforms.Ortho(render.MatrixModeProjection, 0, 100, 0, 50, 1, -1)
+		transforms.Translate(render.MatrixModeModelView, 50, 0, 0)
+
+		x, y := transformPoint(transforms.ModelViewProjection(), 0, 25)
+		assert.InDelta(t, 0, x, 1e-6)
+		assert.InDelta(t, 0, y, 1e-6)
+
+		x, y = transformPoint(transforms.ModelViewProjection(), 50, 50)
+		assert.InDelta(t, 1, x, 1e-6)
+		assert.InDelta(t, 1, y, 1e-6)
+	})
+
+	t.Run("rejects other matrix modes", func(t *testing.T) {
+		transforms := render.MakeTransforms()
+
+		assert.Panics(t, func() {
+			transforms.Matrix(render.MatrixModeTexture)
+		})
+	})
+}
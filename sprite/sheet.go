@@ -176,7 +176,8 @@ func (s *sheet) loadRoutine(renderQueue render.RenderQueueInterface) {
 			// TODO(tmckee): clean: we don't need to spawn a go-routine to send a
 			// func on a chan.
 			go func() {
-				renderQueue.Queue(func(render.RenderQueueState) {
+				// Texture uploads can wait; don't hold up drawing the current frame.
+				renderQueue.QueueInLane(render.LaneBackground, func(render.RenderQueueState) {
 					s.makeTexture(pixer)
 					ready <- true
 				})
@@ -184,7 +185,7 @@ func (s *sheet) loadRoutine(renderQueue render.RenderQueueInterface) {
 		} else {
 			go func() {
 				<-ready
-				renderQueue.Queue(func(render.RenderQueueState) {
+				renderQueue.QueueInLane(render.LaneBackground, func(render.RenderQueueState) {
 					s.texture.Delete()
 					s.texture = 0
 				})
@@ -17,6 +17,7 @@ import (
 	"github.com/caffeine-storm/glop/cache"
 	"github.com/caffeine-storm/glop/glog"
 	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/batch"
 	"github.com/caffeine-storm/glop/util/algorithm"
 	"github.com/caffeine-storm/glu"
 	yed "github.com/runningwild/yedparse"
@@ -740,7 +741,10 @@ func (s *Sprite) Dims() (dx, dy int) {
 	return
 }
 
-func (s *Sprite) Bind() (x, y, x2, y2 float64) {
+// Returns the texture holding the sprite's current frame and the frame's
+// texture co-ordinates within it. If the frame can't be found, the returned
+// texture is the manager's error texture and the co-ordinates are zero.
+func (s *Sprite) frameTexture() (tex gl.Texture, x, y, x2, y2 float64) {
 	var rect FrameRect
 	var sh *sheet
 	var ok bool
@@ -757,11 +761,11 @@ func (s *Sprite) Bind() (x, y, x2, y2 float64) {
 		// It would be better for this function to return an error instead of
 		// expecting a human to see that a visual got flagged for error (at least,
 		// I think that's what this expects).
-		s.shared.manager.error_texture.Bind(gl.TEXTURE_2D)
+		tex = s.shared.manager.error_texture
 		return
 	}
 	glog.InfoLogger().Debug("going to bind sprite sub-texture", "fid", fid, "rect", rect, "connectorSheet?", isConnectorSheet, "spritePath", s.shared.connector.spritePath, "spritelabel", s.anim_node.Label())
-	sh.texture.Bind(gl.TEXTURE_2D)
+	tex = sh.texture
 	dx = float64(sh.dx)
 	dy = float64(sh.dy)
 	x = float64(rect.X) / dx
@@ -771,6 +775,25 @@ func (s *Sprite) Bind() (x, y, x2, y2 float64) {
 	return
 }
 
+func (s *Sprite) Bind() (x, y, x2, y2 float64) {
+	var tex gl.Texture
+	tex, x, y, x2, y2 = s.frameTexture()
+	tex.Bind(gl.TEXTURE_2D)
+	return
+}
+
+// Like Bind but, instead of binding the current frame's texture, queues a quad
+// showing the current frame in the given batch. The quad's texture
+// co-ordinates are filled in; the rest of it is up to the caller.
+func (s *Sprite) AddToBatch(b *batch.SpriteBatch, quad render.Quad) {
+	tex, x, y, x2, y2 := s.frameTexture()
+	quad.U0 = float32(x)
+	quad.V0 = float32(y)
+	quad.U1 = float32(x2)
+	quad.V1 = float32(y2)
+	b.Add(tex, quad)
+}
+
 func (s *Sprite) Facing() int {
 	return s.facing
 }
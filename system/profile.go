@@ -0,0 +1,16 @@
+package system
+
+import "os"
+
+// Set this environment variable to "1" to have CreateWindow ask for an OpenGL
+// 3.3 core-profile context instead of the default compatibility profile. Only
+// code that sticks to render's core-profile helpers (render.Transforms,
+// render.QuadBatch, etc.) works in such a context; fixed-function calls like
+// gl.Begin or gl.PushMatrix raise GL_INVALID_OPERATION.
+const CoreProfileEnvVar = "GLOP_GL_CORE"
+
+// Returns true iff the environment asks for a core-profile context.
+func CoreProfileRequested() bool {
+	val, found := os.LookupEnv(CoreProfileEnvVar)
+	return found && val != "" && val != "0"
+}
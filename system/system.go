@@ -38,6 +38,16 @@ type System interface {
 
 	// Attach a gin.Listener to the underlying input delegate.
 	AddInputListener(gin.Listener)
+
+	// Hands each frame's raw input to the recorder before gin sees it; nil
+	// stops recording. See gin/ginrecord.
+	RecordInput(InputRecorder)
+}
+
+// Sees exactly what System.Think feeds to gin.Input.Think. Timestamps are
+// relative to Startup.
+type InputRecorder interface {
+	RecordFrame(horizonMs int64, events []gin.OsEvent)
 }
 
 // This is the interface implemented by any operating system that glop
@@ -90,6 +100,7 @@ type sysObj struct {
 	input    *gin.Input
 	events   []gin.EventGroup
 	start_ms int64
+	recorder InputRecorder
 }
 
 func Make(os Os, input *gin.Input) System {
@@ -109,6 +120,9 @@ func (sys *sysObj) Think() int64 {
 	for i := range events {
 		events[i].TimestampMs -= sys.start_ms
 	}
+	if sys.recorder != nil {
+		sys.recorder.RecordFrame(horizon-sys.start_ms, events)
+	}
 	sys.events = sys.input.Think(horizon-sys.start_ms, events)
 	return horizon - sys.start_ms
 }
@@ -141,6 +155,10 @@ func (sys *sysObj) AddInputListener(lstnr gin.Listener) {
 	sys.input.RegisterEventListener(lstnr)
 }
 
+func (sys *sysObj) RecordInput(recorder InputRecorder) {
+	sys.recorder = recorder
+}
+
 func (sys *sysObj) EnableVSync(enable bool) {
 	sys.os.EnableVSync(enable)
 }
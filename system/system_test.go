@@ -39,6 +39,8 @@ func (*stubSystem) AddInputListener(gin.Listener) {
 
 func (*stubSystem) EnableVSync(bool) {}
 
+func (*stubSystem) RecordInput(system.InputRecorder) {}
+
 var _ system.System = (*stubSystem)(nil)
 
 func GivenASystem() system.System {
@@ -11,6 +11,7 @@ import (
 	"github.com/caffeine-storm/glop/gin"
 	"github.com/caffeine-storm/glop/glog"
 	"github.com/caffeine-storm/glop/gos"
+	"github.com/caffeine-storm/glop/gos/headless"
 	"github.com/caffeine-storm/glop/gui"
 	"github.com/caffeine-storm/glop/render"
 	"github.com/caffeine-storm/glop/system"
@@ -29,8 +30,8 @@ func main() {
 	render := render.MakeQueue(func(render.RenderQueueState) {
 		sys.CreateWindow(10, 10, wdx, wdy)
 		sys.EnableVSync(true)
-		err := gl.Init()
-		if err != 0 {
+		err := headless.CheckGlInit(gl.Init())
+		if err != nil {
 			panic(err)
 		}
 		gl.Enable(gl.BLEND)
@@ -6,9 +6,11 @@ import (
 	"runtime"
 
 	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/debug"
 	"github.com/caffeine-storm/glop/gin"
 	"github.com/caffeine-storm/glop/glog"
 	"github.com/caffeine-storm/glop/gos"
+	"github.com/caffeine-storm/glop/gos/headless"
 	"github.com/caffeine-storm/glop/gui"
 	"github.com/caffeine-storm/glop/render"
 	"github.com/caffeine-storm/glop/system"
@@ -16,11 +18,19 @@ import (
 
 func main() {
 	if len(os.Args) < 3 {
-		fmt.Fprintf(os.Stderr, "usage: %s <dict.gob> <string-to-render>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "usage: %s <dict.gob> <string-to-render> [out.png]\n", os.Args[0])
 		os.Exit(1)
 	}
 	fromFile := os.Args[1]
 	stringToRender := os.Args[2]
+	toFile := ""
+	if len(os.Args) > 3 {
+		toFile = os.Args[3]
+	}
+	if toFile == "" && headless.Requested() {
+		fmt.Fprintf(os.Stderr, "running headless; pass an output path to see the result\n")
+		os.Exit(1)
+	}
 
 	runtime.LockOSThread()
 	sys := system.Make(gos.NewSystemInterface(), gin.MakeLogged(glog.InfoLogger()))
@@ -31,8 +41,8 @@ func main() {
 	renderQueue := render.MakeQueue(func(render.RenderQueueState) {
 		sys.CreateWindow(0, 0, wdx, wdy)
 		sys.EnableVSync(true)
-		err := gl.Init()
-		if err != 0 {
+		err := headless.CheckGlInit(gl.Init())
+		if err != nil {
 			panic(err)
 		}
 		gl.Enable(gl.BLEND)
@@ -61,6 +71,20 @@ func main() {
 	})
 	renderQueue.Purge()
 
+	if toFile != "" {
+		out, err := os.Create(toFile)
+		if err != nil {
+			panic(err)
+		}
+		defer out.Close()
+
+		renderQueue.Queue(func(render.RenderQueueState) {
+			debug.ScreenShot(wdx, wdy, out)
+		})
+		renderQueue.Purge()
+		return
+	}
+
 	var in string
 	fmt.Fprintf(os.Stderr, "hit enter to exit\n")
 	fmt.Fscanf(os.Stdin, "%s", &in)
@@ -0,0 +1,151 @@
+// Replays a trace written by render/gltrace into an offscreen context and
+// saves the framebuffer after each job as a PNG. Bisecting a rendering
+// regression is then a matter of finding the first PNG that looks wrong.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/caffeine-storm/gl"
+	"github.com/caffeine-storm/glop/debug"
+	"github.com/caffeine-storm/glop/gos"
+	"github.com/caffeine-storm/glop/gos/headless"
+	"github.com/caffeine-storm/glop/render"
+	"github.com/caffeine-storm/glop/render/gltrace"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [flags] <trace-file>\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	outDir := flag.String("out", ".", "directory to write job-NNNNNN.png files to")
+	width := flag.Int("width", 1024, "width of the offscreen framebuffer")
+	height := flag.Int("height", 750, "height of the offscreen framebuffer")
+	list := flag.Bool("list", false, "list the jobs in the trace instead of replaying them")
+	verbose := flag.Bool("v", false, "with -list, also list each job's GL calls")
+	through := flag.Uint64("through", 0, "stop after the job with this sequence number; 0 means replay everything")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(1)
+	}
+
+	records, err := readTrace(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+
+	if *list {
+		listRecords(records, *verbose)
+		return
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		panic(err)
+	}
+
+	failed := replay(records, *through, *width, *height, *outDir)
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func readTrace(path string) ([]*gltrace.JobRecord, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	reader, err := gltrace.NewReader(in)
+	if err != nil {
+		return nil, err
+	}
+	return reader.ReadAll()
+}
+
+func describeRecord(record *gltrace.JobRecord) string {
+	if record.Panicked() {
+		return fmt.Sprintf("job %d: panicked: %s", record.Seq, record.Err)
+	}
+	return fmt.Sprintf("job %d (%s lane): %s", record.Seq, record.Lane, record.Attribution)
+}
+
+func listRecords(records []*gltrace.JobRecord, verbose bool) {
+	for _, record := range records {
+		fmt.Println(describeRecord(record))
+		if verbose {
+			for _, call := range gltrace.Describe(record.Calls) {
+				fmt.Printf("    %s\n", call)
+			}
+		}
+	}
+}
+
+// Returns true if any job couldn't be replayed in full.
+func replay(records []*gltrace.JobRecord, through uint64, width, height int, outDir string) bool {
+	// Replaying is always offscreen; there's no point in popping up a window.
+	os.Setenv(headless.EnvVar, "1")
+
+	runtime.LockOSThread()
+	sysObj := gos.NewSystemInterface()
+	sysObj.Startup()
+	queue := render.MakeQueue(func(render.RenderQueueState) {
+		sysObj.CreateWindow(0, 0, width, height)
+		err := headless.CheckGlInit(gl.Init())
+		if err != nil {
+			panic(err)
+		}
+	})
+	queue.StartProcessing()
+
+	failed := false
+	queue.Queue(func(render.RenderQueueState) {
+		replayer := gltrace.NewReplayer()
+		defer replayer.Close()
+
+		for _, record := range records {
+			if through != 0 && record.Seq > through {
+				break
+			}
+
+			fmt.Println(describeRecord(record))
+			if err := replayer.Replay(record); err != nil {
+				fmt.Fprintf(os.Stderr, "    %v\n", err)
+				failed = true
+			}
+
+			sysObj.SwapBuffers()
+			if err := saveFramebuffer(outDir, record.Seq, width, height); err != nil {
+				fmt.Fprintf(os.Stderr, "    couldn't save framebuffer: %v\n", err)
+				failed = true
+			}
+		}
+	})
+	queue.Purge()
+
+	return failed
+}
+
+func saveFramebuffer(outDir string, seq uint64, width, height int) (err error) {
+	out, err := os.Create(filepath.Join(outDir, fmt.Sprintf("job-%06d.png", seq)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+	}()
+
+	debug.ScreenShot(width, height, out)
+	return nil
+}
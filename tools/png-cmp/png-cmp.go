@@ -1,13 +1,17 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/caffeine-storm/glop/imgmanip"
+	"github.com/caffeine-storm/glop/render/rendertest/imgcmp"
 )
 
 type Delta struct {
@@ -87,16 +91,80 @@ func mustPng(fname string) image.Image {
 	return ret
 }
 
+// Turns the mode flags into options for imgcmp.CompareImages. Returns
+// nil if none of them were given.
+func ParseOptions(args []string) (files []string, options []any, err error) {
+	flags := flag.NewFlagSet("png-cmp", flag.ContinueOnError)
+	threshold := flags.Int("threshold", -1, "max difference in any channel, as for imgcmp.Threshold")
+	tolerance := flags.String("tolerance", "", "per-channel max differences as 'r,g,b,a'")
+	maxRatio := flags.Float64("max-ratio", -1, "fraction of pixels in [0, 1] that may differ")
+	minSSIM := flags.Float64("ssim", 2, "pass if the structural similarity is at least this value in [-1, 1]")
+	mask := flags.String("mask", "", "png whose non-transparent pixels are ignored")
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if flags.NArg() != 2 {
+		return nil, nil, fmt.Errorf("usage: png-cmp [flags] a.png b.png")
+	}
+
+	if *threshold >= 0 {
+		if *threshold > 255 {
+			return nil, nil, fmt.Errorf("-threshold must be in [0, 255]; got %d", *threshold)
+		}
+		options = append(options, imgcmp.Threshold(*threshold))
+	}
+	if *tolerance != "" {
+		parts := strings.Split(*tolerance, ",")
+		if len(parts) != 4 {
+			return nil, nil, fmt.Errorf("-tolerance needs 4 values; got %q", *tolerance)
+		}
+		var ct imgcmp.ChannelTolerance
+		for i, part := range parts {
+			val, err := strconv.ParseUint(strings.TrimSpace(part), 10, 8)
+			if err != nil {
+				return nil, nil, fmt.Errorf("bad -tolerance %q: %w", *tolerance, err)
+			}
+			ct[i] = uint8(val)
+		}
+		options = append(options, ct)
+	}
+	if *maxRatio >= 0 {
+		options = append(options, imgcmp.MaxDifferingPixelRatio(*maxRatio))
+	}
+	if *minSSIM <= 1 {
+		options = append(options, imgcmp.MinSSIM(*minSSIM))
+	}
+	if *mask != "" {
+		options = append(options, imgcmp.IgnoreMask{Image: mustPng(*mask)})
+	}
+
+	return flags.Args(), options, nil
+}
+
 func main() {
-	if len(os.Args) != 3 {
-		panic("usage: png-cmp a.png b.png")
+	files, options, err := ParseOptions(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
 
-	lhs, rhs := os.Args[1], os.Args[2]
+	lhs, rhs := files[0], files[1]
 
 	lhsPng := mustPng(lhs)
 	rhsPng := mustPng(rhs)
 
+	// With any of the mode flags, behave like rendertest.ShouldLookLikeFile
+	// would and report through the exit code.
+	if options != nil {
+		result := imgcmp.CompareImages(lhsPng, rhsPng, options...)
+		fmt.Println(result)
+		if !result.Match {
+			os.Exit(1)
+		}
+		return
+	}
+
 	lhsNrgba := imgmanip.ToNRGBA(lhsPng)
 	rhsNrgba := imgmanip.ToNRGBA(rhsPng)
 
@@ -4,7 +4,10 @@ import (
 	"image"
 	"testing"
 
+	"github.com/caffeine-storm/glop/render/rendertest/imgcmp"
 	"github.com/caffeine-storm/glop/tools/png-cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWeUseNrgba(t *testing.T) {
@@ -14,3 +17,29 @@ func TestWeUseNrgba(t *testing.T) {
 
 	main.ImageCompare(lhs, rhs)
 }
+
+func TestParseOptions(t *testing.T) {
+	t.Run("no flags means no options", func(t *testing.T) {
+		files, options, err := main.ParseOptions([]string{"a.png", "b.png"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.png", "b.png"}, files)
+		assert.Nil(t, options)
+	})
+
+	t.Run("mode flags", func(t *testing.T) {
+		_, options, err := main.ParseOptions([]string{"-tolerance", "1,2,3,4", "-max-ratio", "0.5", "-ssim", "0.9", "a.png", "b.png"})
+		require.NoError(t, err)
+		assert.Equal(t, []any{
+			imgcmp.ChannelTolerance{1, 2, 3, 4},
+			imgcmp.MaxDifferingPixelRatio(0.5),
+			imgcmp.MinSSIM(0.9),
+		}, options)
+	})
+
+	t.Run("bad usage", func(t *testing.T) {
+		_, _, err := main.ParseOptions([]string{"a.png"})
+		assert.Error(t, err)
+		_, _, err = main.ParseOptions([]string{"-tolerance", "1,2", "a.png", "b.png"})
+		assert.Error(t, err)
+	})
+}
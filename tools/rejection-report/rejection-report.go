@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/caffeine-storm/glop/render/rendertest/imgcmp"
+)
+
+func main() {
+	outDir := flag.String("out", "rejections", "directory to write the report to")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: rejection-report [-out dir] [root...]\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Gathers the *.rej.png files under each root (default '.') into one HTML page.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	roots := flag.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	var rejections []imgcmp.Rejection
+	for _, root := range roots {
+		found, err := imgcmp.FindRejections(root)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		rejections = append(rejections, found...)
+	}
+
+	if len(rejections) == 0 {
+		fmt.Println("no rejections found")
+		return
+	}
+
+	err := imgcmp.WriteRejectionReport(rejections, *outDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("wrote %d rejection(s) to %s\n", len(rejections), *outDir)
+}